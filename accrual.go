@@ -0,0 +1,164 @@
+package comarch
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AccrualRule правило начисления баллов за покупки в категории
+type AccrualRule struct {
+	// категория товаров. Коды совпадают с FavCategory, для категорий вне этого словаря приходит код комарха
+	Category FavCategory
+	// описание правила
+	Description string
+	// множитель начисления по отношению к базовому курсу
+	Multiplier float64
+	// начало и окончание действия в часовом поясе WithLocation. Нулевое время, если граница не задана
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+// IsActive проверяет, что правило действует в момент now
+func (r AccrualRule) IsActive(now time.Time) bool {
+	if !r.ValidFrom.IsZero() && now.Before(r.ValidFrom) {
+		return false
+	}
+
+	if !r.ValidTo.IsZero() && now.After(r.ValidTo) {
+		return false
+	}
+
+	return true
+}
+
+type accrualRule struct {
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Multiplier  float64 `json:"multiplier"`
+	ValidFrom   string  `json:"validFrom"`
+	ValidTo     string  `json:"validTo"`
+}
+
+// GetAccrualRules получает правила начисления баллов, действующие для участника
+func (c *Client) GetAccrualRules(accessToken AccessToken, opts ...CallOption) ([]AccrualRule, error) {
+	u := c.endpoint("/resources/accrualrules")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetAccrualRules", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var rawRules []accrualRule
+	if err := c.decodeResponse(resp, &rawRules); err != nil {
+		return nil, err
+	}
+
+	rules := make([]AccrualRule, 0, len(rawRules))
+	for _, raw := range rawRules {
+		validFrom, err := parseDateTime(raw.ValidFrom, c.location)
+		if err != nil {
+			return nil, err
+		}
+
+		validTo, err := parseDateTime(raw.ValidTo, c.location)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, AccrualRule{
+			Category:    FavCategory(raw.Category),
+			Description: raw.Description,
+			Multiplier:  raw.Multiplier,
+			ValidFrom:   validFrom,
+			ValidTo:     validTo,
+		})
+	}
+
+	return rules, nil
+}
+
+// BasketItem позиция корзины для EstimatePoints
+type BasketItem struct {
+	// категория товара
+	Category FavCategory `json:"category"`
+	// сумма позиции в рублях
+	Amount float64 `json:"amount"`
+}
+
+type pointsEstimate struct {
+	Points flexInt `json:"points"`
+}
+
+// EstimatePoints рассчитывает, сколько баллов участник получит за покупку корзины basket. Покупка при этом
+// не регистрируется и баланс не меняется, поэтому метод выполняется и в режиме WithDryRun.
+// Пустая корзина и отрицательные суммы отклоняются с *ValidationError без запроса к комарху.
+func (c *Client) EstimatePoints(accessToken AccessToken, basket []BasketItem, opts ...CallOption) (int, error) {
+	if err := validateBasket(basket); err != nil {
+		return 0, err
+	}
+
+	u := c.endpoint("/resources/accrualestimate")
+
+	req, err := c.newJSONRequest("POST", u, map[string][]BasketItem{"items": basket})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return 0, err
+	}
+
+	resp, err := c.do("EstimatePoints", req, opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return 0, asValidationError(err)
+	}
+
+	var estimate pointsEstimate
+	if err := c.decodeResponse(resp, &estimate); err != nil {
+		return 0, err
+	}
+
+	return int(estimate.Points), nil
+}
+
+// validateBasket проверяет корзину EstimatePoints
+func validateBasket(basket []BasketItem) error {
+	if len(basket) == 0 {
+		return &ValidationError{Fields: map[string]string{"items": "must not be empty"}}
+	}
+
+	fields := map[string]string{}
+	for i, item := range basket {
+		if item.Category == "" {
+			fields[fmt.Sprintf("items[%d].category", i)] = "must not be empty"
+		}
+		if item.Amount < 0 {
+			fields[fmt.Sprintf("items[%d].amount", i)] = "must not be negative"
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	return nil
+}
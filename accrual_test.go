@@ -0,0 +1,74 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetAccrualRules(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/accrualrules", r.URL.Path)
+		w.Write([]byte(`[
+			{"category":"73:X_FD_Ов.фр","multiplier":2,"validFrom":"2020-03-01 00:00","validTo":"2020-03-31 23:59"},
+			{"category":"73:X_FD_Alcohol","multiplier":0.5}
+		]`))
+	}, comarch.WithLocation(time.UTC))
+
+	rules, err := c.GetAccrualRules(testAccessToken)
+	assert.Nil(t, err)
+	if assert.Len(t, rules, 2) {
+		assert.Equal(t, comarch.FavCategory8, rules[0].Category)
+		assert.Equal(t, 2.0, rules[0].Multiplier)
+		assert.Equal(t, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), rules[0].ValidFrom)
+		assert.True(t, rules[0].IsActive(time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)))
+		assert.False(t, rules[0].IsActive(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)))
+
+		assert.Equal(t, comarch.FavCategory2, rules[1].Category)
+		assert.True(t, rules[1].ValidTo.IsZero())
+		assert.True(t, rules[1].IsActive(time.Now()))
+	}
+}
+
+func TestClient_EstimatePoints(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/cwaapiinterface/resources/accrualestimate", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var body map[string][]map[string]interface{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string][]map[string]interface{}{"items": {
+			{"category": string(comarch.FavCategory1), "amount": 150.5},
+			{"category": string(comarch.FavCategory2), "amount": 1000.0},
+		}}, body)
+
+		w.Write([]byte(`{"points":"42"}`))
+	}, comarch.WithDryRun(true))
+
+	points, err := c.EstimatePoints(testAccessToken, []comarch.BasketItem{
+		{Category: comarch.FavCategory1, Amount: 150.5},
+		{Category: comarch.FavCategory2, Amount: 1000},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 42, points)
+}
+
+func TestClient_EstimatePoints_Validation(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not be sent")
+	})
+
+	_, err := c.EstimatePoints(testAccessToken, nil)
+	assert.IsType(t, &comarch.ValidationError{}, err)
+
+	_, err = c.EstimatePoints(testAccessToken, []comarch.BasketItem{{Category: comarch.FavCategory1, Amount: -1}})
+	var validationErr *comarch.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Equal(t, map[string]string{"items[0].amount": "must not be negative"}, validationErr.Fields)
+	}
+}
@@ -0,0 +1,81 @@
+package comarch
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ConfirmCardActivation завершает двухшаговую активацию карты: на инсталляциях, где ActivateCardNo только
+// отправляет участнику одноразовый код, код otp подтверждает активацию и возвращает токен участника.
+// Неверный или просроченный код возвращается как ErrInvalidOTP.
+func (c *Client) ConfirmCardActivation(cardNo, otp string, opts ...CallOption) (*AccessToken, error) {
+	if strings.TrimSpace(otp) == "" {
+		return nil, &ValidationError{Fields: map[string]string{"otp": "must not be empty"}}
+	}
+
+	params := url.Values{}
+	params.Set("cardNo", cardNo)
+	params.Set("otp", otp)
+
+	token, err := c.signIn("ConfirmCardActivation", GrantTypeCardActivation, params, opts...)
+	if err != nil {
+		return nil, asOTPError(err)
+	}
+
+	return token, nil
+}
+
+// ResendActivationConfirmation повторно отправляет владельцу карты cardNo подтверждение активации (email или SMS,
+// в зависимости от настроек комарха). Метод для поддержки, выполняется с учетными данными приложения.
+// Для уже активированной карты возвращает ErrCardAlreadyActivated, для неизвестной ErrNotFound.
+func (c *Client) ResendActivationConfirmation(cardNo string, opts ...CallOption) error {
+	if strings.TrimSpace(cardNo) == "" {
+		return &ValidationError{Fields: map[string]string{"cardNo": "must not be empty"}}
+	}
+
+	u := c.endpoint("/common/cards/activation/resend")
+
+	req, err := c.newJSONRequest("POST", u, map[string]string{"cardNo": cardNo})
+	if err != nil {
+		return err
+	}
+
+	if err := c.basicAuth("ResendActivationConfirmation", req); err != nil {
+		return err
+	}
+
+	resp, err := c.do("ResendActivationConfirmation", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return ErrCardAlreadyActivated
+		}
+
+		return asValidationError(err)
+	}
+
+	c.audit("ResendActivationConfirmation", cardNo)
+
+	return nil
+}
+
+// asOTPError переводит отказ комарха в подтверждении одноразового кода (400, 401 или 410) в ErrInvalidOTP
+func asOTPError(err error) error {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+
+	switch httpErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusGone:
+		return ErrInvalidOTP
+	}
+
+	return err
+}
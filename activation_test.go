@@ -0,0 +1,117 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_ConfirmCardActivation(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "wrong_otp", status: http.StatusUnauthorized, err: comarch.ErrInvalidOTP},
+		{name: "expired_otp", status: http.StatusGone, err: comarch.ErrInvalidOTP},
+		{name: "server_error", status: http.StatusInternalServerError, err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/cwaapiinterface/login", r.URL.Path)
+				assert.Equal(t, "cardactivation", r.URL.Query().Get("grant_type"))
+				assert.Equal(t, testCredentialsCardNo, r.URL.Query().Get("cardNo"))
+				assert.Equal(t, "123456", r.URL.Query().Get("otp"))
+
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					return
+				}
+				tokenHandler(w, r)
+			})
+
+			token, err := c.ConfirmCardActivation(testCredentialsCardNo, "123456")
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err))
+				assert.Nil(t, token)
+				return
+			}
+
+			assert.Nil(t, err)
+			if assert.NotNil(t, token) {
+				assert.Equal(t, "token", token.Value)
+				assert.Equal(t, comarch.GrantTypeCardActivation, token.Grant)
+				assert.Equal(t, map[string]string{"cardNo": testCredentialsCardNo}, token.GrantParams)
+			}
+		})
+	}
+}
+
+func TestClient_ConfirmCardActivation_EmptyOTP(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not be sent")
+	})
+
+	_, err := c.ConfirmCardActivation(testCredentialsCardNo, " ")
+	assert.IsType(t, &comarch.ValidationError{}, err)
+}
+
+func TestClient_ResendActivationConfirmation(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "already_activated", status: http.StatusConflict, err: comarch.ErrCardAlreadyActivated},
+		{name: "unknown_card", status: http.StatusNotFound, err: comarch.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []comarch.AuditEvent
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/cwaapiinterface/common/cards/activation/resend", r.URL.Path)
+
+				username, password, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, testUsername, username)
+				assert.Equal(t, testPassword, password)
+
+				var body map[string]string
+				assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, map[string]string{"cardNo": testCredentialsCardNo}, body)
+
+				w.WriteHeader(tt.status)
+			}, comarch.WithAuditLogger(comarch.AuditLoggerFunc(func(event comarch.AuditEvent) {
+				events = append(events, event)
+			})))
+
+			err := c.ResendActivationConfirmation(testCredentialsCardNo)
+			if tt.err == nil {
+				assert.Nil(t, err)
+				if assert.Len(t, events, 1) {
+					assert.Equal(t, "ResendActivationConfirmation", events[0].Operation)
+				}
+				return
+			}
+
+			assert.True(t, errors.Is(err, tt.err), "got %v", err)
+			assert.Empty(t, events)
+		})
+	}
+
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request must not be sent")
+	})
+
+	var validationErr *comarch.ValidationError
+	assert.True(t, errors.As(c.ResendActivationConfirmation(" "), &validationErr))
+}
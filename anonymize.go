@@ -0,0 +1,45 @@
+package comarch
+
+import (
+	"errors"
+	"net/http"
+)
+
+// AnonymizeCardHolder обезличивает участника вместо удаления, для требований хранения данных. Комарх очищает
+// персональные поля анкеты: имя и фамилию, адрес (street, homeFraction, building, flat, postCode, city),
+// день рождения, телефоны (phone, secondPhone, mobilePhone) и адрес эл.почты, а также снимает все согласия
+// на связь. Номер карты, баланс и история операций сохраняются, поэтому агрегаты по операциям не меняются.
+// После обезличивания анкету нельзя восстановить, повторный вызов возвращает ErrCardHolderAnonymized.
+// В режиме WithDryRun запрос не отправляется.
+func (c *Client) AnonymizeCardHolder(accessToken AccessToken, opts ...CallOption) error {
+	u := c.endpoint("/resources/cardholders/anonymize")
+
+	req, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("AnonymizeCardHolder", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusConflict || httpErr.StatusCode == http.StatusGone) {
+			return ErrCardHolderAnonymized
+		}
+
+		return err
+	}
+
+	c.audit("AnonymizeCardHolder", tokenSubject(c.resolveToken(req.Context(), accessToken)))
+	c.balanceCache.invalidate(c.resolveToken(req.Context(), accessToken).Value)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_AnonymizeCardHolder(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "already_anonymized", status: http.StatusConflict, err: comarch.ErrCardHolderAnonymized},
+		{name: "gone", status: http.StatusGone, err: comarch.ErrCardHolderAnonymized},
+		{name: "unauthorized", status: http.StatusUnauthorized, err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []comarch.AuditEvent
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/cwaapiinterface/resources/cardholders/anonymize", r.URL.Path)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+				w.WriteHeader(tt.status)
+			}, comarch.WithAuditLogger(comarch.AuditLoggerFunc(func(event comarch.AuditEvent) {
+				events = append(events, event)
+			})))
+
+			err := c.AnonymizeCardHolder(testAccessToken)
+			if tt.err == nil {
+				assert.Nil(t, err)
+				if assert.Len(t, events, 1) {
+					assert.Equal(t, "AnonymizeCardHolder", events[0].Operation)
+				}
+				return
+			}
+
+			assert.True(t, errors.Is(err, tt.err), "got %v", err)
+			assert.Empty(t, events)
+		})
+	}
+
+	t.Run("bearer_token", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer upstream", r.Header.Get("Authorization"))
+		}))
+		defer srv.Close()
+
+		var events []comarch.AuditEvent
+		c, err := comarch.New(log, srv.URL, "", "", nil,
+			comarch.WithBearerToken(comarch.AccessToken{
+				Value:       "upstream",
+				GrantParams: map[string]string{"cardNo": testCredentialsCardNo},
+			}),
+			comarch.WithAuditLogger(comarch.AuditLoggerFunc(func(event comarch.AuditEvent) {
+				events = append(events, event)
+			})),
+		)
+		assert.Nil(t, err)
+
+		// участник определяется по токену, которым запрос авторизован
+		assert.Nil(t, c.AnonymizeCardHolder(comarch.AccessToken{}))
+		if assert.Len(t, events, 1) {
+			assert.NotEmpty(t, events[0].Subject)
+			assert.True(t, strings.HasSuffix(events[0].Subject, testCredentialsCardNo[len(testCredentialsCardNo)-4:]), events[0].Subject)
+		}
+	})
+
+	t.Run("dry_run", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("request must not be sent")
+		}, comarch.WithDryRun(true))
+
+		assert.Nil(t, c.AnonymizeCardHolder(testAccessToken))
+	})
+}
@@ -0,0 +1,61 @@
+package comarch
+
+import (
+	"strings"
+	"time"
+)
+
+// AuditEvent успешное выполнение чувствительной операции. Не содержит паролей и токенов
+type AuditEvent struct {
+	// имя метода клиента, например ChangePassword
+	Operation string
+	// замаскированный номер карты или телефона участника, например ************4444.
+	// Пустой, если участника не удалось определить
+	Subject string
+	// время завершения операции
+	Time time.Time
+}
+
+// AuditLogger получает события аудита чувствительных операций. Вызывается синхронно после успешного ответа
+type AuditLogger interface {
+	Audit(event AuditEvent)
+}
+
+// AuditLoggerFunc функция, реализующая AuditLogger
+type AuditLoggerFunc func(event AuditEvent)
+
+func (f AuditLoggerFunc) Audit(event AuditEvent) {
+	f(event)
+}
+
+// audit сообщает AuditLogger об успешной операции. В режиме WithDryRun операции не выполняются и не аудируются
+func (c *Client) audit(operation, subject string) {
+	if c.auditLogger == nil || c.isDryRun(operation) {
+		return
+	}
+
+	c.auditLogger.Audit(AuditEvent{
+		Operation: operation,
+		Subject:   maskSubject(subject),
+		Time:      time.Now(),
+	})
+}
+
+// tokenSubject возвращает номер карты или телефона, по которому был получен токен
+func tokenSubject(accessToken AccessToken) string {
+	if cardNo := accessToken.GrantParams["cardNo"]; cardNo != "" {
+		return cardNo
+	}
+
+	return accessToken.GrantParams["phoneNo"]
+}
+
+// maskSubject оставляет последние 4 символа идентификатора, остальные заменяет на *
+func maskSubject(subject string) string {
+	runes := []rune(subject)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
@@ -0,0 +1,45 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestWithAuditLogger(t *testing.T) {
+	status := http.StatusOK
+	var events []comarch.AuditEvent
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/login" {
+			tokenHandler(w, r)
+			return
+		}
+		w.WriteHeader(status)
+	}, comarch.WithAuditLogger(comarch.AuditLoggerFunc(func(event comarch.AuditEvent) {
+		events = append(events, event)
+	})))
+
+	assert.Nil(t, c.ResetPasswordByCardNo(testCredentialsCardNo))
+	_, err := c.ResetPasswordByPhoneNoWithResult("79990001122")
+	assert.Nil(t, err)
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Nil(t, c.ChangePassword(*token, testCredentialsPassword, "new"))
+
+	status = http.StatusBadRequest
+	assert.NotNil(t, c.ChangePassword(*token, testCredentialsPassword, "new"))
+
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, "ResetPasswordByCardNo", events[0].Operation)
+		assert.Equal(t, "************4444", events[0].Subject)
+		assert.False(t, events[0].Time.IsZero())
+
+		assert.Equal(t, "ResetPasswordByPhoneNo", events[1].Operation)
+		assert.Equal(t, "*******1122", events[1].Subject)
+
+		assert.Equal(t, "ChangePassword", events[2].Operation)
+		assert.Equal(t, "************4444", events[2].Subject)
+	}
+}
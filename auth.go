@@ -0,0 +1,510 @@
+package comarch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SignIn аутентификация пользователя по произвольному grant_type. params параметры логина
+// (например, cardNo и password), grant_type в них указывать не нужно. Допускаются только
+// стандартные grant_type и зарегистрированные через WithGrantTypes, для остальных возвращается ErrUnknownGrantType.
+// Пустой номер карты или телефона и их одновременная передача отклоняются с *ValidationError до отправки запроса.
+func (c *Client) SignIn(grant GrantType, params map[string]string, opts ...CallOption) (*AccessToken, error) {
+	if !c.isKnownGrantType(grant) {
+		return nil, ErrUnknownGrantType
+	}
+
+	return c.signIn("SignIn", grant, toValues(params), opts...)
+}
+
+// BuildLoginURL возвращает адрес логина, который отправили бы SignIn и SignIn* методы с теми же grant и params,
+// не выполняя запрос. Пароль в params проходит ту же нормализацию, что и при логине.
+// Параметры всегда передаются в query string, даже для grant_type из WithFormLogin.
+// Для незарегистрированного grant_type возвращается ErrUnknownGrantType.
+func (c *Client) BuildLoginURL(grant GrantType, params map[string]string) (string, error) {
+	if !c.isKnownGrantType(grant) {
+		return "", ErrUnknownGrantType
+	}
+
+	return c.loginURL(grant, toValues(params))
+}
+
+func toValues(params map[string]string) url.Values {
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+
+	return values
+}
+
+// isKnownGrantType проверяет, что grant_type стандартный или зарегистрирован через WithGrantTypes
+func (c *Client) isKnownGrantType(grant GrantType) bool {
+	switch grant {
+	case GrantTypeByCard, GrantTypeByPhone, GrantTypeBySMS, GrantTypeCardActivation:
+		return true
+	}
+
+	_, ok := c.grantTypes[grant]
+
+	return ok
+}
+
+// loginQuery собирает параметры логина с указанным grant_type. Нормализует пароль, номер телефона и способ
+// доставки одноразового кода в params
+func (c *Client) loginQuery(grant GrantType, params url.Values) (url.Values, error) {
+	if err := validateLoginParams(grant, params); err != nil {
+		return nil, err
+	}
+
+	if err := normalizeOTPChannel(grant, params); err != nil {
+		return nil, err
+	}
+
+	if _, ok := params["phoneNo"]; ok {
+		phoneNo, err := normalizePhoneField("phoneNo", params.Get("phoneNo"))
+		if err != nil {
+			return nil, err
+		}
+
+		params.Set("phoneNo", phoneNo)
+	}
+
+	if password := params.Get("password"); password != "" {
+		password, err := c.normalizePassword("password", password)
+		if err != nil {
+			return nil, err
+		}
+
+		params.Set("password", password)
+	}
+
+	query := url.Values{}
+	query.Set("grant_type", string(grant))
+	for key, values := range params {
+		query[key] = values
+	}
+
+	return query, nil
+}
+
+// validateLoginParams проверяет номер карты и телефона для стандартных grant_type до отправки запроса:
+// нужный параметр не пустой, и карта с телефоном не переданы одновременно. authbysms принимает один из них.
+// Параметры дополнительных grant_type из WithGrantTypes не проверяются.
+func validateLoginParams(grant GrantType, params url.Values) error {
+	var required []string
+	switch grant {
+	case GrantTypeByCard, GrantTypeCardActivation:
+		required = []string{"cardNo"}
+	case GrantTypeByPhone:
+		required = []string{"phoneNo"}
+	case GrantTypeBySMS:
+		// пустое значение проверяется для переданного параметра, иначе требуется любой из двух
+		for _, field := range []string{"cardNo", "phoneNo"} {
+			if _, ok := params[field]; ok {
+				required = append(required, field)
+			}
+		}
+		if len(required) == 0 {
+			required = []string{"cardNo", "phoneNo"}
+		}
+	default:
+		return nil
+	}
+
+	cardNo := strings.TrimSpace(params.Get("cardNo"))
+	phoneNo := strings.TrimSpace(params.Get("phoneNo"))
+
+	if cardNo != "" && phoneNo != "" {
+		return &ValidationError{Fields: map[string]string{
+			"cardNo":  "must not be combined with phoneNo",
+			"phoneNo": "must not be combined with cardNo",
+		}}
+	}
+
+	for _, field := range required {
+		if strings.TrimSpace(params.Get(field)) != "" {
+			return nil
+		}
+	}
+
+	fields := map[string]string{}
+	for _, field := range required {
+		fields[field] = "must not be empty"
+		if len(required) > 1 {
+			fields[field] = "either cardNo or phoneNo is required"
+		}
+	}
+
+	return &ValidationError{Fields: fields}
+}
+
+// loginURL собирает адрес логина с параметрами в query string
+func (c *Client) loginURL(grant GrantType, params url.Values) (string, error) {
+	query, err := c.loginQuery(grant, params)
+	if err != nil {
+		return "", err
+	}
+
+	return c.endpoint("/login") + "?" + query.Encode(), nil
+}
+
+// newLoginRequest создает запрос на логин. Параметры передаются в query string, а для grant_type
+// из WithFormLogin в теле application/x-www-form-urlencoded
+func (c *Client) newLoginRequest(grant GrantType, params url.Values) (*http.Request, error) {
+	if !c.isFormLogin(grant) {
+		u, err := c.loginURL(grant, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return http.NewRequest(c.loginMethod(grant), u, nil)
+	}
+
+	query, err := c.loginQuery(grant, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint("/login"), strings.NewReader(query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
+
+// loginMethod возвращает http метод логина для grant, см. WithLoginMethod
+func (c *Client) loginMethod(grant GrantType) string {
+	if method, ok := c.loginMethods[grant]; ok {
+		return method
+	}
+
+	return "POST"
+}
+
+// isFormLogin проверяет, что параметры логина grant нужно отправлять в теле запроса
+func (c *Client) isFormLogin(grant GrantType) bool {
+	if c.formLoginGrants == nil {
+		return false
+	}
+
+	if len(c.formLoginGrants) == 0 {
+		return true
+	}
+
+	_, ok := c.formLoginGrants[grant]
+
+	return ok
+}
+
+// signIn выполняет запрос на логин с указанным grant_type и параметрами
+func (c *Client) signIn(operation string, grant GrantType, params url.Values, opts ...CallOption) (*AccessToken, error) {
+	var token *AccessToken
+	err := applyLoginParams(params, opts)
+	if err == nil {
+		applyOTPChannel(grant, params, opts)
+
+		token, err = c.requestToken(operation, grant, params, opts...)
+		if grant == GrantTypeBySMS && params.Get("phoneNo") != "" {
+			err = asNotRegisteredError(err)
+		}
+	}
+
+	success, failure := EventLogin, EventLoginFailed
+	if operation == "Reauthenticate" {
+		success, failure = EventRefresh, EventRefreshFailed
+	}
+
+	subject := params.Get("cardNo") + params.Get("phoneNo")
+	if err != nil {
+		c.emit(failure, operation, subject, err)
+		return nil, err
+	}
+
+	c.emit(success, operation, subject, nil)
+
+	return token, nil
+}
+
+// reservedLoginParams параметры логина, которые задает сам клиент и нельзя передать через WithLoginParams
+var reservedLoginParams = []string{"grant_type", "cardNo", "phoneNo", "password", "otp", otpChannelParam}
+
+// applyLoginParams добавляет в params дополнительные параметры из WithLoginParams. Совпадение с параметрами
+// логина возвращает *ValidationError. Параметр, уже переданный с тем же значением (например, из GrantParams
+// при Reauthenticate), не считается совпадением
+func applyLoginParams(params url.Values, opts []CallOption) error {
+	var call callOptions
+	for _, opt := range opts {
+		opt(&call)
+	}
+
+	fields := map[string]string{}
+	for key, value := range call.loginParams {
+		_, exists := params[key]
+		if containsString(reservedLoginParams, key) || exists && params.Get(key) != value {
+			fields[key] = "conflicts with a login parameter"
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	for key, value := range call.loginParams {
+		params.Set(key, value)
+	}
+
+	return nil
+}
+
+// notRegisteredCodes коды ошибок комарха, которыми он отвечает на вход по номеру, для которого нет участника
+var notRegisteredCodes = map[string]struct{}{
+	"USER_NOT_FOUND":        {},
+	"MEMBER_NOT_FOUND":      {},
+	"MEMBER_NOT_REGISTERED": {},
+}
+
+// asNotRegisteredError переводит ответ комарха об отсутствии участника (404 или код ошибки из notRegisteredCodes)
+// в ErrMemberNotRegistered. Применяется только ко входу по номеру телефона: для карты 404 означает неизвестную
+// карту, а не отсутствие регистрации. Остальные ошибки возвращаются без изменений
+func asNotRegisteredError(err error) error {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+
+	if httpErr.StatusCode == http.StatusNotFound {
+		return ErrMemberNotRegistered
+	}
+
+	if httpErr.APIError != nil {
+		if _, ok := notRegisteredCodes[strings.ToUpper(httpErr.APIError.Code)]; ok {
+			return ErrMemberNotRegistered
+		}
+	}
+
+	return err
+}
+
+// requestToken выполняет запрос на логин и разбирает токен из ответа
+func (c *Client) requestToken(operation string, grant GrantType, params url.Values, opts ...CallOption) (*AccessToken, error) {
+	req, err := c.newLoginRequest(grant, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.basicAuth(operation, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(operation, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	publicToken, err := c.parseAccessToken(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.authResponseHeaders) > 0 {
+		publicToken.ResponseHeaders = map[string]string{}
+		for _, name := range c.authResponseHeaders {
+			if value := resp.Header.Get(name); value != "" {
+				publicToken.ResponseHeaders[name] = value
+			}
+		}
+	}
+
+	publicToken.Grant = grant
+	publicToken.GrantParams = map[string]string{}
+	for key := range params {
+		// пароль и одноразовый код не сохраняются в токене
+		if key == "password" || key == "otp" {
+			continue
+		}
+		publicToken.GrantParams[key] = params.Get(key)
+	}
+
+	if password := params.Get("password"); password != "" && c.keepPasswords {
+		c.rememberPassword(*publicToken, password)
+	}
+
+	return publicToken, nil
+}
+
+// Reauthenticate получает новый токен, повторяя логин, которым был получен oldToken.
+//
+// Для входа без пароля (authbysms) логин повторяется по сохраненному в токене номеру карты или телефона.
+// Для входа по паролю (authbycard, authbyphone) нужен пароль, который клиент хранит в памяти только
+// при включенной опции WithReauthentication, иначе возвращается ErrReauthenticationUnavailable.
+// Активацию карты (cardactivation) повторить нельзя, для таких токенов возвращается ErrReauthenticationUnavailable.
+func (c *Client) Reauthenticate(oldToken AccessToken, opts ...CallOption) (*AccessToken, error) {
+	params, err := c.reauthenticationParams(oldToken)
+	if err != nil {
+		c.emit(EventRefreshFailed, "Reauthenticate", tokenSubject(oldToken), err)
+		return nil, err
+	}
+
+	newToken, err := c.signIn("Reauthenticate", oldToken.Grant, params, opts...)
+	if err != nil && isRefreshRejected(err) {
+		// комарх больше не принимает пароль, хранить его незачем
+		c.forgetPassword(oldToken)
+	}
+
+	return newToken, err
+}
+
+// reauthenticationParams восстанавливает параметры логина, которым был получен oldToken
+func (c *Client) reauthenticationParams(oldToken AccessToken) (url.Values, error) {
+	if oldToken.Grant == "" || len(oldToken.GrantParams) == 0 {
+		return nil, ErrReauthenticationUnavailable
+	}
+
+	params := url.Values{}
+	for key, value := range oldToken.GrantParams {
+		params.Set(key, value)
+	}
+
+	switch oldToken.Grant {
+	case GrantTypeBySMS:
+	case GrantTypeByCard, GrantTypeByPhone:
+		password, ok := c.lookupPassword(oldToken, time.Now())
+		if !ok {
+			return nil, ErrReauthenticationUnavailable
+		}
+
+		params.Set("password", password)
+	default:
+		return nil, ErrReauthenticationUnavailable
+	}
+
+	return params, nil
+}
+
+// storedPassword пароль пользователя для Reauthenticate и время, после которого он удаляется.
+// Нулевое время означает, что пароль хранится до SignOut
+type storedPassword struct {
+	password  string
+	expiresAt time.Time
+}
+
+// rememberPassword сохраняет пароль, которым получен accessToken. Пароль хранится еще одно время жизни токена
+// после его истечения, чтобы Reauthenticate успел повторить вход. Заодно удаляются пароли с истекшим сроком,
+// так что в памяти остаются только пароли действующих сессий.
+func (c *Client) rememberPassword(accessToken AccessToken, password string) {
+	now := time.Now()
+
+	entry := storedPassword{password: password}
+	if !accessToken.ExpiresAt.IsZero() {
+		issuedAt := accessToken.IssuedAt
+		if issuedAt.IsZero() {
+			issuedAt = now
+		}
+		entry.expiresAt = accessToken.ExpiresAt.Add(accessToken.ExpiresAt.Sub(issuedAt))
+	}
+
+	c.passwordsMu.Lock()
+	defer c.passwordsMu.Unlock()
+
+	for key, stored := range c.passwords {
+		if stored.expired(now) {
+			delete(c.passwords, key)
+		}
+	}
+	c.passwords[credentialsKey(accessToken.Grant, accessToken.GrantParams)] = entry
+}
+
+// lookupPassword возвращает сохраненный пароль, которым получен accessToken. Пароль с истекшим сроком удаляется
+func (c *Client) lookupPassword(accessToken AccessToken, now time.Time) (string, bool) {
+	key := credentialsKey(accessToken.Grant, accessToken.GrantParams)
+
+	c.passwordsMu.Lock()
+	defer c.passwordsMu.Unlock()
+
+	stored, ok := c.passwords[key]
+	if !ok {
+		return "", false
+	}
+
+	if stored.expired(now) {
+		delete(c.passwords, key)
+		return "", false
+	}
+
+	return stored.password, true
+}
+
+// forgetPassword удаляет сохраненный пароль, которым получен accessToken
+func (c *Client) forgetPassword(accessToken AccessToken) {
+	if accessToken.Grant == "" {
+		return
+	}
+
+	c.passwordsMu.Lock()
+	delete(c.passwords, credentialsKey(accessToken.Grant, accessToken.GrantParams))
+	c.passwordsMu.Unlock()
+}
+
+func (p storedPassword) expired(now time.Time) bool {
+	return !p.expiresAt.IsZero() && now.After(p.expiresAt)
+}
+
+// credentialsKey ключ для хранения пароля пользователя
+func credentialsKey(grant GrantType, params map[string]string) string {
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+
+	return string(grant) + "?" + values.Encode()
+}
+
+// ValidateToken проверяет, что комарх еще принимает токен, без побочных эффектов
+func (c *Client) ValidateToken(accessToken AccessToken, opts ...CallOption) (bool, error) {
+	return c.ValidateTokenContext(context.Background(), accessToken, opts...)
+}
+
+// ValidateTokenContext проверяет, что комарх еще принимает токен: запрашивает состояние карты, самый легкий
+// метод с авторизацией по токену. Ответ 401 означает, что токен недействителен, и возвращается false без ошибки.
+// Прочие ошибки (сеть, 5xx) возвращаются как есть: по ним нельзя судить о токене. Кэш WithBalanceCache
+// не используется. Проверка нужна после загрузки токенов из хранилища, локальный ExpiresAt ее не заменяет.
+// Если accessToken пустой, проверяется токен из контекста.
+func (c *Client) ValidateTokenContext(ctx context.Context, accessToken AccessToken, opts ...CallOption) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint("/resources/cards/status"), nil)
+	if err != nil {
+		return false, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return false, err
+	}
+
+	resp, err := c.do("ValidateToken", req, opts...)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.emit(EventTokenExpired, "ValidateToken", tokenSubject(c.resolveToken(ctx, accessToken)), nil)
+		return false, nil
+	}
+
+	if err := checkResponse(resp); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
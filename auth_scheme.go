@@ -0,0 +1,21 @@
+package comarch
+
+// defaultAuthScheme схема заголовка Authorization для токена участника по умолчанию
+const defaultAuthScheme = "Bearer"
+
+// AuthorizationHeader возвращает значение заголовка Authorization для токена, такое же, как отправляет
+// выдавший его клиент: схема из WithAuthScheme и значение токена. Нужен, чтобы передавать токен другим
+// сервисам, которые обращаются к комарху сами. Для токенов без Scheme используется Bearer
+func (t AccessToken) AuthorizationHeader() string {
+	scheme := t.Scheme
+	if scheme == "" {
+		scheme = defaultAuthScheme
+	}
+
+	return scheme + " " + t.Value
+}
+
+// makeAuthHeader возвращает заголовок Authorization для запросов клиента со схемой клиента
+func (c *Client) makeAuthHeader(accessToken AccessToken) string {
+	return c.authScheme + " " + accessToken.Value
+}
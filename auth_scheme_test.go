@@ -0,0 +1,57 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestAccessToken_AuthorizationHeader(t *testing.T) {
+	var auth string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			tokenHandler(w, r)
+		default:
+			auth = r.Header.Get("Authorization")
+			w.Write([]byte(`{}`))
+		}
+	}
+
+	t.Run("default_bearer", func(t *testing.T) {
+		c := newTestServer(t, handler)
+
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+		assert.Equal(t, "Bearer "+token.Value, token.AuthorizationHeader())
+
+		_, err = c.GetBalanceInfo(*token)
+		assert.Nil(t, err)
+		assert.Equal(t, token.AuthorizationHeader(), auth)
+	})
+
+	t.Run("custom_scheme", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithAuthScheme("Token"))
+
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+		assert.Equal(t, "Token "+token.Value, token.AuthorizationHeader())
+
+		_, err = c.GetBalanceInfo(*token)
+		assert.Nil(t, err)
+		assert.Equal(t, token.AuthorizationHeader(), auth)
+	})
+
+	t.Run("manual_token", func(t *testing.T) {
+		assert.Equal(t, "Bearer token", testAccessToken.AuthorizationHeader())
+	})
+
+	t.Run("invalid_scheme", func(t *testing.T) {
+		for _, scheme := range []string{"", "Bearer token"} {
+			_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithAuthScheme(scheme))
+			assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration), scheme)
+		}
+	})
+}
@@ -0,0 +1,549 @@
+package comarch_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_SignIn(t *testing.T) {
+	const customGrant comarch.GrantType = "authbyemail"
+
+	var query string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		tokenHandler(w, r)
+	}
+
+	t.Run("unknown_grant", func(t *testing.T) {
+		c := newTestServer(t, handler)
+
+		_, err := c.SignIn(customGrant, map[string]string{"email": "user@example.com"})
+		assert.Equal(t, comarch.ErrUnknownGrantType, err)
+	})
+
+	t.Run("registered_grant", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithGrantTypes(customGrant))
+
+		token, err := c.SignIn(customGrant, map[string]string{"email": "user@example.com"})
+		assert.Nil(t, err)
+		assert.Equal(t, customGrant, token.Grant)
+		assert.Equal(t, "email=user%40example.com&grant_type=authbyemail", query)
+	})
+
+	t.Run("standard_grant", func(t *testing.T) {
+		c := newTestServer(t, handler)
+
+		_, err := c.SignIn(comarch.GrantTypeByCard, map[string]string{"cardNo": testCredentialsCardNo, "password": testCredentialsPassword})
+		assert.Nil(t, err)
+		assert.Equal(t, "cardNo="+testCredentialsCardNo+"&grant_type=authbycard&password="+testCredentialsPassword, query)
+	})
+}
+
+func TestWithAuthResponseHeaders(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-Session-Scope", "member")
+		w.Header().Set("X-Other", "ignored")
+		tokenHandler(w, r)
+	}
+
+	c := newTestServer(t, handler, comarch.WithAuthResponseHeaders("x-ratelimit-remaining", "X-Session-Scope", "X-Missing"))
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"X-Ratelimit-Remaining": "10", "X-Session-Scope": "member"}, token.ResponseHeaders)
+
+	c = newTestServer(t, handler)
+	token, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Nil(t, token.ResponseHeaders)
+}
+
+func TestWithSessionCookieNames(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "_ga", Value: "tracking"})
+		tokenHandler(w, r)
+	}
+
+	c := newTestServer(t, handler, comarch.WithSessionCookieNames([]string{"JSESSIONID"}))
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"JSESSIONID": "session"}, token.Cookies)
+
+	c = newTestServer(t, handler)
+	token, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"JSESSIONID": "session", "_ga": "tracking"}, token.Cookies)
+}
+
+func TestClient_BuildLoginURL(t *testing.T) {
+	var requested string
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		tokenHandler(w, r)
+	}, comarch.WithAPIPrefix("/loyalty/api"))
+
+	u, err := c.BuildLoginURL(comarch.GrantTypeByCard, map[string]string{"cardNo": testCredentialsCardNo, "password": testCredentialsPassword})
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(u, requested))
+	assert.True(t, strings.HasPrefix(requested, "/loyalty/api/login?"))
+
+	_, err = c.BuildLoginURL("custom", nil)
+	assert.Equal(t, comarch.ErrUnknownGrantType, err)
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithAPIPrefix("api/"))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestWithFormLogin(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []comarch.Option
+		grant comarch.GrantType
+		form  bool
+	}{
+		{name: "default", opts: nil, grant: comarch.GrantTypeByCard, form: false},
+		{name: "all", opts: []comarch.Option{comarch.WithFormLogin()}, grant: comarch.GrantTypeByCard, form: true},
+		{name: "listed", opts: []comarch.Option{comarch.WithFormLogin(comarch.GrantTypeByCard)}, grant: comarch.GrantTypeByCard, form: true},
+		{name: "not_listed", opts: []comarch.Option{comarch.WithFormLogin(comarch.GrantTypeByPhone)}, grant: comarch.GrantTypeByCard, form: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.form {
+					assert.Empty(t, r.URL.RawQuery)
+					assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+				} else {
+					assert.NotEmpty(t, r.URL.RawQuery)
+				}
+
+				assert.Nil(t, r.ParseForm())
+				assert.Equal(t, string(tt.grant), r.Form.Get("grant_type"))
+				assert.Equal(t, testCredentialsCardNo, r.Form.Get("cardNo"))
+				assert.Equal(t, testCredentialsPassword, r.Form.Get("password"))
+
+				tokenHandler(w, r)
+			}, tt.opts...)
+
+			_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestAccessToken_Scopes(t *testing.T) {
+	jwt := func(payload string) string {
+		return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		scopes []string
+	}{
+		{name: "opaque", body: `{"access_token":"token"}`, scopes: nil},
+		{name: "response_scope", body: `{"access_token":"token","scope":"balance coupons"}`, scopes: []string{"balance", "coupons"}},
+		{name: "jwt_string", body: `{"access_token":"` + jwt(`{"scope":"balance profile"}`) + `"}`, scopes: []string{"balance", "profile"}},
+		{name: "jwt_array", body: `{"access_token":"` + jwt(`{"scp":["balance"]}`) + `"}`, scopes: []string{"balance"}},
+		{name: "jwt_without_scope", body: `{"access_token":"` + jwt(`{"sub":"1"}`) + `"}`, scopes: nil},
+		{name: "broken_jwt", body: `{"access_token":"a.!!!.c"}`, scopes: nil},
+		{name: "unknown_scope_type", body: `{"access_token":"token","scope":42}`, scopes: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			})
+
+			token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.scopes, token.Scopes)
+			if len(tt.scopes) > 0 {
+				assert.True(t, token.HasScope(tt.scopes[0]))
+			}
+			assert.False(t, token.HasScope("admin"))
+		})
+	}
+}
+
+func TestClient_ValidateToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		valid  bool
+		err    error
+	}{
+		{name: "valid", status: http.StatusOK, valid: true},
+		{name: "expired", status: http.StatusUnauthorized, valid: false},
+		{name: "failure", status: http.StatusInternalServerError, valid: false, err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				w.WriteHeader(tt.status)
+			})
+
+			valid, err := c.ValidateToken(testAccessToken)
+			assert.Equal(t, tt.valid, valid)
+			if tt.err == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.err))
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	_, err := c.ValidateTokenContext(ctx, testAccessToken)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestAccessToken_Extra(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":60,"member_tier":"gold","promo":{"active":true}}`))
+	})
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", token.Value)
+	assert.Equal(t, map[string]json.RawMessage{
+		"member_tier": json.RawMessage(`"gold"`),
+		"promo":       json.RawMessage(`{"active":true}`),
+	}, token.Extra)
+
+	c = newTestServer(t, tokenHandler)
+	token, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Nil(t, token.Extra)
+}
+
+func TestAccessToken_Features(t *testing.T) {
+	tests := []struct {
+		name     string
+		features string
+		expected map[string]bool
+	}{
+		{name: "object", features: `,"features":{"beta":true,"promo":"Y","legacy":false,"broken":"maybe"}`, expected: map[string]bool{"beta": true, "promo": true, "legacy": false}},
+		{name: "list", features: `,"features":["beta","promo"]`, expected: map[string]bool{"beta": true, "promo": true}},
+		{name: "absent", features: ``, expected: nil},
+		{name: "null", features: `,"features":null`, expected: nil},
+		{name: "unknown_format", features: `,"features":"beta"`, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":60` + tt.features + `}`))
+			})
+
+			token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, token.Features)
+			assert.Equal(t, tt.expected["beta"], token.HasFeature("beta"))
+			assert.Nil(t, token.Extra)
+		})
+	}
+}
+
+func TestClient_SignIn_ValidatesCardAndPhone(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request must not be sent")
+	})
+
+	tests := []struct {
+		name   string
+		signIn func() error
+		fields []string
+	}{
+		{name: "empty_phone", signIn: func() error {
+			_, err := c.SignInByPhoneOnly("")
+			return err
+		}, fields: []string{"phoneNo"}},
+		{name: "blank_card", signIn: func() error {
+			_, err := c.SignInByCardNoOnly("  ")
+			return err
+		}, fields: []string{"cardNo"}},
+		{name: "empty_card_with_password", signIn: func() error {
+			_, err := c.SignInByCard("", testCredentialsPassword)
+			return err
+		}, fields: []string{"cardNo"}},
+		{name: "activation_without_card", signIn: func() error {
+			_, err := c.ActivateCardNo("")
+			return err
+		}, fields: []string{"cardNo"}},
+		{name: "sms_without_both", signIn: func() error {
+			_, err := c.SignIn(comarch.GrantTypeBySMS, map[string]string{})
+			return err
+		}, fields: []string{"cardNo", "phoneNo"}},
+		{name: "sms_with_both", signIn: func() error {
+			_, err := c.SignIn(comarch.GrantTypeBySMS, map[string]string{"cardNo": testCredentialsCardNo, "phoneNo": "79990001122"})
+			return err
+		}, fields: []string{"cardNo", "phoneNo"}},
+		{name: "card_with_phone", signIn: func() error {
+			_, err := c.SignIn(comarch.GrantTypeByCard, map[string]string{"cardNo": testCredentialsCardNo, "phoneNo": "79990001122", "password": testCredentialsPassword})
+			return err
+		}, fields: []string{"cardNo", "phoneNo"}},
+		{name: "build_url", signIn: func() error {
+			_, err := c.BuildLoginURL(comarch.GrantTypeByPhone, map[string]string{"cardNo": testCredentialsCardNo})
+			return err
+		}, fields: []string{"phoneNo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.signIn()
+
+			var validationErr *comarch.ValidationError
+			if assert.True(t, errors.As(err, &validationErr)) {
+				for _, field := range tt.fields {
+					assert.Contains(t, validationErr.Fields, field)
+				}
+				assert.Len(t, validationErr.Fields, len(tt.fields))
+			}
+		})
+	}
+}
+
+func TestClient_SignInByPhoneOnly_NotRegistered(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    error
+	}{
+		{name: "not_found", status: http.StatusNotFound, err: comarch.ErrMemberNotRegistered},
+		{name: "user_not_found_code", status: http.StatusBadRequest, body: `{"errorCode":"USER_NOT_FOUND","errorMessage":"User not found"}`, err: comarch.ErrMemberNotRegistered},
+		{name: "member_not_registered_code", status: http.StatusUnauthorized, body: `{"errorCode":"member_not_registered"}`, err: comarch.ErrMemberNotRegistered},
+		{name: "other_error", status: http.StatusBadRequest, body: `{"errorCode":"INVALID_REQUEST"}`, err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "authbysms", r.URL.Query().Get("grant_type"))
+				assert.Equal(t, "79990001122", r.URL.Query().Get("phoneNo"))
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			token, err := c.SignInByPhoneOnly("79990001122")
+			assert.Nil(t, token)
+			assert.True(t, errors.Is(err, tt.err), "got %v", err)
+			if tt.err != comarch.ErrMemberNotRegistered {
+				assert.False(t, errors.Is(err, comarch.ErrMemberNotRegistered))
+			}
+		})
+	}
+
+	t.Run("card_not_found_is_not_mapped", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		_, err := c.SignInByCardNoOnly(testCredentialsCardNo)
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, comarch.ErrMemberNotRegistered))
+	})
+}
+
+func TestClient_GrantTypeFlows(t *testing.T) {
+	tests := []struct {
+		name   string
+		grant  comarch.GrantType
+		signIn func(c *comarch.Client) (*comarch.AccessToken, error)
+		query  url.Values
+	}{
+		{
+			name:  "by_card",
+			grant: comarch.GrantTypeByCard,
+			signIn: func(c *comarch.Client) (*comarch.AccessToken, error) {
+				return c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			},
+			query: url.Values{"grant_type": {"authbycard"}, "cardNo": {testCredentialsCardNo}, "password": {testCredentialsPassword}},
+		},
+		{
+			name:  "by_phone",
+			grant: comarch.GrantTypeByPhone,
+			signIn: func(c *comarch.Client) (*comarch.AccessToken, error) {
+				return c.SignInByPhone("+7 999 000-11-22", testCredentialsPassword)
+			},
+			query: url.Values{"grant_type": {"authbyphone"}, "phoneNo": {"79990001122"}, "password": {testCredentialsPassword}},
+		},
+		{
+			name:  "by_sms_phone",
+			grant: comarch.GrantTypeBySMS,
+			signIn: func(c *comarch.Client) (*comarch.AccessToken, error) {
+				return c.SignInByPhoneOnly("89990001122")
+			},
+			query: url.Values{"grant_type": {"authbysms"}, "phoneNo": {"79990001122"}, "channel": {"text"}},
+		},
+		{
+			name:  "by_sms_card",
+			grant: comarch.GrantTypeBySMS,
+			signIn: func(c *comarch.Client) (*comarch.AccessToken, error) {
+				return c.SignInByCardNoOnly(testCredentialsCardNo)
+			},
+			query: url.Values{"grant_type": {"authbysms"}, "cardNo": {testCredentialsCardNo}, "channel": {"text"}},
+		},
+		{
+			name:  "card_activation",
+			grant: comarch.GrantTypeCardActivation,
+			signIn: func(c *comarch.Client) (*comarch.AccessToken, error) {
+				return c.ActivateCardNo(testCredentialsCardNo)
+			},
+			query: url.Values{"grant_type": {"cardactivation"}, "cardNo": {testCredentialsCardNo}, "channel": {"text"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/cwaapiinterface/login", r.URL.Path)
+				assert.Equal(t, tt.query, r.URL.Query())
+
+				username, password, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, testUsername, username)
+				assert.Equal(t, testPassword, password)
+
+				tokenHandler(w, r)
+			})
+
+			before := time.Now()
+			token, err := tt.signIn(c)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, requests)
+			if !assert.NotNil(t, token) {
+				return
+			}
+
+			assert.Equal(t, "token", token.Value)
+			assert.Equal(t, tt.grant, token.Grant)
+			assert.Equal(t, map[string]string{"JSESSIONID": "session"}, token.Cookies)
+			assert.False(t, token.ExpiresAt.Before(before.Add(time.Hour)))
+			assert.False(t, token.ExpiresAt.After(time.Now().Add(time.Hour)))
+			assert.NotContains(t, token.GrantParams, "password")
+		})
+	}
+}
+
+func TestWithLoginMethod(t *testing.T) {
+	var method string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		assert.Equal(t, "authbysms", r.URL.Query().Get("grant_type"))
+		tokenHandler(w, r)
+	}
+
+	t.Run("default_post", func(t *testing.T) {
+		c := newTestServer(t, handler)
+
+		_, err := c.SignInByPhoneOnly("79990001122")
+		assert.Nil(t, err)
+		assert.Equal(t, "POST", method)
+	})
+
+	t.Run("get_for_grant", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithLoginMethod(comarch.GrantTypeBySMS, "get"))
+
+		_, err := c.SignIn(comarch.GrantTypeBySMS, map[string]string{"phoneNo": "79990001122"})
+		assert.Nil(t, err)
+		assert.Equal(t, "GET", method)
+	})
+
+	t.Run("other_grant_unchanged", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			tokenHandler(w, r)
+		}, comarch.WithLoginMethod(comarch.GrantTypeBySMS, "GET"))
+
+		_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+		assert.Equal(t, "POST", method)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, opts := range map[string][]comarch.Option{
+			"method":        {comarch.WithLoginMethod(comarch.GrantTypeBySMS, "PUT")},
+			"unknown_grant": {comarch.WithLoginMethod("authbyemail", "GET")},
+			"form_login":    {comarch.WithLoginMethod(comarch.GrantTypeBySMS, "GET"), comarch.WithFormLogin()},
+		} {
+			_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, opts...)
+			assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration), name)
+		}
+
+		_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil,
+			comarch.WithGrantTypes("authbyemail"),
+			comarch.WithLoginMethod("authbyemail", "GET"),
+		)
+		assert.Nil(t, err)
+	})
+}
+
+func TestWithLoginParams(t *testing.T) {
+	var queries []url.Values
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		tokenHandler(w, r)
+	}, comarch.WithReauthentication())
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword,
+		comarch.WithLoginParams(map[string]string{"channelCode": "MOBILE"}),
+		comarch.WithLoginParams(map[string]string{"campaign": "spring"}),
+	)
+	assert.Nil(t, err)
+	if assert.Len(t, queries, 1) {
+		assert.Equal(t, url.Values{
+			"grant_type":  {"authbycard"},
+			"cardNo":      {testCredentialsCardNo},
+			"password":    {testCredentialsPassword},
+			"channelCode": {"MOBILE"},
+			"campaign":    {"spring"},
+		}, queries[0])
+	}
+	assert.Equal(t, "MOBILE", token.GrantParams["channelCode"])
+
+	_, err = c.Reauthenticate(*token, comarch.WithLoginParams(map[string]string{"channelCode": "MOBILE"}))
+	assert.Nil(t, err)
+	if assert.Len(t, queries, 2) {
+		assert.Equal(t, queries[0], queries[1])
+	}
+
+	for _, params := range []map[string]string{{"cardNo": "other"}, {"grant_type": "authbyphone"}, {"channel": "voice"}} {
+		_, err := c.SignInByPhoneOnly("79990001122", comarch.WithLoginParams(params))
+
+		var validationErr *comarch.ValidationError
+		assert.True(t, errors.As(err, &validationErr), params)
+	}
+
+	_, err = c.SignIn(comarch.GrantTypeBySMS, map[string]string{"phoneNo": "79990001122", "tag": "a"}, comarch.WithLoginParams(map[string]string{"tag": "b"}))
+	var validationErr *comarch.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Contains(t, validationErr.Fields, "tag")
+	}
+	assert.Len(t, queries, 2)
+}
@@ -0,0 +1,268 @@
+package comarch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuthenticatedClient методы Client от имени одного участника, токен которого хранится в TokenStore.
+// Методы принимают контекст первым параметром и сами берут токен из хранилища: истекший токен (по ExpiresAt)
+// обновляется через Reauthenticate до запроса, а на ответ 401 токен обновляется и запрос повторяется один раз.
+// Обновленный токен сохраняется в хранилище.
+//
+// Если действующий токен получить нельзя (токена нет в хранилище, Reauthenticate недоступен или комарх
+// отказал в логине), методы возвращают ошибку, соответствующую ErrUnauthorized, а токен удаляется из хранилища:
+// участнику нужно войти заново. Ошибки сети и 5xx при обновлении возвращаются как есть, токен остается.
+// Ограничения Reauthenticate (WithReauthentication для входа по паролю) действуют и здесь.
+//
+// Для методов, которых нет в AuthenticatedClient, токен можно получить через Token.
+type AuthenticatedClient struct {
+	client *Client
+	store  TokenStore
+	key    string
+
+	// не дает обновлять токен одновременно из нескольких горутин
+	refreshMu sync.Mutex
+}
+
+// Authenticated возвращает AuthenticatedClient участника, токен которого хранится в store под ключом key.
+// Токен кладется в хранилище через SetToken, например после SignIn*
+func (c *Client) Authenticated(store TokenStore, key string) *AuthenticatedClient {
+	return &AuthenticatedClient{client: c, store: store, key: key}
+}
+
+// SetToken сохраняет токен участника в хранилище
+func (ac *AuthenticatedClient) SetToken(ctx context.Context, accessToken AccessToken) error {
+	return ac.store.Save(ctx, ac.key, accessToken)
+}
+
+// Token возвращает действующий токен участника, при необходимости обновляя истекший
+func (ac *AuthenticatedClient) Token(ctx context.Context) (AccessToken, error) {
+	accessToken, ok, err := ac.store.Load(ctx, ac.key)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	if !ok {
+		return AccessToken{}, ErrUnauthorized
+	}
+
+	if isExpired(accessToken) {
+		ac.client.emit(EventTokenExpired, "AuthenticatedClient", tokenSubject(accessToken), nil)
+		return ac.refresh(ctx, accessToken)
+	}
+
+	return accessToken, nil
+}
+
+// refresh получает новый токен вместо oldToken. Если токен уже обновила другая горутина, возвращает его
+func (ac *AuthenticatedClient) refresh(ctx context.Context, oldToken AccessToken) (AccessToken, error) {
+	ac.refreshMu.Lock()
+	defer ac.refreshMu.Unlock()
+
+	current, ok, err := ac.store.Load(ctx, ac.key)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	if !ok {
+		return AccessToken{}, ErrUnauthorized
+	}
+
+	if current.Value != oldToken.Value && !isExpired(current) {
+		return current, nil
+	}
+
+	newToken, err := ac.client.Reauthenticate(current, withCallContext(ctx))
+	if err != nil {
+		if !isRefreshRejected(err) {
+			return AccessToken{}, err
+		}
+
+		if err := ac.store.Delete(ctx, ac.key); err != nil {
+			return AccessToken{}, err
+		}
+
+		return AccessToken{}, &unauthorizedError{err: err}
+	}
+
+	if err := ac.store.Save(ctx, ac.key, *newToken); err != nil {
+		return AccessToken{}, err
+	}
+
+	return *newToken, nil
+}
+
+// call выполняет fn с токеном участника. На ответ 401 обновляет токен и повторяет fn один раз
+func (ac *AuthenticatedClient) call(ctx context.Context, fn func(accessToken AccessToken) error) error {
+	accessToken, err := ac.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(accessToken)
+	if !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	ac.client.emit(EventTokenExpired, "AuthenticatedClient", tokenSubject(accessToken), nil)
+
+	accessToken, err = ac.refresh(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	return fn(accessToken)
+}
+
+// isExpired сообщает, что срок жизни токена истек. Токен без ExpiresAt считается бессрочным
+func isExpired(accessToken AccessToken) bool {
+	return !accessToken.ExpiresAt.IsZero() && !time.Now().Before(accessToken.ExpiresAt)
+}
+
+// isRefreshRejected сообщает, что токен нельзя обновить: Reauthenticate недоступен или комарх отказал в логине (4xx)
+func isRefreshRejected(err error) bool {
+	if errors.Is(err, ErrReauthenticationUnavailable) {
+		return true
+	}
+
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode >= http.StatusBadRequest &&
+		httpErr.StatusCode < http.StatusInternalServerError
+}
+
+// withContext добавляет к опциям вызова контекст ctx для методов Client без параметра ctx
+func withContext(ctx context.Context, opts []CallOption) []CallOption {
+	return append(append([]CallOption(nil), opts...), withCallContext(ctx))
+}
+
+// GetBalanceInfo получает данные о состоянии баланса участника, см. Client.GetBalanceInfo
+func (ac *AuthenticatedClient) GetBalanceInfo(ctx context.Context, opts ...CallOption) (*BalanceInfoResp, error) {
+	var balance *BalanceInfoResp
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		balance, err = ac.client.GetBalanceInfoContext(ctx, accessToken, opts...)
+		return err
+	})
+
+	return balance, err
+}
+
+// GetCardStatus получает состояние карты участника, см. Client.GetCardStatus
+func (ac *AuthenticatedClient) GetCardStatus(ctx context.Context, opts ...CallOption) (CardStatus, error) {
+	var status CardStatus
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		status, err = ac.client.GetCardStatus(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return status, err
+}
+
+// GetCards получает карты участника, см. Client.GetCards
+func (ac *AuthenticatedClient) GetCards(ctx context.Context, opts ...CallOption) ([]Card, error) {
+	var cards []Card
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		cards, err = ac.client.GetCards(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return cards, err
+}
+
+// GetLastVisit получает время последнего посещения участника, см. Client.GetLastVisit
+func (ac *AuthenticatedClient) GetLastVisit(ctx context.Context, opts ...CallOption) (time.Time, error) {
+	var lastVisit time.Time
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		lastVisit, err = ac.client.GetLastVisit(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return lastVisit, err
+}
+
+// GetCardHolder получает анкету участника, см. Client.GetCardHolder
+func (ac *AuthenticatedClient) GetCardHolder(ctx context.Context, opts ...CallOption) (*PersonalData, error) {
+	var personalData *PersonalData
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		personalData, err = ac.client.GetCardHolder(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return personalData, err
+}
+
+// UpdateCardHolder обновляет анкету участника, см. Client.UpdateCardHolder
+func (ac *AuthenticatedClient) UpdateCardHolder(ctx context.Context, personalData PersonalData, opts ...CallOption) error {
+	return ac.call(ctx, func(accessToken AccessToken) error {
+		return ac.client.UpdateCardHolder(accessToken, personalData, withContext(ctx, opts)...)
+	})
+}
+
+// GetCoupons получает купоны участника, см. Client.GetCoupons
+func (ac *AuthenticatedClient) GetCoupons(ctx context.Context, opts ...CallOption) ([]Coupon, error) {
+	var coupons []Coupon
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		coupons, err = ac.client.GetCoupons(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return coupons, err
+}
+
+// ActivateCoupon активирует купон участника, см. Client.ActivateCoupon
+func (ac *AuthenticatedClient) ActivateCoupon(ctx context.Context, couponCode string, opts ...CallOption) error {
+	return ac.call(ctx, func(accessToken AccessToken) error {
+		return ac.client.ActivateCoupon(accessToken, couponCode, withContext(ctx, opts)...)
+	})
+}
+
+// GetConsents получает согласия участника на рассылки, см. Client.GetConsents
+func (ac *AuthenticatedClient) GetConsents(ctx context.Context, opts ...CallOption) (*NotificationPreferences, error) {
+	var prefs *NotificationPreferences
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		prefs, err = ac.client.GetConsents(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return prefs, err
+}
+
+// UpdateConsents обновляет согласия участника на рассылки, см. Client.UpdateConsents
+func (ac *AuthenticatedClient) UpdateConsents(ctx context.Context, prefs NotificationPreferences, opts ...CallOption) error {
+	return ac.call(ctx, func(accessToken AccessToken) error {
+		return ac.client.UpdateConsents(accessToken, prefs, withContext(ctx, opts)...)
+	})
+}
+
+// GetMemberTier получает уровень участника, см. Client.GetMemberTier
+func (ac *AuthenticatedClient) GetMemberTier(ctx context.Context, opts ...CallOption) (Tier, error) {
+	var tier Tier
+	err := ac.call(ctx, func(accessToken AccessToken) (err error) {
+		tier, err = ac.client.GetMemberTier(accessToken, withContext(ctx, opts)...)
+		return err
+	})
+
+	return tier, err
+}
+
+// SignOut завершает сессию участника и удаляет токен из хранилища. Токен не обновляется: уже недействительный
+// токен (401) только удаляется из хранилища
+func (ac *AuthenticatedClient) SignOut(ctx context.Context, opts ...CallOption) error {
+	accessToken, ok, err := ac.store.Load(ctx, ac.key)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	if err := ac.client.SignOutContext(ctx, accessToken, opts...); err != nil && !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	return ac.store.Delete(ctx, ac.key)
+}
@@ -0,0 +1,158 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// authenticatedServer комарх, который отвечает на баланс статусами из balanceStatuses по очереди (затем 200),
+// а на логин статусом loginStatus
+type authenticatedServer struct {
+	loginStatus     int
+	balanceStatuses []int
+
+	logins   int
+	balances int
+}
+
+func (s *authenticatedServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/login") {
+			s.logins++
+			if s.loginStatus != 0 && s.logins > 1 {
+				w.WriteHeader(s.loginStatus)
+				return
+			}
+			tokenHandler(w, r)
+			return
+		}
+
+		assert.Equal(t, "/cwaapiinterface/resources/balanceinfo", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		s.balances++
+		if s.balances <= len(s.balanceStatuses) {
+			w.WriteHeader(s.balanceStatuses[s.balances-1])
+			return
+		}
+		w.Write([]byte(`{"balanceInfo":{"balance":100}}`))
+	}
+}
+
+func TestAuthenticatedClient(t *testing.T) {
+	ctx := context.Background()
+
+	signIn := func(t *testing.T, c *comarch.Client) *comarch.AuthenticatedClient {
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ac := c.Authenticated(comarch.NewMemoryTokenStore(), testCredentialsCardNo)
+		assert.Nil(t, ac.SetToken(ctx, *token))
+
+		return ac
+	}
+
+	t.Run("valid_token", func(t *testing.T) {
+		srv := &authenticatedServer{}
+		c := newTestServer(t, srv.handler(t), comarch.WithReauthentication())
+		ac := signIn(t, c)
+
+		balance, err := ac.GetBalanceInfo(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 100, balance.BalanceInfo.Balance)
+		assert.Equal(t, 1, srv.logins)
+	})
+
+	t.Run("retry_on_401", func(t *testing.T) {
+		srv := &authenticatedServer{balanceStatuses: []int{http.StatusUnauthorized}}
+		c := newTestServer(t, srv.handler(t), comarch.WithReauthentication())
+		ac := signIn(t, c)
+
+		balance, err := ac.GetBalanceInfo(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 100, balance.BalanceInfo.Balance)
+		assert.Equal(t, 2, srv.logins)
+		assert.Equal(t, 2, srv.balances)
+	})
+
+	t.Run("expired_token", func(t *testing.T) {
+		srv := &authenticatedServer{}
+		c := newTestServer(t, srv.handler(t), comarch.WithReauthentication())
+		ac := signIn(t, c)
+
+		token, err := ac.Token(ctx)
+		assert.Nil(t, err)
+		token.IssuedAt = time.Now().Add(-time.Hour * 2)
+		token.ExpiresAt = time.Now().Add(-time.Hour)
+		assert.Nil(t, ac.SetToken(ctx, token))
+
+		_, err = ac.GetBalanceInfo(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, srv.logins)
+		assert.Equal(t, 1, srv.balances)
+
+		token, err = ac.Token(ctx)
+		assert.Nil(t, err)
+		assert.True(t, token.TimeToLive() > 0)
+	})
+
+	t.Run("refresh_rejected", func(t *testing.T) {
+		srv := &authenticatedServer{loginStatus: http.StatusUnauthorized, balanceStatuses: []int{http.StatusUnauthorized}}
+		c := newTestServer(t, srv.handler(t), comarch.WithReauthentication())
+		ac := signIn(t, c)
+
+		_, err := ac.GetBalanceInfo(ctx)
+		assert.True(t, errors.Is(err, comarch.ErrUnauthorized), "got %v", err)
+
+		var httpErr *comarch.HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+
+		// токен удален, участнику нужно войти заново
+		_, err = ac.GetBalanceInfo(ctx)
+		assert.True(t, errors.Is(err, comarch.ErrUnauthorized))
+		assert.Equal(t, 1, srv.balances)
+	})
+
+	t.Run("reauthentication_unavailable", func(t *testing.T) {
+		srv := &authenticatedServer{balanceStatuses: []int{http.StatusUnauthorized}}
+		c := newTestServer(t, srv.handler(t))
+		ac := signIn(t, c)
+
+		_, err := ac.GetBalanceInfo(ctx)
+		assert.True(t, errors.Is(err, comarch.ErrUnauthorized))
+		assert.True(t, errors.Is(err, comarch.ErrReauthenticationUnavailable))
+	})
+
+	t.Run("refresh_server_error", func(t *testing.T) {
+		srv := &authenticatedServer{loginStatus: http.StatusBadGateway, balanceStatuses: []int{http.StatusUnauthorized}}
+		c := newTestServer(t, srv.handler(t), comarch.WithReauthentication())
+		ac := signIn(t, c)
+
+		_, err := ac.GetBalanceInfo(ctx)
+		assert.False(t, errors.Is(err, comarch.ErrUnauthorized))
+		assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+
+		// токен остается в хранилище
+		_, err = ac.Token(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("no_token", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("request must not be sent")
+		})
+		ac := c.Authenticated(comarch.NewMemoryTokenStore(), testCredentialsCardNo)
+
+		_, err := ac.GetBalanceInfo(ctx)
+		assert.True(t, errors.Is(err, comarch.ErrUnauthorized))
+		assert.Nil(t, ac.SignOut(ctx))
+	})
+}
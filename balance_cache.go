@@ -0,0 +1,168 @@
+package comarch
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// balanceCacheEntry закэшированный ответ GetBalanceInfo и его валидаторы
+type balanceCacheEntry struct {
+	balance      *BalanceInfoResp
+	etag         string
+	lastModified string
+	// когда баланс последний раз подтвержден комархом
+	storedAt time.Time
+	// когда запись можно удалить
+	expiresAt time.Time
+}
+
+// setConditionalHeaders добавляет в запрос валидаторы закэшированного ответа
+func (e *balanceCacheEntry) setConditionalHeaders(req *http.Request) {
+	if e.etag != "" {
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	if e.lastModified != "" {
+		req.Header.Set("If-Modified-Since", e.lastModified)
+	}
+}
+
+// balanceCache кэш балансов по значению токена. Методы nil кэша ничего не делают
+type balanceCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*balanceCacheEntry
+	lastSweep time.Time
+}
+
+func newBalanceCache(ttl time.Duration) *balanceCache {
+	return &balanceCache{
+		ttl:     ttl,
+		entries: map[string]*balanceCacheEntry{},
+	}
+}
+
+// get возвращает запись кэша и признак того, что ее можно отдать без запроса к комарху.
+// Устаревшая запись возвращается, только если у нее есть валидаторы для условного запроса.
+func (c *balanceCache) get(key string) (*balanceCacheEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Since(entry.storedAt) < c.ttl {
+		return entry, true
+	}
+
+	if entry.etag == "" && entry.lastModified == "" {
+		return nil, false
+	}
+
+	return entry, false
+}
+
+// touch отмечает, что комарх подтвердил актуальность закэшированного баланса
+func (c *balanceCache) touch(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		entry.storedAt = time.Now()
+	}
+}
+
+// put сохраняет баланс, полученный по accessToken
+func (c *balanceCache) put(accessToken AccessToken, balance *BalanceInfoResp, resp *http.Response) {
+	if c == nil {
+		return
+	}
+
+	now := time.Now()
+	entry := &balanceCacheEntry{
+		balance:      balance.copy(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		storedAt:     now,
+		expiresAt:    accessToken.ExpiresAt,
+	}
+	if entry.expiresAt.IsZero() {
+		entry.expiresAt = now.Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[accessToken.Value] = entry
+
+	if now.Sub(c.lastSweep) >= c.ttl {
+		c.lastSweep = now
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
+
+// invalidate удаляет запись токена
+func (c *balanceCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// invalidateCard удаляет записи всех токенов, по которым был получен баланс карты cardNo
+func (c *balanceCache) invalidateCard(cardNo string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.balance.CardNo == cardNo {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// InvalidateBalance удаляет закэшированный баланс токена, следующий GetBalanceInfo сходит в комарх.
+// Нужен после изменения баллов в обход клиента, например после начисления за покупку на кассе.
+// Без WithBalanceCache ничего не делает.
+func (c *Client) InvalidateBalance(accessToken AccessToken) {
+	c.balanceCache.invalidate(accessToken.Value)
+}
+
+// InvalidateBalanceByCard удаляет закэшированные балансы карты по всем токенам.
+// Без WithBalanceCache ничего не делает.
+func (c *Client) InvalidateBalanceByCard(cardNo string) {
+	c.balanceCache.invalidateCard(cardNo)
+}
+
+// copy возвращает копию баланса, которую можно менять, не затрагивая кэш
+func (b *BalanceInfoResp) copy() *BalanceInfoResp {
+	res := *b
+	if b.ExpressPoints != nil {
+		res.ExpressPoints = make([]ExpressPoints, len(b.ExpressPoints))
+		copy(res.ExpressPoints, b.ExpressPoints)
+	}
+
+	return &res
+}
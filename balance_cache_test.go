@@ -0,0 +1,90 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithBalanceCache(t *testing.T) {
+	var requests, notModified int
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"cardNo":"1111","balanceInfo":{"balance":100}}`))
+	}, comarch.WithBalanceCache(time.Millisecond*50))
+
+	first, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, first.BalanceInfo.Balance)
+	assert.False(t, first.FromCache())
+
+	// изменения возвращенного значения не попадают в кэш
+	first.BalanceInfo.Balance = 0
+
+	second, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, second.BalanceInfo.Balance)
+	assert.True(t, second.FromCache())
+	assert.Equal(t, 1, requests)
+
+	time.Sleep(time.Millisecond * 60)
+
+	third, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, third.BalanceInfo.Balance)
+	// ответ 304 подтвержден комархом
+	assert.False(t, third.FromCache())
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified)
+
+	// другой токен кэшируется отдельно
+	_, err = c.GetBalanceInfo(comarch.AccessToken{Value: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, requests)
+}
+
+func TestClient_InvalidateBalance(t *testing.T) {
+	var requests int
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/resources/coupons/activation" {
+			return
+		}
+
+		requests++
+		w.Write([]byte(`{"cardNo":"1111","balanceInfo":{"balance":100}}`))
+	}, comarch.WithBalanceCache(time.Hour))
+
+	balance := func() {
+		_, err := c.GetBalanceInfo(testAccessToken)
+		assert.Nil(t, err)
+	}
+
+	balance()
+	balance()
+	assert.Equal(t, 1, requests)
+
+	c.InvalidateBalance(testAccessToken)
+	balance()
+	assert.Equal(t, 2, requests)
+
+	c.InvalidateBalanceByCard("2222")
+	balance()
+	assert.Equal(t, 2, requests)
+
+	c.InvalidateBalanceByCard("1111")
+	balance()
+	assert.Equal(t, 3, requests)
+
+	assert.Nil(t, c.ActivateCoupon(testAccessToken, "SALE"))
+	balance()
+	assert.Equal(t, 4, requests)
+}
@@ -0,0 +1,85 @@
+package comarch
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Шаг истории баланса для GetBalanceHistory
+const (
+	BalanceHistoryDaily  = "daily"
+	BalanceHistoryWeekly = "weekly"
+)
+
+// BalanceSnapshot баланс участника на дату
+type BalanceSnapshot struct {
+	// дата среза в часовом поясе WithLocation
+	Date time.Time
+	// баланс баллов
+	Balance int
+}
+
+type balanceSnapshot struct {
+	Date    string  `json:"date"`
+	Balance flexInt `json:"balance"`
+}
+
+// GetBalanceHistory получает срезы баланса участника за период [from, to] с шагом granularity
+// (BalanceHistoryDaily или BalanceHistoryWeekly). Границы периода передаются в часовом поясе WithLocation.
+// Неизвестный шаг или from позже to возвращают *ValidationError без запроса к комарху.
+func (c *Client) GetBalanceHistory(accessToken AccessToken, from, to time.Time, granularity string, opts ...CallOption) ([]BalanceSnapshot, error) {
+	if granularity != BalanceHistoryDaily && granularity != BalanceHistoryWeekly {
+		return nil, &ValidationError{Fields: map[string]string{"granularity": "must be daily or weekly"}}
+	}
+
+	if from.After(to) {
+		return nil, &ValidationError{Fields: map[string]string{"from": "must not be after to"}}
+	}
+
+	params := url.Values{}
+	params.Set("from", from.In(c.location).Format(DATETIME_FMT))
+	params.Set("to", to.In(c.location).Format(DATETIME_FMT))
+	params.Set("granularity", granularity)
+
+	u := c.endpoint("/resources/balancehistory") + "?" + params.Encode()
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetBalanceHistory", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var rawSnapshots []balanceSnapshot
+	if err := c.decodeResponse(resp, &rawSnapshots); err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]BalanceSnapshot, 0, len(rawSnapshots))
+	for _, raw := range rawSnapshots {
+		date, err := parseDateTime(raw.Date, c.location)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, BalanceSnapshot{
+			Date:    date,
+			Balance: int(raw.Balance),
+		})
+	}
+
+	return snapshots, nil
+}
@@ -0,0 +1,37 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetBalanceHistory(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/balancehistory", r.URL.Path)
+		assert.Equal(t, "2020-03-01 03:00", r.URL.Query().Get("from"))
+		assert.Equal(t, "2020-03-08 03:00", r.URL.Query().Get("to"))
+		assert.Equal(t, "daily", r.URL.Query().Get("granularity"))
+
+		w.Write([]byte(`[{"date":"2020-03-01 00:00","balance":100},{"date":"2020-03-02 00:00","balance":"150"}]`))
+	}, comarch.WithLocation(loc))
+
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour * 24 * 7)
+
+	history, err := c.GetBalanceHistory(testAccessToken, from, to, comarch.BalanceHistoryDaily)
+	assert.Nil(t, err)
+	assert.Equal(t, []comarch.BalanceSnapshot{
+		{Date: time.Date(2020, 3, 1, 0, 0, 0, 0, loc), Balance: 100},
+		{Date: time.Date(2020, 3, 2, 0, 0, 0, 0, loc), Balance: 150},
+	}, history)
+
+	_, err = c.GetBalanceHistory(testAccessToken, from, to, "hourly")
+	assert.IsType(t, &comarch.ValidationError{}, err)
+
+	_, err = c.GetBalanceHistory(testAccessToken, to, from, comarch.BalanceHistoryWeekly)
+	assert.IsType(t, &comarch.ValidationError{}, err)
+}
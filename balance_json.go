@@ -0,0 +1,42 @@
+package comarch
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MarshalJSON сериализует баланс для передачи другим сервисам: LastAuth, IssueDate и ExpiryDate экспресс-баллов
+// выводятся в RFC3339 с часовым поясом WithLocation клиента, получившего баланс (time.Local для баланса,
+// собранного вручную). Пустые и неразборчивые даты выводятся как есть, числовые поля не меняются.
+// Разбор ответа комарха (UnmarshalJSON по умолчанию) не меняется и принимает обе формы дат.
+func (b BalanceInfoResp) MarshalJSON() ([]byte, error) {
+	loc := b.location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	type balanceInfoResp BalanceInfoResp
+	raw := balanceInfoResp(b)
+	raw.LastAuth = rfc3339Date(b.LastAuth, loc)
+
+	if b.ExpressPoints != nil {
+		raw.ExpressPoints = make([]ExpressPoints, len(b.ExpressPoints))
+		for i, points := range b.ExpressPoints {
+			points.IssueDate = rfc3339Date(points.IssueDate, loc)
+			points.ExpiryDate = rfc3339Date(points.ExpiryDate, loc)
+			raw.ExpressPoints[i] = points
+		}
+	}
+
+	return json.Marshal(raw)
+}
+
+// rfc3339Date переводит дату комарха в RFC3339. Пустые и неразборчивые даты возвращаются без изменений
+func rfc3339Date(value string, loc *time.Location) string {
+	t, err := parseDateTime(value, loc)
+	if err != nil || t.IsZero() {
+		return value
+	}
+
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,49 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBalanceInfoResp_MarshalJSON(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"cardNo":"1111222233334444",
+			"lastAuth":"2020-03-10 12:30",
+			"balanceInfo":{"balance":"1500","balanceID":7,"balanceRate":2},
+			"expressPoints":[
+				{"points":100,"issueDate":"2020-03-01 00:00","expiryDate":"2020-04-01 23:59:30"},
+				{"points":50,"issueDate":"","expiryDate":"someday"}
+			]
+		}`))
+	}, comarch.WithLocation(loc))
+
+	info, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(info)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"cardNo":"1111222233334444",
+		"lastAuth":"2020-03-10T12:30:00+03:00",
+		"balanceInfo":{"balance":1500,"balanceID":7,"balanceRate":2},
+		"expressPoints":[
+			{"points":100,"issueDate":"2020-03-01T00:00:00+03:00","expiryDate":"2020-04-01T23:59:30+03:00"},
+			{"points":50,"issueDate":"","expiryDate":"someday"}
+		]
+	}`, string(data))
+
+	// сериализованный баланс разбирается обратно
+	var decoded comarch.BalanceInfoResp
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, 100, decoded.PointsExpiringWithin(time.Hour*24*31, time.Date(2020, 3, 5, 0, 0, 0, 0, loc)))
+
+	// исходная структура не меняется
+	assert.Equal(t, "2020-03-10 12:30", info.LastAuth)
+	assert.Equal(t, "2020-04-01 23:59:30", info.ExpressPoints[0].ExpiryDate)
+}
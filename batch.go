@@ -0,0 +1,204 @@
+package comarch
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// defaultConcurrency число одновременных запросов пакетной операции по умолчанию
+const defaultConcurrency = 4
+
+// forEach вызывает fn для индексов [0, n), выполняя не более c.concurrency вызовов одновременно.
+// После отмены ctx новые вызовы не запускаются, уже запущенные дожидаются завершения.
+func (c *Client) forEach(ctx context.Context, n int, fn func(i int)) error {
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// GetBalanceInfoBatch получает балансы для нескольких токенов параллельно, не более WithConcurrency запросов одновременно.
+// Результаты возвращаются в порядке токенов. При ошибке хотя бы одного запроса возвращается первая из ошибок.
+func (c *Client) GetBalanceInfoBatch(ctx context.Context, accessTokens []AccessToken) ([]*BalanceInfoResp, error) {
+	results := make([]*BalanceInfoResp, len(accessTokens))
+	errs := make([]error, len(accessTokens))
+
+	err := c.forEach(ctx, len(accessTokens), func(i int) {
+		results[i], errs[i] = c.getBalanceInfo(ctx, accessTokens[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// SignOutBatch разлогинивает несколько токенов параллельно, не более WithConcurrency запросов одновременно.
+// При ошибке хотя бы одного запроса возвращается первая из ошибок.
+func (c *Client) SignOutBatch(ctx context.Context, accessTokens []AccessToken) error {
+	errs := make([]error, len(accessTokens))
+
+	err := c.forEach(ctx, len(accessTokens), func(i int) {
+		errs[i] = c.SignOutContext(ctx, accessTokens[i])
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BalanceBatchItem результат получения баланса для одного токена пакета: баланс или ошибка
+type BalanceBatchItem struct {
+	Balance *BalanceInfoResp
+	Err     error
+}
+
+// BalanceBatchResult результаты GetBalanceInfoBatchPartial в порядке токенов
+type BalanceBatchResult []BalanceBatchItem
+
+// Summary подсчитывает итоги получения балансов по видам ошибок
+func (r BalanceBatchResult) Summary() BatchSummary {
+	errs := make([]error, len(r))
+	for i, item := range r {
+		errs[i] = item.Err
+	}
+
+	return SummarizeBatch(errs)
+}
+
+// GetBalanceInfoBatchPartial получает балансы для нескольких токенов параллельно, как GetBalanceInfoBatch,
+// но не прерывается на ошибке одного токена. Результаты возвращаются в порядке токенов, у каждого либо
+// баланс, либо ошибка. Ошибка возвращается, только если отменен ctx: тогда у незапущенных запросов в Err ctx.Err().
+func (c *Client) GetBalanceInfoBatchPartial(ctx context.Context, accessTokens []AccessToken) (BalanceBatchResult, error) {
+	items := make(BalanceBatchResult, len(accessTokens))
+	done := make([]bool, len(accessTokens))
+
+	err := c.forEach(ctx, len(accessTokens), func(i int) {
+		items[i].Balance, items[i].Err = c.getBalanceInfo(ctx, accessTokens[i])
+		done[i] = true
+	})
+
+	for i := range items {
+		if !done[i] {
+			items[i].Err = err
+		}
+	}
+
+	return items, err
+}
+
+// SignOutBatchPartial разлогинивает несколько токенов параллельно, как SignOutBatch, но не прерывается на ошибке
+// одного токена. Возвращает ошибки в порядке токенов, nil для успешных. Ошибка возвращается, только если
+// отменен ctx: тогда для незапущенных запросов ошибка ctx.Err().
+func (c *Client) SignOutBatchPartial(ctx context.Context, accessTokens []AccessToken) ([]error, error) {
+	errs := make([]error, len(accessTokens))
+	done := make([]bool, len(accessTokens))
+
+	err := c.forEach(ctx, len(accessTokens), func(i int) {
+		errs[i] = c.SignOutContext(ctx, accessTokens[i])
+		done[i] = true
+	})
+
+	for i := range errs {
+		if !done[i] {
+			errs[i] = err
+		}
+	}
+
+	return errs, err
+}
+
+// BatchSummary итоги пакетной операции по видам ошибок
+type BatchSummary struct {
+	// успешные запросы
+	Succeeded int
+	// комарх не нашел карту или участника (ErrNotFound)
+	NotFound int
+	// комарх отклонил запрос по другой причине (прочие ErrBadResponse)
+	Rejected int
+	// запрос не дошел до комарха или не получил ответ: ошибки сети, таймауты, отмена контекста
+	Failed int
+}
+
+// SummarizeBatch подсчитывает итоги пакетной операции по ошибкам ее элементов
+func SummarizeBatch(errs []error) BatchSummary {
+	var summary BatchSummary
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			summary.Succeeded++
+		case errors.Is(err, ErrNotFound):
+			summary.NotFound++
+		case errors.Is(err, ErrBadResponse):
+			summary.Rejected++
+		default:
+			summary.Failed++
+		}
+	}
+
+	return summary
+}
+
+// ActivationResult результат активации одной карты пакета: токен активации или ошибка
+type ActivationResult struct {
+	CardNo string
+	Token  *AccessToken
+	Err    error
+}
+
+// ActivateCardsBatch активирует несколько карт параллельно (ActivateCardNo), не более WithConcurrency запросов
+// одновременно и с учетом WithRateLimit. Не прерывается на ошибке одной карты: результаты возвращаются в порядке
+// карт, у каждого либо токен, либо ошибка. Ошибка возвращается, только если отменен ctx: тогда у незапущенных
+// активаций в Err ctx.Err().
+func (c *Client) ActivateCardsBatch(ctx context.Context, cardNos []string) ([]ActivationResult, error) {
+	results := make([]ActivationResult, len(cardNos))
+	done := make([]bool, len(cardNos))
+
+	err := c.forEach(ctx, len(cardNos), func(i int) {
+		results[i].CardNo = cardNos[i]
+		results[i].Token, results[i].Err = c.ActivateCardNo(cardNos[i], withCallContext(ctx))
+		done[i] = true
+	})
+
+	for i := range results {
+		if !done[i] {
+			results[i].CardNo = cardNos[i]
+			results[i].Err = err
+		}
+	}
+
+	return results, err
+}
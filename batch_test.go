@@ -0,0 +1,179 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_GetBalanceInfoBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		// запрос держится, пока тест не убедится, что одновременно выполняется WithConcurrency запросов
+		arrived <- struct{}{}
+		<-release
+		w.Write([]byte(`{"cardNo":"` + r.Header.Get("Authorization") + `"}`))
+	}, comarch.WithConcurrency(2))
+
+	tokens := make([]comarch.AccessToken, 6)
+	for i := range tokens {
+		tokens[i] = comarch.AccessToken{Value: string(rune('a' + i))}
+	}
+
+	var res []*comarch.BalanceInfoResp
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res, err = c.GetBalanceInfoBatch(context.Background(), tokens)
+	}()
+
+	for wave := 0; wave < len(tokens)/2; wave++ {
+		for i := 0; i < 2; i++ {
+			select {
+			case <-arrived:
+			case <-time.After(time.Second * 5):
+				t.Fatalf("wave %d: request %d did not arrive", wave, i)
+			}
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&inFlight), "wave %d", wave)
+
+		release <- struct{}{}
+		release <- struct{}{}
+	}
+	<-done
+
+	assert.Nil(t, err)
+	if assert.Len(t, res, len(tokens)) {
+		for i := range tokens {
+			assert.Equal(t, "Bearer "+tokens[i].Value, res[i].CardNo)
+		}
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
+
+func TestNew_InvalidConcurrency(t *testing.T) {
+	_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithConcurrency(0))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestClient_GetBalanceInfoBatchPartial(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "Bearer broken":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Write([]byte(`{"cardNo":"1111"}`))
+		}
+	})
+
+	tokens := []comarch.AccessToken{{Value: "ok"}, {Value: "missing"}, {Value: "broken"}, {Value: "ok"}}
+	items, err := c.GetBalanceInfoBatchPartial(context.Background(), tokens)
+	assert.Nil(t, err)
+	if assert.Len(t, items, 4) {
+		assert.Equal(t, "1111", items[0].Balance.CardNo)
+		assert.True(t, errors.Is(items[1].Err, comarch.ErrNotFound))
+		assert.True(t, errors.Is(items[2].Err, comarch.ErrBadResponse))
+		assert.Nil(t, items[3].Err)
+	}
+	assert.Equal(t, comarch.BatchSummary{Succeeded: 2, NotFound: 1, Rejected: 1}, items.Summary())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	items, err = c.GetBalanceInfoBatchPartial(ctx, tokens)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 4, items.Summary().Failed)
+}
+
+func TestClient_SignOutBatchPartial(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	errs, err := c.SignOutBatchPartial(context.Background(), []comarch.AccessToken{{Value: "ok"}, {Value: "broken"}})
+	assert.Nil(t, err)
+	if assert.Len(t, errs, 2) {
+		assert.Nil(t, errs[0])
+		assert.True(t, errors.Is(errs[1], comarch.ErrBadResponse))
+	}
+	assert.Equal(t, comarch.BatchSummary{Succeeded: 1, Rejected: 1}, comarch.SummarizeBatch(errs))
+	assert.Equal(t, comarch.BatchSummary{Failed: 1}, comarch.SummarizeBatch([]error{comarch.ErrTimeout}))
+}
+
+func TestClient_ActivateCardsBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		assert.Equal(t, "cardactivation", r.URL.Query().Get("grant_type"))
+		time.Sleep(time.Millisecond * 20)
+		if r.URL.Query().Get("cardNo") == "2" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		tokenHandler(w, r)
+	}, comarch.WithConcurrency(2))
+
+	results, err := c.ActivateCardsBatch(context.Background(), []string{"1", "2", "3", "4"})
+	assert.Nil(t, err)
+	assert.True(t, atomic.LoadInt32(&maxInFlight) <= 2)
+	if assert.Len(t, results, 4) {
+		for i, result := range results {
+			assert.Equal(t, string(rune('1'+i)), result.CardNo)
+			if result.CardNo == "2" {
+				assert.True(t, errors.Is(result.Err, comarch.ErrConflict))
+				assert.Nil(t, result.Token)
+				continue
+			}
+			assert.Nil(t, result.Err)
+			if assert.NotNil(t, result.Token) {
+				assert.Equal(t, "token", result.Token.Value)
+			}
+		}
+	}
+}
+
+func TestClient_ActivateCardsBatch_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}, comarch.WithConcurrency(1))
+
+	results, err := c.ActivateCardsBatch(ctx, []string{"1", "2", "3"})
+	assert.True(t, errors.Is(err, context.Canceled))
+	if assert.Len(t, results, 3) {
+		for i, result := range results {
+			assert.Equal(t, string(rune('1'+i)), result.CardNo)
+			assert.NotNil(t, result.Err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const benchBalanceInfo = `{
+	"cardNo":"1111222233334444",
+	"lastAuth":"2020-03-10 12:00",
+	"balanceInfo":{"balance":1500,"balanceID":1,"balanceRate":1},
+	"expressPoints":[
+		{"points":100,"issueDate":"2020-01-01 00:00","expiryDate":"2020-04-01 00:00"},
+		{"points":200,"issueDate":"2020-02-01 00:00","expiryDate":"2020-05-01 00:00"},
+		{"points":300,"issueDate":"2020-03-01 00:00","expiryDate":"2020-06-01 00:00"}
+	]
+}`
+
+// newBenchClient поднимает фейковый комарх, отвечающий на логин и запрос баланса
+func newBenchClient(b *testing.B) *comarch.Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cwaapiinterface/login", tokenHandler)
+	mux.HandleFunc("/cwaapiinterface/resources/balanceinfo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(benchBalanceInfo))
+	})
+
+	srv := httptest.NewServer(mux)
+	b.Cleanup(srv.Close)
+
+	c, err := comarch.New(nil, srv.URL, testUsername, testPassword, srv.Client())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return c
+}
+
+func BenchmarkClient_SignInByCard(b *testing.B) {
+	c := newBenchClient(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_GetBalanceInfo(b *testing.B) {
+	c := newBenchClient(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := c.GetBalanceInfo(testAccessToken); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_GetBalanceInfoParallel(b *testing.B) {
+	c := newBenchClient(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.GetBalanceInfo(testAccessToken); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package comarch
+
+import (
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState состояние circuit breaker из WithCircuitBreaker
+type BreakerState string
+
+const (
+	// запросы отправляются как обычно
+	BreakerClosed BreakerState = "closed"
+	// комарх считается недоступным, запросы сразу завершаются с ErrCircuitOpen
+	BreakerOpen BreakerState = "open"
+	// после паузы пропускается один пробный запрос, остальные завершаются с ErrCircuitOpen
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// circuitBreaker размыкается после threshold неудачных попыток подряд и через cooldown пропускает
+// один пробный запрос: его успех замыкает breaker, неудача снова размыкает на cooldown
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	// источник текущего времени, в тестах подменяется
+	now func() time.Time
+	// вызывается при смене состояния под мьютексом
+	onChange func(state BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     BreakerClosed,
+	}
+}
+
+// allow проверяет, можно ли отправить попытку запроса, и переводит breaker в half-open по истечении cooldown
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// release освобождает пробный запрос, итог которого ничего не говорит о доступности комарха
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record учитывает итог разрешенной попытки
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if success {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(BreakerOpen)
+	}
+}
+
+func (b *circuitBreaker) current() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *circuitBreaker) setState(state BreakerState) {
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+// CircuitState возвращает текущее состояние circuit breaker. Без WithCircuitBreaker всегда BreakerClosed.
+// При WithCircuitBreakerPerOperation возвращает худшее из состояний операций: BreakerOpen, если разомкнут
+// breaker хотя бы одной операции, иначе BreakerHalfOpen, если хотя бы одна операция в пробном режиме.
+// Состояние отдельной операции возвращает CircuitStateOf.
+func (c *Client) CircuitState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+
+	if !c.breakerPerOperation {
+		return c.breaker.current()
+	}
+
+	c.operationBreakersMu.Lock()
+	defer c.operationBreakersMu.Unlock()
+
+	state := BreakerClosed
+	for _, breaker := range c.operationBreakers {
+		switch breaker.current() {
+		case BreakerOpen:
+			return BreakerOpen
+		case BreakerHalfOpen:
+			state = BreakerHalfOpen
+		}
+	}
+
+	return state
+}
+
+// CircuitStateOf возвращает состояние circuit breaker, через который проходят запросы операции operation
+// (имя метода клиента, например GetBalanceInfo). Без WithCircuitBreakerPerOperation совпадает с CircuitState.
+func (c *Client) CircuitStateOf(operation string) BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+
+	return c.breakerFor(operation).current()
+}
+
+// breakerFor возвращает circuit breaker операции operation. При WithCircuitBreakerPerOperation breaker
+// операции создается при первом обращении с настройками WithCircuitBreaker
+func (c *Client) breakerFor(operation string) *circuitBreaker {
+	if !c.breakerPerOperation {
+		return c.breaker
+	}
+
+	c.operationBreakersMu.Lock()
+	defer c.operationBreakersMu.Unlock()
+
+	breaker, ok := c.operationBreakers[operation]
+	if !ok {
+		breaker = newCircuitBreaker(c.breaker.threshold, c.breaker.cooldown)
+		breaker.now = c.breaker.now
+		breaker.onChange = func(state BreakerState) {
+			c.metrics.ObserveOperationCircuitState(operation, state)
+		}
+		c.operationBreakers[operation] = breaker
+	}
+
+	return breaker
+}
+
+// allowAttempt проверяет circuit breaker перед попыткой запроса
+func (c *Client) allowAttempt(operation string, req *http.Request) error {
+	if c.breaker == nil {
+		return nil
+	}
+
+	breaker := c.breakerFor(operation)
+	if err := breaker.allow(breaker.now()); err != nil {
+		c.log.WithFields(logrus.Fields{
+			"operation":  operation,
+			"request_id": requestID(req),
+		}).Debug("Comarch request rejected by circuit breaker")
+
+		return err
+	}
+
+	return nil
+}
+
+// recordAttempt сообщает circuit breaker операции итог попытки. Неудачей считаются ошибки транспорта и ответы 5xx,
+// кроме ошибок из-за контекста вызывающего (отмена или дедлайн, в том числе во время ожидания WithRateLimit):
+// они ничего не говорят о доступности комарха. Дедлайн первой попытки из WithFirstAttemptShare считается неудачей.
+func (c *Client) recordAttempt(operation string, req *http.Request, err error) {
+	if c.breaker == nil {
+		return
+	}
+
+	breaker := c.breakerFor(operation)
+	if err != nil && req.Context().Err() != nil {
+		breaker.release()
+		return
+	}
+
+	breaker.record(err == nil, breaker.now())
+}
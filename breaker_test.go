@@ -0,0 +1,185 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type breakerMetrics struct {
+	comarch.NopMetrics
+
+	mu     sync.Mutex
+	states []comarch.BreakerState
+}
+
+func (m *breakerMetrics) ObserveCircuitState(state comarch.BreakerState) {
+	m.mu.Lock()
+	m.states = append(m.states, state)
+	m.mu.Unlock()
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	healthy := false
+	setHealthy := func(value bool) {
+		mu.Lock()
+		healthy = value
+		mu.Unlock()
+	}
+	getCalls := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+
+	metrics := &breakerMetrics{}
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{}`))
+	},
+		comarch.WithRetry(3),
+		comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration { return 0 }),
+		comarch.WithCircuitBreaker(2, time.Minute),
+		comarch.WithMetrics(metrics),
+	)
+	now := time.Now()
+	comarch.SetBreakerClock(c, func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	assert.Equal(t, comarch.BreakerClosed, c.CircuitState())
+
+	// вторая неудачная попытка размыкает breaker, третья попытка не отправляется
+	_, err := c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrCircuitOpen))
+	assert.Equal(t, 2, getCalls())
+	assert.Equal(t, comarch.BreakerOpen, c.CircuitState())
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrCircuitOpen))
+	assert.Equal(t, 2, getCalls())
+
+	// неудачный пробный запрос снова размыкает breaker
+	advance(time.Minute)
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrCircuitOpen))
+	assert.Equal(t, 3, getCalls())
+	assert.Equal(t, comarch.BreakerOpen, c.CircuitState())
+
+	// истекший контекст вызывающего освобождает пробный запрос, не размыкая breaker
+	setHealthy(true)
+	advance(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = c.GetBalanceInfoContext(ctx, testAccessToken)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.Equal(t, comarch.BreakerHalfOpen, c.CircuitState())
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, getCalls())
+	assert.Equal(t, comarch.BreakerClosed, c.CircuitState())
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	assert.Equal(t, []comarch.BreakerState{
+		comarch.BreakerOpen,
+		comarch.BreakerHalfOpen,
+		comarch.BreakerOpen,
+		comarch.BreakerHalfOpen,
+		comarch.BreakerClosed,
+	}, metrics.states)
+}
+
+func TestWithCircuitBreaker_Config(t *testing.T) {
+	for _, opt := range []comarch.Option{
+		comarch.WithCircuitBreaker(0, time.Second),
+		comarch.WithCircuitBreaker(3, 0),
+	} {
+		_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, opt)
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+	}
+}
+
+type operationBreakerMetrics struct {
+	comarch.NopMetrics
+
+	mu     sync.Mutex
+	states map[string][]comarch.BreakerState
+	global int
+}
+
+func (m *operationBreakerMetrics) ObserveOperationCircuitState(operation string, state comarch.BreakerState) {
+	m.mu.Lock()
+	m.states[operation] = append(m.states[operation], state)
+	m.mu.Unlock()
+}
+
+func (m *operationBreakerMetrics) ObserveCircuitState(comarch.BreakerState) {
+	m.mu.Lock()
+	m.global++
+	m.mu.Unlock()
+}
+
+func TestWithCircuitBreakerPerOperation(t *testing.T) {
+	metrics := &operationBreakerMetrics{states: map[string][]comarch.BreakerState{}}
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			tokenHandler(w, r)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	},
+		comarch.WithCircuitBreaker(2, time.Minute),
+		comarch.WithCircuitBreakerPerOperation(),
+		comarch.WithMetrics(metrics),
+	)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetBalanceInfo(testAccessToken)
+		assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+	}
+
+	_, err := c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrCircuitOpen))
+	assert.Equal(t, comarch.BreakerOpen, c.CircuitStateOf("GetBalanceInfo"))
+	assert.Equal(t, comarch.BreakerOpen, c.CircuitState())
+
+	// логин проходит через свой breaker
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, comarch.BreakerClosed, c.CircuitStateOf("SignInByCard"))
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	assert.Equal(t, map[string][]comarch.BreakerState{
+		"GetBalanceInfo": {comarch.BreakerOpen},
+	}, metrics.states)
+	assert.Equal(t, 0, metrics.global)
+}
+
+func TestWithCircuitBreakerPerOperation_RequiresBreaker(t *testing.T) {
+	_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithCircuitBreakerPerOperation())
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
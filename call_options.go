@@ -0,0 +1,93 @@
+package comarch
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// CallOption настройка одного вызова метода клиента. Перекрывает настройки клиента только для этого вызова
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	header  http.Header
+	timeout time.Duration
+	// количество попыток, не меньше 1. По умолчанию из WithRetry
+	retryAttempts int
+	// контекст запроса для методов без параметра ctx, nil - контекст запроса не меняется
+	ctx context.Context
+	// способ доставки одноразового кода, пустой - OTPChannelText
+	otpChannel OTPChannel
+	// дополнительные параметры логина из WithLoginParams
+	loginParams map[string]string
+}
+
+// WithCallHeader добавляет заголовок к запросу. Заголовок заменяет одноименный заголовок, выставленный клиентом
+func WithCallHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Set(key, value)
+	}
+}
+
+// WithCallTimeout ограничивает время вызова вместо WithOperationTimeout. Время считается со всеми повторами
+// и до конца чтения ответа. WithCallTimeout(0) снимает ограничение WithOperationTimeout для вызова
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithCallRetry задает количество попыток вызова вместо WithRetry. WithCallRetry(1) отключает повторы,
+// значения меньше 1 отклоняются с ошибкой ErrInvalidConfiguration без запроса к комарху
+func WithCallRetry(attempts int) CallOption {
+	return func(o *callOptions) {
+		o.retryAttempts = attempts
+	}
+}
+
+// WithLoginParams добавляет к запросу на логин (SignIn* методы, ActivateCardNo, ConfirmCardActivation)
+// дополнительные параметры, которые требуют некоторые инсталляции комарха, например код канала или метку
+// кампании. Параметры не должны совпадать с параметрами самого логина (grant_type, cardNo, phoneNo, password,
+// otp, channel и переданными в SignIn), иначе логин возвращает *ValidationError без запроса к комарху.
+// Параметры сохраняются в AccessToken.GrantParams и повторяются при Reauthenticate. Для остальных вызовов опция
+// игнорируется. Повторные опции дополняют параметры.
+func WithLoginParams(params map[string]string) CallOption {
+	return func(o *callOptions) {
+		if o.loginParams == nil {
+			o.loginParams = map[string]string{}
+		}
+		for key, value := range params {
+			o.loginParams[key] = value
+		}
+	}
+}
+
+// withCallContext выполняет вызов метода без параметра ctx в контексте ctx. Используется пакетными операциями
+func withCallContext(ctx context.Context) CallOption {
+	return func(o *callOptions) {
+		o.ctx = ctx
+	}
+}
+
+// newCallOptions применяет опции вызова поверх настроек клиента
+func (c *Client) newCallOptions(operation string, opts []CallOption) (callOptions, error) {
+	call := callOptions{
+		timeout:       c.operationTimeouts[operation],
+		retryAttempts: c.retryAttempts,
+	}
+	for _, opt := range opts {
+		opt(&call)
+	}
+
+	switch {
+	case call.timeout < 0:
+		return call, &ConfigError{Field: "WithCallTimeout", Reason: "must not be negative"}
+	case call.retryAttempts < 1:
+		return call, &ConfigError{Field: "WithCallRetry", Reason: "must be at least 1"}
+	}
+
+	return call, nil
+}
@@ -0,0 +1,80 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCallOptions(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		var header http.Header
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			w.Write([]byte(`{}`))
+		})
+
+		_, err := c.GetBalanceInfo(testAccessToken,
+			comarch.WithCallHeader("X-Request-Source", "campaign"),
+			comarch.WithCallHeader("Authorization", "Bearer other"),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "campaign", header.Get("X-Request-Source"))
+		assert.Equal(t, "Bearer other", header.Get("Authorization"))
+
+		// опции одного вызова не влияют на следующие
+		_, err = c.GetBalanceInfo(testAccessToken)
+		assert.Nil(t, err)
+		assert.Empty(t, header.Get("X-Request-Source"))
+		assert.Equal(t, "Bearer token", header.Get("Authorization"))
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		calls := 0
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, comarch.WithRetry(3), comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration {
+			return 0
+		}))
+
+		_, err := c.GetBalanceInfo(testAccessToken, comarch.WithCallRetry(1))
+		assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+		assert.Equal(t, 1, calls)
+
+		calls = 0
+		_, err = c.GetBalanceInfo(testAccessToken)
+		assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Millisecond * 100)
+			w.Write([]byte(`{}`))
+		}, comarch.WithOperationTimeout("GetCardHolder", time.Millisecond*10))
+
+		_, err := c.GetCardHolder(testAccessToken)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+		_, err = c.GetCardHolder(testAccessToken, comarch.WithCallTimeout(time.Second))
+		assert.Nil(t, err)
+
+		_, err = c.GetCardHolder(testAccessToken, comarch.WithCallTimeout(0))
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := newTestServer(t, tokenHandler)
+
+		_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword, comarch.WithCallRetry(0))
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+
+		err = c.SignOut(testAccessToken, comarch.WithCallTimeout(-time.Second))
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+	})
+}
@@ -0,0 +1,104 @@
+package comarch
+
+import (
+	"context"
+	"net/http"
+)
+
+// Capabilities методы api, которые опубликованы на сервере комарха. Разные версии комарха поддерживают
+// разный набор методов, значение false означает, что метод отвечает 404
+type Capabilities struct {
+	// логин (SignIn*, ActivateCardNo)
+	Login bool
+	// разлогин (SignOut)
+	Logout bool
+	// баланс по токену (GetBalanceInfo)
+	BalanceInfo bool
+	// баланс по номеру карты (GetBalanceInfoByCard)
+	BalanceInfoByCard bool
+	// анкета владельца карты (CreateCardHolder, GetCardHolder, UpdateCardHolder)
+	CardHolders bool
+	// состояние карты (GetCardStatus, ValidateToken)
+	CardStatus bool
+	// смена пароля (ChangePassword)
+	ChangePassword bool
+	// сброс пароля (ResetPassword*)
+	PasswordReset bool
+	// купоны (GetCoupons)
+	Coupons bool
+	// активация купона (ActivateCoupon)
+	CouponActivation bool
+	// магазины (GetStores)
+	Stores bool
+}
+
+// capabilityProbes пути методов и поля Capabilities, которые они определяют
+var capabilityProbes = []struct {
+	path  string
+	field func(*Capabilities) *bool
+}{
+	{"/login", func(c *Capabilities) *bool { return &c.Login }},
+	{"/logout", func(c *Capabilities) *bool { return &c.Logout }},
+	{"/resources/balanceinfo", func(c *Capabilities) *bool { return &c.BalanceInfo }},
+	{"/common/balanceinfo", func(c *Capabilities) *bool { return &c.BalanceInfoByCard }},
+	{"/resources/cardholders", func(c *Capabilities) *bool { return &c.CardHolders }},
+	{"/resources/cards/status", func(c *Capabilities) *bool { return &c.CardStatus }},
+	{"/resources/cards/password", func(c *Capabilities) *bool { return &c.ChangePassword }},
+	{"/common/passresetting", func(c *Capabilities) *bool { return &c.PasswordReset }},
+	{"/resources/coupons", func(c *Capabilities) *bool { return &c.Coupons }},
+	{"/resources/coupons/activation", func(c *Capabilities) *bool { return &c.CouponActivation }},
+	{"/common/stores", func(c *Capabilities) *bool { return &c.Stores }},
+}
+
+// Capabilities определяет, какие методы api опубликованы на сервере комарха, отправляя на каждый запрос OPTIONS
+// без авторизации. Метод считается опубликованным при любом ответе, кроме 404 (в том числе 401 и 405).
+// Запросы выполняются параллельно, не более WithConcurrency одновременно. Успешный результат кэшируется
+// на время жизни клиента, повторные вызовы запросов не отправляют. При ошибке хотя бы одного запроса
+// возвращается первая из ошибок, и результат не кэшируется.
+func (c *Client) Capabilities(ctx context.Context) (Capabilities, error) {
+	c.capabilitiesMu.Lock()
+	defer c.capabilitiesMu.Unlock()
+
+	if c.capabilities != nil {
+		return *c.capabilities, nil
+	}
+
+	var caps Capabilities
+	present := make([]bool, len(capabilityProbes))
+	errs := make([]error, len(capabilityProbes))
+
+	err := c.forEach(ctx, len(capabilityProbes), func(i int) {
+		present[i], errs[i] = c.probeEndpoint(ctx, capabilityProbes[i].path)
+	})
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	for i, probe := range capabilityProbes {
+		if errs[i] != nil {
+			return Capabilities{}, errs[i]
+		}
+
+		*probe.field(&caps) = present[i]
+	}
+
+	c.capabilities = &caps
+
+	return caps, nil
+}
+
+// probeEndpoint проверяет, что путь path опубликован на сервере
+func (c *Client) probeEndpoint(ctx context.Context, path string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "OPTIONS", c.endpoint(path), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do("Capabilities", req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode != http.StatusNotFound, nil
+}
@@ -0,0 +1,63 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestClient_Capabilities(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		assert.Equal(t, "OPTIONS", r.Method)
+		switch r.URL.Path {
+		case "/cwaapiinterface/resources/coupons", "/cwaapiinterface/resources/coupons/activation":
+			w.WriteHeader(http.StatusNotFound)
+		case "/cwaapiinterface/common/stores":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case "/cwaapiinterface/resources/balanceinfo":
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	caps, err := c.Capabilities(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, caps.Login)
+	assert.True(t, caps.BalanceInfo)
+	assert.True(t, caps.Stores)
+	assert.False(t, caps.Coupons)
+	assert.False(t, caps.CouponActivation)
+
+	probes := requests
+	cached, err := c.Capabilities(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, caps, cached)
+	assert.Equal(t, probes, requests)
+}
+
+func TestClient_Capabilities_Error(t *testing.T) {
+	failing := true
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}, comarch.WithConcurrency(1))
+
+	_, err := c.Capabilities(context.Background())
+	assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+
+	// ошибка не кэшируется
+	failing = false
+	caps, err := c.Capabilities(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, caps.Stores)
+}
@@ -0,0 +1,253 @@
+package comarch
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CardStatus состояние карты
+type CardStatus int
+
+const (
+	CardStatusUnknown CardStatus = iota
+	// карта активна
+	CardStatusActive
+	// карта заблокирована
+	CardStatusBlocked
+	// срок действия карты истек
+	CardStatusExpired
+	// карта выпущена, но не активирована
+	CardStatusNotActivated
+	// карта закрыта
+	CardStatusClosed
+)
+
+// cardStatusCodes коды состояний карты в комархе. Все методы клиента переводят коды в CardStatus только по этой таблице
+var cardStatusCodes = map[string]CardStatus{
+	"A": CardStatusActive,
+	"B": CardStatusBlocked,
+	"E": CardStatusExpired,
+	"N": CardStatusNotActivated,
+	"C": CardStatusClosed,
+}
+
+var cardStatusNames = map[CardStatus]string{
+	CardStatusUnknown:      "unknown",
+	CardStatusActive:       "active",
+	CardStatusBlocked:      "blocked",
+	CardStatusExpired:      "expired",
+	CardStatusNotActivated: "not_activated",
+	CardStatusClosed:       "closed",
+}
+
+// parseCardStatus переводит код состояния карты комарха в CardStatus
+func parseCardStatus(code string) (CardStatus, error) {
+	status, ok := cardStatusCodes[code]
+	if !ok {
+		return CardStatusUnknown, ErrUnknownCardStatus
+	}
+
+	return status, nil
+}
+
+func (s CardStatus) String() string {
+	if name, ok := cardStatusNames[s]; ok {
+		return name
+	}
+
+	return cardStatusNames[CardStatusUnknown]
+}
+
+// IsUsable сообщает, можно ли пользоваться картой: начислять и списывать баллы, входить по ней
+func (s CardStatus) IsUsable() bool {
+	return s == CardStatusActive
+}
+
+type cardStatusResp struct {
+	CardNo string `json:"cardNo"`
+	Status string `json:"status"`
+}
+
+// GetCardStatus получает состояние карты участника.
+// Если комарх вернул неизвестный код состояния, возвращается CardStatusUnknown и ErrUnknownCardStatus.
+func (c *Client) GetCardStatus(accessToken AccessToken, opts ...CallOption) (CardStatus, error) {
+	u := c.endpoint("/resources/cards/status")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return CardStatusUnknown, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return CardStatusUnknown, err
+	}
+
+	resp, err := c.do("GetCardStatus", req, opts...)
+	if err != nil {
+		return CardStatusUnknown, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return CardStatusUnknown, err
+	}
+
+	var statusResp cardStatusResp
+	if err := c.decodeResponse(resp, &statusResp); err != nil {
+		return CardStatusUnknown, err
+	}
+
+	return parseCardStatus(statusResp.Status)
+}
+
+// SetGoldenCard присваивает или снимает признак "Золотая карта" (PersonalData.Golden) карты cardNo.
+// Операция служебная: выполняется с учетными данными клиента вместо токена участника и меняет только
+// этот признак, не перезаписывая анкету. Для неизвестной карты возвращается ошибка, соответствующая
+// ErrNotFound, если учетным данным не хватает прав - ErrForbidden.
+func (c *Client) SetGoldenCard(cardNo string, golden bool, opts ...CallOption) error {
+	if cardNo == "" {
+		return &ValidationError{Fields: map[string]string{"cardNo": "must not be empty"}}
+	}
+
+	params := url.Values{}
+	params.Set("cardNo", cardNo)
+
+	u := c.endpoint("/common/cards/golden") + "?" + params.Encode()
+
+	req, err := c.newJSONRequest("PUT", u, map[string]bool{"golden": golden})
+	if err != nil {
+		return err
+	}
+
+	if err := c.basicAuth("SetGoldenCard", req); err != nil {
+		return err
+	}
+
+	resp, err := c.do("SetGoldenCard", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	c.audit("SetGoldenCard", cardNo)
+
+	return nil
+}
+
+// Card карта участника с несколькими картами
+type Card struct {
+	CardNo string
+	// состояние карты. CardStatusUnknown, если комарх прислал неизвестный код
+	Status CardStatus
+	// основная карта, на которую по умолчанию начисляются баллы
+	Primary bool
+}
+
+type cardResp struct {
+	CardNo  string   `json:"cardNo"`
+	Status  string   `json:"status"`
+	Primary flexBool `json:"primary"`
+}
+
+// GetCards возвращает карты участника. Неизвестный код состояния карты не считается ошибкой,
+// у такой карты Status равен CardStatusUnknown.
+func (c *Client) GetCards(accessToken AccessToken, opts ...CallOption) ([]Card, error) {
+	u := c.endpoint("/resources/cards")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetCards", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var raw []cardResp
+	if err := c.decodeResponse(resp, &raw); err != nil {
+		return nil, err
+	}
+
+	cards := make([]Card, 0, len(raw))
+	for _, card := range raw {
+		status, _ := parseCardStatus(card.Status)
+		cards = append(cards, Card{CardNo: card.CardNo, Status: status, Primary: bool(card.Primary)})
+	}
+
+	return cards, nil
+}
+
+// SetPrimaryCard делает карту cardNo основной картой участника. Карта должна быть привязана к участнику:
+// это проверяется по GetCards до изменения, и для чужой или неизвестной карты возвращается ErrCardNotLinked.
+// Если карта уже основная, изменение не отправляется.
+func (c *Client) SetPrimaryCard(accessToken AccessToken, cardNo string, opts ...CallOption) error {
+	if strings.TrimSpace(cardNo) == "" {
+		return &ValidationError{Fields: map[string]string{"cardNo": "must not be empty"}}
+	}
+
+	cards, err := c.GetCards(accessToken, opts...)
+	if err != nil {
+		return err
+	}
+
+	linked := false
+	for _, card := range cards {
+		if card.CardNo == cardNo {
+			if card.Primary {
+				return nil
+			}
+			linked = true
+		}
+	}
+	if !linked {
+		return ErrCardNotLinked
+	}
+
+	u := c.endpoint("/resources/cards/primary")
+
+	req, err := c.newJSONRequest("PUT", u, map[string]string{"cardNo": cardNo})
+	if err != nil {
+		return err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("SetPrimaryCard", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		// карту отвязали между проверкой и изменением
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return ErrCardNotLinked
+		}
+
+		return err
+	}
+
+	c.audit("SetPrimaryCard", cardNo)
+	c.balanceCache.invalidate(c.resolveToken(req.Context(), accessToken).Value)
+
+	return nil
+}
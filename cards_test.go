@@ -0,0 +1,187 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_GetCardStatus(t *testing.T) {
+	tests := []struct {
+		code   string
+		status comarch.CardStatus
+		err    error
+	}{
+		{code: "A", status: comarch.CardStatusActive},
+		{code: "B", status: comarch.CardStatusBlocked},
+		{code: "E", status: comarch.CardStatusExpired},
+		{code: "N", status: comarch.CardStatusNotActivated},
+		{code: "C", status: comarch.CardStatusClosed},
+		{code: "Z", status: comarch.CardStatusUnknown, err: comarch.ErrUnknownCardStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/cwaapiinterface/resources/cards/status", r.URL.Path)
+				w.Write([]byte(`{"cardNo":"1111222233334444","status":"` + tt.code + `"}`))
+			})
+
+			status, err := c.GetCardStatus(testAccessToken)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
+
+func TestCardStatus(t *testing.T) {
+	tests := []struct {
+		status comarch.CardStatus
+		name   string
+		usable bool
+	}{
+		{status: comarch.CardStatusUnknown, name: "unknown", usable: false},
+		{status: comarch.CardStatusActive, name: "active", usable: true},
+		{status: comarch.CardStatusBlocked, name: "blocked", usable: false},
+		{status: comarch.CardStatusExpired, name: "expired", usable: false},
+		{status: comarch.CardStatusNotActivated, name: "not_activated", usable: false},
+		{status: comarch.CardStatusClosed, name: "closed", usable: false},
+		{status: comarch.CardStatus(100), name: "unknown", usable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.name, tt.status.String())
+			assert.Equal(t, tt.usable, tt.status.IsUsable())
+		})
+	}
+}
+
+func TestClient_SetGoldenCard(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "unknown_card", status: http.StatusNotFound, err: comarch.ErrNotFound},
+		{name: "forbidden", status: http.StatusForbidden, err: comarch.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []comarch.AuditEvent
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "PUT", r.Method)
+				assert.Equal(t, "/cwaapiinterface/common/cards/golden", r.URL.Path)
+				assert.Equal(t, testCredentialsCardNo, r.URL.Query().Get("cardNo"))
+
+				username, _, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, testUsername, username)
+
+				var body map[string]bool
+				json.NewDecoder(r.Body).Decode(&body)
+				assert.Equal(t, map[string]bool{"golden": true}, body)
+
+				w.WriteHeader(tt.status)
+			}, comarch.WithAuditLogger(comarch.AuditLoggerFunc(func(event comarch.AuditEvent) {
+				events = append(events, event)
+			})))
+
+			err := c.SetGoldenCard(testCredentialsCardNo, true)
+			if tt.err == nil {
+				assert.Nil(t, err)
+				if assert.Len(t, events, 1) {
+					assert.Equal(t, "SetGoldenCard", events[0].Operation)
+				}
+			} else {
+				assert.True(t, errors.Is(err, tt.err))
+				assert.Empty(t, events)
+			}
+		})
+	}
+}
+
+func TestClient_GetCards(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/cwaapiinterface/resources/cards", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		w.Write([]byte(`[{"cardNo":"1111","status":"A","primary":true},{"cardNo":"2222","status":"Z","primary":"N"}]`))
+	})
+
+	cards, err := c.GetCards(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, []comarch.Card{
+		{CardNo: "1111", Status: comarch.CardStatusActive, Primary: true},
+		{CardNo: "2222", Status: comarch.CardStatusUnknown},
+	}, cards)
+}
+
+func TestClient_SetPrimaryCard(t *testing.T) {
+	const cards = `[{"cardNo":"1111","status":"A","primary":true},{"cardNo":"2222","status":"A"}]`
+
+	tests := []struct {
+		name    string
+		cardNo  string
+		status  int
+		updated bool
+		err     error
+	}{
+		{name: "ok", cardNo: "2222", status: http.StatusOK, updated: true},
+		{name: "already_primary", cardNo: "1111"},
+		{name: "not_linked", cardNo: "3333", err: comarch.ErrCardNotLinked},
+		{name: "unlinked_concurrently", cardNo: "2222", status: http.StatusNotFound, updated: true, err: comarch.ErrCardNotLinked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			var events []comarch.AuditEvent
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+				switch r.URL.Path {
+				case "/cwaapiinterface/resources/cards":
+					w.Write([]byte(cards))
+				case "/cwaapiinterface/resources/cards/primary":
+					updated = true
+					assert.Equal(t, "PUT", r.Method)
+
+					var body map[string]string
+					json.NewDecoder(r.Body).Decode(&body)
+					assert.Equal(t, map[string]string{"cardNo": tt.cardNo}, body)
+
+					w.WriteHeader(tt.status)
+				default:
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+			}, comarch.WithAuditLogger(comarch.AuditLoggerFunc(func(event comarch.AuditEvent) {
+				events = append(events, event)
+			})))
+
+			err := c.SetPrimaryCard(testAccessToken, tt.cardNo)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.updated, updated)
+			if tt.updated && tt.err == nil {
+				assert.Len(t, events, 1)
+			} else {
+				assert.Empty(t, events)
+			}
+		})
+	}
+
+	t.Run("empty_card", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("request must not be sent")
+		})
+
+		var validationErr *comarch.ValidationError
+		assert.True(t, errors.As(c.SetPrimaryCard(testAccessToken, " "), &validationErr))
+	})
+}
@@ -1,13 +1,15 @@
 package comarch
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/sirupsen/logrus"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,12 +17,37 @@ type accessToken struct {
 	Token     string `json:"access_token"`
 	Type      string `json:"token_type"`
 	ExpiresIn int64  `json:"expires_in"`
+	// права токена, если комарх их присылает
+	Scope scopeClaim `json:"scope"`
+	// флаги функций участника, если комарх их присылает
+	Features featureFlags `json:"features"`
 }
 
 type AccessToken struct {
 	Value     string            `json:"value"`
 	ExpiresAt time.Time         `json:"expires_at"`
 	Cookies   map[string]string `json:"cookies"`
+	// время получения токена. Вместе с ExpiresAt задает полное время жизни для WithRefreshHint
+	IssuedAt time.Time `json:"issued_at,omitempty"`
+	// grant_type, по которому был получен токен, и его параметры без пароля. Используются в Reauthenticate
+	Grant       GrantType         `json:"grant,omitempty"`
+	GrantParams map[string]string `json:"grant_params,omitempty"`
+	// заголовки ответа на логин, перечисленные в WithAuthResponseHeaders
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	// права токена из поля scope ответа на логин или, если токен JWT, из его payload.
+	// Пустой для непрозрачных токенов без scope
+	Scopes []string `json:"scopes,omitempty"`
+	// поля ответа на логин, которые библиотека пока не знает. Позволяют читать новые поля комарха
+	// без обновления библиотеки
+	Extra map[string]json.RawMessage `json:"extra,omitempty"`
+	// адрес api комарха, выдавшего токен. Проверяется при WithTokenIssuerCheck, пустой для токенов,
+	// собранных вручную
+	Issuer string `json:"issuer,omitempty"`
+	// схема заголовка Authorization клиента, выдавшего токен (WithAuthScheme). Пустая означает Bearer
+	Scheme string `json:"scheme,omitempty"`
+	// флаги функций участника из поля features ответа на логин (бета-функции, участие в акциях).
+	// Nil, если комарх их не прислал
+	Features map[string]bool `json:"features,omitempty"`
 }
 
 type GrantType string
@@ -32,276 +59,540 @@ const (
 	GrantTypeCardActivation GrantType = "cardactivation"
 )
 
+// defaultAPIPrefix префикс путей api комарха
+const defaultAPIPrefix = "/cwaapiinterface"
+
 type Client struct {
 	basePath   string
+	apiPrefix  string
 	username   string
 	password   string
 	httpClient *http.Client
 	log        *logrus.Logger
+	// операции, которые отправляются без Basic Auth
+	anonymousOperations map[string]struct{}
+	// источник учетных данных приложения, заменяющий username и password
+	credentialProvider func(ctx context.Context) (username, password string, err error)
+	// схема заголовка Authorization для токена участника
+	authScheme string
+	// построитель заголовка Authorization для Basic Auth, nil для req.SetBasicAuth
+	basicAuthBuilder func(username, password string) string
+	// опции транспорта для http.Client, который создает сам клиент
+	transport transportConfig
+	// резервные адреса комарха из WithBackupBaseURLs
+	backupBasePaths []string
+	// заголовок Host из WithHostHeader, пустой - host из адреса запроса
+	hostHeader string
+
+	onTimings   func(RequestTimings)
+	concurrency int
+
+	retryAttempts int
+	onRetry       func(operation string, attempt int, err error)
+	backoff       BackoffStrategy
+	retryable     RetryClassifier
+	metrics       Metrics
+
+	// пароли пользователей для Reauthenticate. Заполняется только при WithReauthentication
+	keepPasswords bool
+	passwordsMu   sync.Mutex
+	passwords     map[string]storedPassword
+
+	// дополнительные grant_type, зарегистрированные через WithGrantTypes
+	grantTypes map[GrantType]struct{}
+	// grant_type, параметры логина которых отправляются в теле запроса. nil отключено, пустой для всех grant_type
+	formLoginGrants map[GrantType]struct{}
+	// http метод логина по grant_type из WithLoginMethod, для остальных grant_type POST
+	loginMethods map[GrantType]string
+	// заголовки ответа на логин, которые сохраняются в токене
+	authResponseHeaders []string
+	// отправлять ли куки сессии вместе с токеном
+	sendCookies bool
+	// имена кук, которые сохраняются в токене. Пустой означает все куки
+	sessionCookieNames map[string]struct{}
+	codec              Codec
+	// запрещать неизвестные поля в ответах
+	strictDecoding bool
+	// операции, ответы которых не проверяются при WithStrictDecoding
+	strictDecodingExcept map[string]struct{}
+	// приводить ли пароли к NFC перед отправкой
+	nfcPasswords bool
+	// максимальный размер тела запроса и ответа, 0 без ограничения
+	maxTransferSize int64
+	// кэш GetBalanceInfo, nil если кэш не включен
+	balanceCache *balanceCache
+	// таймауты операций из WithOperationTimeout
+	operationTimeouts map[string]time.Duration
+	// доля оставшейся жизни токена, ниже которой срабатывает onRefreshHint. 0 отключено
+	refreshHintThreshold float64
+	onRefreshHint        func(accessToken AccessToken, ttl time.Duration)
+	// вызывается для каждого собранного запроса перед отправкой
+	interceptor func(operation string, req *http.Request) error
+	// результат Capabilities, nil пока не определен
+	capabilitiesMu sync.Mutex
+	capabilities   *Capabilities
+	// получатель событий аудита, nil если аудит не включен
+	auditLogger AuditLogger
+	// канал событий жизненного цикла токена, nil если не задан WithEventChannel
+	events chan<- Event
+	// получатель записей о запросах из WithTranscriptSink, nil если не задан
+	transcriptSink func(RequestRecord)
+	// генератор внутренних идентификаторов запросов для логов и ошибок
+	requestIDGenerator func() string
+	// период опроса и время ожидания long-poll в WatchBalance
+	watchInterval time.Duration
+	// доля оставшегося до дедлайна контекста времени, которая отдается первой попытке при WithRetry
+	firstAttemptShare float64
+	// проверять ли Issuer токенов и отклонять ли токены другого комарха, см. WithTokenIssuerCheck
+	tokenIssuerCheck  bool
+	strictTokenIssuer bool
+	// не отправлять запросы, меняющие данные
+	dryRun bool
+	// часовой пояс дат комарха
+	location *time.Location
+	// ограничение частоты запросов, nil если не задано WithRateLimit
+	limiter *rateLimiter
+	// circuit breaker, nil если не задан WithCircuitBreaker. При WithCircuitBreakerPerOperation только
+	// хранит настройки для breaker операций
+	breaker             *circuitBreaker
+	breakerPerOperation bool
+	operationBreakersMu sync.Mutex
+	operationBreakers   map[string]*circuitBreaker
+	// токен, полученный вне клиента. Используется вместо пустого токена в аргументах методов
+	bearerToken *AccessToken
+	// сдвиг часов комарха относительно локальных, см. clock_skew.go
+	clock clockSkew
 }
 
-func New(log *logrus.Logger, basePath, login, password string, httpClient *http.Client) (*Client, error) {
-	if strings.HasSuffix(basePath, "/") {
-		return nil, ErrInvalidConfiguration
+// New создает клиент комарха. login и password учетные данные приложения для Basic Auth. Клиент, который только
+// пересылает токены, полученные из другого сервиса, создается с пустыми login и password и опцией WithBearerToken.
+func New(log *logrus.Logger, basePath, login, password string, httpClient *http.Client, opts ...Option) (*Client, error) {
+	if err := validateBaseURL("basePath", basePath); err != nil {
+		return nil, err
 	}
 
+	customClient := httpClient != nil
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 
-	return &Client{
-		basePath:   basePath,
-		username:   login,
-		password:   password,
-		httpClient: httpClient,
-		log:        log,
-	}, nil
-}
-
-func (c *Client) makeAuthHeader(accessToken AccessToken) string {
-	return "Bearer " + accessToken.Value
-}
+	if log == nil {
+		log = logrus.New()
+		log.SetOutput(ioutil.Discard)
+	}
 
-func (c *Client) SignInByCard(cardNo string, password string) (*AccessToken, error) {
-	params := url.Values{}
-	params.Set("grant_type", string(GrantTypeByCard))
-	params.Set("cardNo", cardNo)
-	params.Set("password", password)
+	c := &Client{
+		basePath:           basePath,
+		apiPrefix:          defaultAPIPrefix,
+		username:           login,
+		password:           password,
+		httpClient:         httpClient,
+		log:                log,
+		passwords:          map[string]storedPassword{},
+		grantTypes:         map[GrantType]struct{}{},
+		sendCookies:        true,
+		authScheme:         defaultAuthScheme,
+		codec:              stdCodec{},
+		requestIDGenerator: newRequestID,
+		firstAttemptShare:  1,
+		watchInterval:      defaultWatchInterval,
+		location:           time.Local,
+
+		operationTimeouts: map[string]time.Duration{},
+
+		nfcPasswords: true,
+		concurrency:  defaultConcurrency,
+
+		retryAttempts: 1,
+		backoff:       defaultBackoff,
+		retryable:     DefaultRetryClassifier,
+		metrics:       NopMetrics{},
+	}
 
-	u := c.basePath + "/cwaapiinterface/login?" + params.Encode()
+	for _, opt := range opts {
+		opt(c)
+	}
 
-	req, err := http.NewRequest("POST", u, nil)
-	if err != nil {
+	if err := c.validate(); err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
+	if err := c.setupTransport(customClient); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrBadResponse
+	if c.breaker != nil && !c.breakerPerOperation {
+		c.breaker.onChange = c.metrics.ObserveCircuitState
 	}
-
-	publicToken, err := parseAccessToken(resp)
-	if err != nil {
-		return nil, err
+	if c.breakerPerOperation {
+		c.operationBreakers = map[string]*circuitBreaker{}
 	}
 
-	reqDump, _ := httputil.DumpRequest(resp.Request, false)
-	respDump, _ := httputil.DumpResponse(resp, false)
-	c.log.WithFields(logrus.Fields{
-		"req":      string(reqDump),
-		"req_body": "",
-		"resp":     string(respDump),
-	}).Debug("Comarch req-resp")
+	if c.dryRun {
+		c.log.Warn("Comarch client is in dry run mode: write operations are not sent")
+	}
 
-	return publicToken, nil
+	return c, nil
 }
 
-func (c *Client) SignInByPhone(phoneNo string, password string) (*AccessToken, error) {
-	params := url.Values{}
-	params.Set("grant_type", string(GrantTypeByPhone))
-	params.Set("phoneNo", phoneNo)
-	params.Set("password", password)
-
-	u := c.basePath + "/cwaapiinterface/login?" + params.Encode()
-
-	req, err := http.NewRequest("POST", u, nil)
+// do выполняет запрос к комарху, повторяя его при ошибках согласно WithRetry. Все методы клиента
+// отправляют запросы только через него. operation имя публичного метода клиента, от имени которого
+// выполняется запрос. Используется в логах и метриках. opts опции вызова, переданные в публичный метод.
+func (c *Client) do(operation string, req *http.Request, opts ...CallOption) (*http.Response, error) {
+	call, err := c.newCallOptions(operation, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	if call.ctx != nil {
+		req = req.WithContext(call.ctx)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrBadResponse
+	if c.hostHeader != "" {
+		req.Host = c.hostHeader
 	}
 
-	publicToken, err := parseAccessToken(resp)
-	if err != nil {
-		return nil, err
+	for key, values := range call.header {
+		req.Header[key] = values
 	}
 
-	return publicToken, nil
-}
-
-// SignInByPhoneOnly аутентификация пользователя по номеру телефона без пароля
-func (c *Client) SignInByPhoneOnly(phoneNo string) (*AccessToken, error) {
-	params := url.Values{}
-	params.Set("grant_type", string(GrantTypeBySMS))
-	params.Set("phoneNo", phoneNo)
-
-	u := c.basePath + "/cwaapiinterface/login?" + params.Encode()
+	req = c.withRequestID(req)
+	req = c.withStrictDecodingOperation(operation, req)
 
-	req, err := http.NewRequest("POST", u, nil)
-	if err != nil {
-		return nil, err
+	if c.interceptor != nil {
+		if err := c.interceptor(operation, req); err != nil {
+			return nil, err
+		}
 	}
 
-	req.SetBasicAuth(c.username, c.password)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	if c.isDryRun(operation) {
+		return c.dryRunResponse(operation, req), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrBadResponse
+	if call.timeout == 0 {
+		return c.doAttempts(operation, req, call.retryAttempts)
 	}
 
-	publicToken, err := parseAccessToken(resp)
+	ctx, cancel := context.WithTimeout(req.Context(), call.timeout)
+	resp, err := c.doAttempts(operation, req.WithContext(ctx), call.retryAttempts)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return publicToken, nil
+	// контекст нужен до конца чтения тела ответа
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
 }
 
-// SignInByCardNoOnly аутентификация пользователя по номеру карты без пароля
-func (c *Client) SignInByCardNoOnly(cardNo string) (*AccessToken, error) {
-	params := url.Values{}
-	params.Set("grant_type", string(GrantTypeBySMS))
-	params.Set("cardNo", cardNo)
+// doAttempts выполняет до attempts попыток запроса
+func (c *Client) doAttempts(operation string, req *http.Request, attempts int) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		attemptReq, cancel := req, context.CancelFunc(func() {})
+		if attempt == 1 && attempts > 1 {
+			attemptReq, cancel = c.firstAttemptRequest(req)
+		}
+
+		// разомкнутый breaker прерывает и повторы
+		if err := c.allowAttempt(operation, req); err != nil {
+			cancel()
+			return nil, err
+		}
+
+		resp, err := c.sendWithFailover(operation, attemptReq)
+
+		retryable := c.retryable(resp, err)
+
+		retryErr := err
+		if err == nil && (resp.StatusCode >= http.StatusInternalServerError || retryable) {
+			retryErr = checkResponse(resp)
+		}
+
+		breakerErr := retryErr
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			breakerErr = nil
+		}
+		c.recordAttempt(operation, req, breakerErr)
+
+		// плановые работы не закончатся за время повторов
+		if !retryable || retryErr == nil || attempt >= attempts || errors.Is(retryErr, ErrServiceUnavailable) {
+			outcome := RetryOutcomeSuccess
+			if retryErr != nil {
+				outcome = RetryOutcomeFailure
+			}
+			c.metrics.ObserveRetries(operation, outcome, attempt-1)
+
+			if err == nil && retryErr != nil {
+				// тело ответа прочитано в checkResponse, закрытие освобождает соединение и завершает учет ответа
+				resp.Body.Close()
+				cancel()
+				return nil, retryErr
+			}
+
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			if attemptReq != req {
+				// контекст попытки нужен до конца чтения тела ответа
+				resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			}
+
+			return resp, nil
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+
+		c.log.WithFields(logrus.Fields{
+			"operation":  operation,
+			"request_id": requestID(req),
+			"attempt":    attempt,
+			"error":      retryErr,
+		}).Debug("Comarch request will be retried")
+
+		if c.onRetry != nil {
+			c.onRetry(operation, attempt, retryErr)
+		}
+
+		if err := c.waitRetry(req, resp, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
 
-	u := c.basePath + "/cwaapiinterface/login?" + params.Encode()
+// send выполняет одну попытку запроса
+func (c *Client) send(operation string, req *http.Request) (*http.Response, error) {
+	if c.maxTransferSize > 0 && req.ContentLength > c.maxTransferSize {
+		return nil, ErrTransferTooLarge
+	}
 
-	req, err := http.NewRequest("POST", u, nil)
-	if err != nil {
+	if err := c.waitRateLimit(operation, req); err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.username, c.password)
+	record := c.newTranscript(operation, req)
+	req = c.traceConnReuse(operation, req)
+
+	var resp *http.Response
+	var err error
+	if c.onTimings == nil {
+		resp, err = c.httpClient.Do(req)
+	} else {
+		tracer := newTimingsTracer(req)
+		resp, err = c.httpClient.Do(tracer.wrap(req))
+		c.onTimings(tracer.timings())
+	}
 
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+		c.log.WithFields(logrus.Fields{
+			"operation":  operation,
+			"request_id": requestID(req),
+			"error":      err,
+		}).Debug("Comarch request failed")
+
+		record.fail(err)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrBadResponse
+		return nil, &requestError{requestID: requestID(req), err: classifyTransportError(err)}
 	}
 
-	publicToken, err := parseAccessToken(resp)
-	if err != nil {
-		return nil, err
+	reqDump, _ := httputil.DumpRequest(debugDumpRequest(resp.Request), false)
+	respDump, _ := httputil.DumpResponse(debugDumpResponse(resp), false)
+	c.log.WithFields(logrus.Fields{
+		"operation":  operation,
+		"request_id": requestID(req),
+		"req":        string(reqDump),
+		"req_body":   "",
+		"resp":       string(respDump),
+	}).Debug("Comarch req-resp")
+
+	if c.maxTransferSize > 0 && resp.ContentLength > c.maxTransferSize {
+		resp.Body.Close()
+		record.fail(ErrTransferTooLarge)
+		return nil, ErrTransferTooLarge
 	}
 
-	return publicToken, nil
-}
+	c.observeClockSkew(operation, resp)
 
-// ActivateCardNo активирует номер карты в комархе
-func (c *Client) ActivateCardNo(cardNo string) (*AccessToken, error) {
-	params := url.Values{}
-	params.Set("grant_type", string(GrantTypeCardActivation))
-	params.Set("cardNo", cardNo)
+	resp.Body = c.newCountingBody(operation, req.ContentLength, resp.Body)
+	record.finish(resp)
 
-	u := c.basePath + "/cwaapiinterface/login?" + params.Encode()
+	return resp, nil
+}
 
-	req, err := http.NewRequest("POST", u, nil)
-	if err != nil {
-		return nil, err
-	}
+// endpoint возвращает полный адрес метода комарха. path путь метода относительно префикса api, например /logout
+func (c *Client) endpoint(path string) string {
+	return c.basePath + c.apiPrefix + path
+}
 
-	req.SetBasicAuth(c.username, c.password)
+// basicAuth добавляет в запрос операции operation учетные данные приложения: из WithCredentialProvider,
+// если он задан, иначе переданные в New. Операции из WithAnonymousOperations отправляются без учетных данных.
+// Клиенту без учетных данных, созданному только для работы с токенами, возвращает ErrCredentialsRequired.
+func (c *Client) basicAuth(operation string, req *http.Request) error {
+	if _, ok := c.anonymousOperations[operation]; ok {
+		return nil
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	username, password := c.username, c.password
+	if c.credentialProvider != nil {
+		var err error
+		username, password, err = c.credentialProvider(req.Context())
+		if err != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrBadResponse
+	if username == "" && password == "" {
+		return ErrCredentialsRequired
 	}
 
-	publicToken, err := parseAccessToken(resp)
-	if err != nil {
-		return nil, err
+	if c.basicAuthBuilder != nil {
+		req.Header.Set("Authorization", c.basicAuthBuilder(username, password))
+		return nil
 	}
 
-	return publicToken, nil
+	req.SetBasicAuth(username, password)
+
+	return nil
 }
 
-// ResetPasswordByCardNo сбрасывает пароль дла данного номера карты на дефолтный в комархе
-func (c *Client) ResetPasswordByCardNo(cardNo string) error {
-	u := c.basePath + "/cwaapiinterface/common/passresetting"
-	reqBytes, err := json.Marshal(map[string]string{"cardNo": cardNo})
-	if err != nil {
-		return err
+// resolveToken заменяет токен без значения токеном из контекста (WithTokenContext), а при его отсутствии
+// токеном из WithBearerToken
+func (c *Client) resolveToken(ctx context.Context, accessToken AccessToken) AccessToken {
+	if accessToken.Value != "" {
+		return accessToken
 	}
 
-	req, err := http.NewRequest("POST", u, bytes.NewBuffer(reqBytes))
-	if err != nil {
-		return err
+	if ctxToken, ok := TokenFromContext(ctx); ok {
+		return ctxToken
 	}
 
-	req.SetBasicAuth(c.username, c.password)
+	if c.bearerToken != nil {
+		return *c.bearerToken
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
+	return accessToken
+}
+
+// authorize добавляет в запрос токен пользователя и куки его сессии, если их отправка не отключена WithSendCookies.
+// Токен без значения заменяется токеном из контекста запроса или из WithBearerToken.
+func (c *Client) authorize(req *http.Request, accessToken AccessToken) error {
+	accessToken = c.resolveToken(req.Context(), accessToken)
+	if err := c.checkTokenIssuer(accessToken); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	c.checkRefreshHint(req, accessToken)
+
+	req.Header.Add("Authorization", c.makeAuthHeader(accessToken))
+	if !c.sendCookies {
+		return nil
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return ErrBadResponse
+	for cookieName, cookieValue := range accessToken.Cookies {
+		req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
 	}
 
 	return nil
 }
 
-// ResetPasswordByPhoneNo сбрасывает пароль дла данного номера телефона на дефолтный в комархе
-func (c *Client) ResetPasswordByPhoneNo(phoneNo string) error {
-	u := c.basePath + "/cwaapiinterface/common/passresetting"
-	reqBytes, err := json.Marshal(map[string]string{"phoneNo": phoneNo})
-	if err != nil {
-		return err
-	}
+// SignInByCard аутентификация пользователя по номеру карты и паролю
+func (c *Client) SignInByCard(cardNo string, password string, opts ...CallOption) (*AccessToken, error) {
+	params := url.Values{}
+	params.Set("cardNo", cardNo)
+	params.Set("password", password)
 
-	req, err := http.NewRequest("POST", u, bytes.NewBuffer(reqBytes))
-	if err != nil {
-		return err
-	}
+	return c.signIn("SignInByCard", GrantTypeByCard, params, opts...)
+}
 
-	req.SetBasicAuth(c.username, c.password)
+// SignInByPhone аутентификация пользователя по номеру телефона и паролю
+func (c *Client) SignInByPhone(phoneNo string, password string, opts ...CallOption) (*AccessToken, error) {
+	params := url.Values{}
+	params.Set("phoneNo", phoneNo)
+	params.Set("password", password)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return c.signIn("SignInByPhone", GrantTypeByPhone, params, opts...)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return ErrBadResponse
-	}
+// SignInByPhoneOnly аутентификация пользователя по номеру телефона без пароля. Если участника с таким номером
+// нет, возвращается ErrMemberNotRegistered: участника нужно направить на регистрацию. Код приходит в SMS,
+// голосовой звонок включается опцией WithOTPChannel(OTPChannelVoice)
+func (c *Client) SignInByPhoneOnly(phoneNo string, opts ...CallOption) (*AccessToken, error) {
+	params := url.Values{}
+	params.Set("phoneNo", phoneNo)
 
-	return nil
+	return c.signIn("SignInByPhoneOnly", GrantTypeBySMS, params, opts...)
+}
+
+// SignInByCardNoOnly аутентификация пользователя по номеру карты без пароля
+func (c *Client) SignInByCardNoOnly(cardNo string, opts ...CallOption) (*AccessToken, error) {
+	params := url.Values{}
+	params.Set("cardNo", cardNo)
+
+	return c.signIn("SignInByCardNoOnly", GrantTypeBySMS, params, opts...)
+}
+
+// ActivateCardNo активирует номер карты в комархе. На инсталляциях с двухшаговой активацией вызов только
+// отправляет участнику одноразовый код, активацию завершает ConfirmCardActivation
+func (c *Client) ActivateCardNo(cardNo string, opts ...CallOption) (*AccessToken, error) {
+	params := url.Values{}
+	params.Set("cardNo", cardNo)
+
+	return c.signIn("ActivateCardNo", GrantTypeCardActivation, params, opts...)
+}
+
+// ResetPasswordByCardNo сбрасывает пароль дла данного номера карты на дефолтный в комархе
+func (c *Client) ResetPasswordByCardNo(cardNo string, opts ...CallOption) error {
+	_, err := c.ResetPasswordByCardNoWithResult(cardNo, opts...)
+	return err
+}
+
+// ResetPasswordByCardNoWithResult сбрасывает пароль дла данного номера карты и возвращает,
+// куда комарх отправил новый пароль
+func (c *Client) ResetPasswordByCardNoWithResult(cardNo string, opts ...CallOption) (*PasswordResetResult, error) {
+	return c.resetPassword("ResetPasswordByCardNo", map[string]string{"cardNo": cardNo}, opts...)
+}
+
+// ResetPasswordByPhoneNo сбрасывает пароль дла данного номера телефона на дефолтный в комархе
+func (c *Client) ResetPasswordByPhoneNo(phoneNo string, opts ...CallOption) error {
+	_, err := c.ResetPasswordByPhoneNoWithResult(phoneNo, opts...)
+	return err
+}
+
+// ResetPasswordByPhoneNoWithResult сбрасывает пароль дла данного номера телефона и возвращает,
+// куда комарх отправил новый пароль
+func (c *Client) ResetPasswordByPhoneNoWithResult(phoneNo string, opts ...CallOption) (*PasswordResetResult, error) {
+	return c.resetPassword("ResetPasswordByPhoneNo", map[string]string{"phoneNo": phoneNo}, opts...)
 }
 
 // формат даты, с которым работает комарх
 const DATETIME_FMT = "2006-01-02 15:04"
 
+// формат даты с секундами, который присылают некоторые методы комарха
+const DATETIME_SECONDS_FMT = "2006-01-02 15:04:05"
+
 type BalanceInfoResp struct {
 	// номер карты
 	CardNo string `json:"cardNo"`
-	// последнее посещение
-	// TODO: конвертация в DATETIME_FMT
+	// последнее посещение в формате DATETIME_FMT, разобранное значение возвращает GetLastVisit
 	LastAuth      string          `json:"lastAuth"`
 	BalanceInfo   BalanceInfo     `json:"balanceInfo"`
 	ExpressPoints []ExpressPoints `json:"expressPoints"`
+
+	// часовой пояс дат клиента, который получил баланс
+	location *time.Location
+	// баланс взят из кэша WithBalanceCache без обращения к комарху
+	fromCache bool
+}
+
+// FromCache сообщает, что баланс взят из кэша WithBalanceCache без обращения к комарху, то есть может отставать
+// от комарха не больше, чем на время жизни кэша. Баланс, подтвержденный комархом через условный запрос (304),
+// и баланс без кэша считаются полученными из сети.
+func (b BalanceInfoResp) FromCache() bool {
+	return b.fromCache
 }
 
 type BalanceInfo struct {
@@ -311,6 +602,31 @@ type BalanceInfo struct {
 	BalanceID int `json:"balanceID"`
 	// коэффициент пересчета. курс баллов по отношению к рублю
 	BalanceRate int `json:"balanceRate"`
+	// сумма баллов, начисленных за все время участия, включая потраченные и сгоревшие. Определяет
+	// продвижение по уровням (см. GetMemberTier) и не равна Balance, который можно потратить.
+	// 0, если инсталляция комарха не присылает накопленную сумму
+	LifetimePoints int `json:"lifetimePoints,omitempty"`
+}
+
+// UnmarshalJSON разбирает BalanceInfo. Некоторые версии комарха присылают числа строками ("1500"),
+// поэтому числовые поля принимаются в обоих видах.
+func (b *BalanceInfo) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Balance        flexInt `json:"balance"`
+		BalanceID      flexInt `json:"balanceID"`
+		BalanceRate    flexInt `json:"balanceRate"`
+		LifetimePoints flexInt `json:"lifetimePoints"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	b.Balance = int(raw.Balance)
+	b.BalanceID = int(raw.BalanceID)
+	b.BalanceRate = int(raw.BalanceRate)
+	b.LifetimePoints = int(raw.LifetimePoints)
+
+	return nil
 }
 
 type ExpressPoints struct {
@@ -324,44 +640,156 @@ type ExpressPoints struct {
 	ExpiryDate string `json:"expiryDate"`
 }
 
+// PointsExpiringWithin возвращает сумму экспресс-баллов, которые сгорают в промежутке [now, now+d].
+// Даты сгорания разбираются в часовом поясе now. Баллы без даты сгорания или с неразборчивой датой
+// считаются несгораемыми.
+func (b BalanceInfoResp) PointsExpiringWithin(d time.Duration, now time.Time) int {
+	deadline := now.Add(d)
+
+	total := 0
+	for _, points := range b.ExpressPoints {
+		expiry, err := parseDateTime(points.ExpiryDate, now.Location())
+		if err != nil || expiry.IsZero() {
+			continue
+		}
+
+		if !expiry.Before(now) && !expiry.After(deadline) {
+			total += points.Points
+		}
+	}
+
+	return total
+}
+
 // GetBalanceInfo получает данные о состоянии баланса
-func (c *Client) GetBalanceInfo(accessToken AccessToken) (*BalanceInfoResp, error) {
-	u := c.basePath + "/cwaapiinterface/resources/balanceinfo"
+func (c *Client) GetBalanceInfo(accessToken AccessToken, opts ...CallOption) (*BalanceInfoResp, error) {
+	return c.getBalanceInfo(context.Background(), accessToken, opts...)
+}
+
+// GetBalanceInfoContext получает данные о состоянии баланса. Если accessToken пустой, используется токен
+// из контекста (WithTokenContext)
+func (c *Client) GetBalanceInfoContext(ctx context.Context, accessToken AccessToken, opts ...CallOption) (*BalanceInfoResp, error) {
+	return c.getBalanceInfo(ctx, accessToken, opts...)
+}
+
+func (c *Client) getBalanceInfo(ctx context.Context, accessToken AccessToken, opts ...CallOption) (*BalanceInfoResp, error) {
+	accessToken = c.resolveToken(ctx, accessToken)
+	cached, fresh := c.balanceCache.get(accessToken.Value)
+	if fresh {
+		balance := cached.balance.copy()
+		balance.fromCache = true
+		return balance, nil
+	}
+
+	u := c.endpoint("/resources/balanceinfo")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		cached.setConditionalHeaders(req)
+	}
+
+	resp, err := c.do("GetBalanceInfo", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		c.balanceCache.touch(accessToken.Value)
+		return cached.balance.copy(), nil
+	}
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var balanceInfoResp BalanceInfoResp
+	if err := c.decodeResponse(resp, &balanceInfoResp); err != nil {
+		return nil, err
+	}
+	balanceInfoResp.location = c.location
+
+	c.balanceCache.put(accessToken, &balanceInfoResp, resp)
+
+	return &balanceInfoResp, nil
+}
+
+// GetLastVisit возвращает время последнего посещения участника (LastAuth из баланса) в часовом поясе
+// WithLocation. Комарх не отдает это поле отдельно, поэтому запрашивается баланс, и при WithBalanceCache
+// ответ берется из кэша. Если участник еще ни разу не входил, возвращается нулевое время и ErrNoPreviousVisit.
+func (c *Client) GetLastVisit(accessToken AccessToken, opts ...CallOption) (time.Time, error) {
+	balance, err := c.getBalanceInfo(context.Background(), accessToken, opts...)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	lastVisit, err := parseDateTime(balance.LastAuth, c.location)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if lastVisit.IsZero() {
+		return time.Time{}, ErrNoPreviousVisit
+	}
+
+	return lastVisit, nil
+}
+
+// GetBalanceInfoByCard получает данные о состоянии баланса любой карты по ее номеру. Использует учетные
+// данные клиента вместо токена участника и предназначен для служебных инструментов.
+// Для неизвестной карты возвращает ошибку, соответствующую ErrNotFound.
+func (c *Client) GetBalanceInfoByCard(cardNo string, opts ...CallOption) (*BalanceInfoResp, error) {
+	params := url.Values{}
+	params.Set("cardNo", cardNo)
+
+	u := c.endpoint("/common/balanceinfo") + "?" + params.Encode()
 
 	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", c.makeAuthHeader(accessToken))
-	for cookieName, cookieValue := range accessToken.Cookies {
-		req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
+	if err := c.basicAuth("GetBalanceInfoByCard", req); err != nil {
+		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do("GetBalanceInfoByCard", req, opts...)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrBadResponse
+	if err := checkResponse(resp); err != nil {
+		return nil, err
 	}
 
 	var balanceInfoResp BalanceInfoResp
-	if err := json.NewDecoder(resp.Body).Decode(&balanceInfoResp); err != nil {
+	if err := c.decodeResponse(resp, &balanceInfoResp); err != nil {
 		return nil, err
 	}
+	balanceInfoResp.location = c.location
 
 	return &balanceInfoResp, nil
 }
 
 // ChangePassword изменяет пароля пользователя со старого на новый. текущий пароль не обязателен для установки нового.
-func (c *Client) ChangePassword(accessToken AccessToken, password string, newPassword string) error {
+// Пароли приводятся к NFC (см. WithPasswordNormalization), пароль с управляющими символами отклоняется с *ValidationError.
+func (c *Client) ChangePassword(accessToken AccessToken, password string, newPassword string, opts ...CallOption) error {
+	password, newPassword, err := c.normalizePasswords(password, newPassword)
+	if err != nil {
+		return err
+	}
 
-	u := c.basePath + "/cwaapiinterface/resources/cards/password"
+	u := c.endpoint("/resources/cards/password")
 
-	reqBytes, err := json.Marshal(map[string]string{
+	req, err := c.newJSONRequest("PUT", u, map[string]string{
 		"oldPass": password,
 		"newPass": newPassword,
 	})
@@ -369,26 +797,22 @@ func (c *Client) ChangePassword(accessToken AccessToken, password string, newPas
 		return err
 	}
 
-	req, err := http.NewRequest("PUT", u, bytes.NewBuffer(reqBytes))
-	if err != nil {
+	if err := c.authorize(req, accessToken); err != nil {
 		return err
 	}
 
-	req.Header.Add("Authorization", c.makeAuthHeader(accessToken))
-	for cookieName, cookieValue := range accessToken.Cookies {
-		req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do("ChangePassword", req, opts...)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return ErrBadResponse
+	if err := checkResponse(resp); err != nil {
+		return err
 	}
 
+	c.audit("ChangePassword", tokenSubject(c.resolveToken(req.Context(), accessToken)))
+
 	return nil
 }
 
@@ -487,72 +911,226 @@ type PersonalData struct {
 	//FavPrdSegment Dictionary `json:"favPrdSegment"`
 	// Согласие на обработку и ис- пользование персональных данных: true– даfalse– нет
 	AcceptAdv bool `json:"acceptAdv"`
-	// Пол
-	Sex string `json:"sex"`
+	// Пол. Пустой, если комарх прислал неизвестное значение, тогда оно сохраняется в SexRaw
+	Sex Sex `json:"sex"`
+	// значение пола от комарха, которое не удалось привести к Sex. Отправляется обратно при сохранении анкеты,
+	// если Sex не задан
+	SexRaw string `json:"-"`
 	// Согласие получать Push сооб- щения
 	PushNotification bool `json:"pushNotification"`
-	// Признак “Золотая карта”
+	// Признак “Золотая карта”. Подробный уровень участника возвращает GetMemberTier
 	Golden bool `json:"golden"`
 }
 
+// UnmarshalJSON разбирает анкету. Согласия и признаки комарх присылает в разных видах
+// (true, "true", "Y", 1), все они приводятся к bool, см. flexBool. Неизвестное значение пола
+// сохраняется в SexRaw.
+func (p *PersonalData) UnmarshalJSON(data []byte) error {
+	type personalData PersonalData
+	raw := struct {
+		*personalData
+		Sex               string   `json:"sex"`
+		PostNotification  flexBool `json:"postNotification"`
+		PhoneNotification flexBool `json:"phoneNotification"`
+		MailNotification  flexBool `json:"mailNotification"`
+		SmsAdv            flexBool `json:"smsAdv"`
+		SmslNotification  flexBool `json:"smslNotification"`
+		AcceptAdv         flexBool `json:"acceptAdv"`
+		PushNotification  flexBool `json:"pushNotification"`
+		Golden            flexBool `json:"golden"`
+	}{personalData: (*personalData)(p)}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	p.PostNotification = bool(raw.PostNotification)
+	p.PhoneNotification = bool(raw.PhoneNotification)
+	p.MailNotification = bool(raw.MailNotification)
+	p.SmsAdv = bool(raw.SmsAdv)
+	p.SmslNotification = bool(raw.SmslNotification)
+	p.AcceptAdv = bool(raw.AcceptAdv)
+	p.PushNotification = bool(raw.PushNotification)
+	p.Golden = bool(raw.Golden)
+
+	p.Sex, p.SexRaw = "", ""
+	if sex, ok := parseSex(raw.Sex); ok {
+		p.Sex = sex
+	} else {
+		p.SexRaw = raw.Sex
+	}
+
+	return nil
+}
+
+// MarshalJSON сериализует анкету. Если Sex не задан, отправляется исходное значение из SexRaw,
+// чтобы неизвестный комарху клиента пол не терялся при сохранении прочитанной анкеты.
+func (p PersonalData) MarshalJSON() ([]byte, error) {
+	type personalData PersonalData
+	raw := struct {
+		personalData
+		Sex string `json:"sex"`
+	}{personalData: personalData(p), Sex: string(p.Sex)}
+
+	if p.Sex == "" {
+		raw.Sex = p.SexRaw
+	}
+
+	return json.Marshal(raw)
+}
+
+// prepare проверяет пол анкеты и нормализует мобильный телефон перед отправкой
+func (p PersonalData) prepare() (PersonalData, error) {
+	if p.Sex != "" && !p.Sex.Valid() {
+		return p, &ValidationError{Fields: map[string]string{"sex": "must be SexMale or SexFemale"}}
+	}
+
+	mobilePhone, err := normalizePhoneField("mobilePhone", p.MobilePhone)
+	if err != nil {
+		return p, err
+	}
+	p.MobilePhone = mobilePhone
+
+	return p, nil
+}
+
 // CreateCardHolder создает новую учетную запись. Для доступа к данному методу необходим токен аутентификации клиента. Его можно получить, например, после активации номера карты.
 // обязательными явлюятся след. поля name, surname, birthday, mobilePhone, acceptAdv.
-func (c *Client) CreateCardHolder(accessToken AccessToken, personalData PersonalData) error {
+// Если комарх отклоняет значения полей, возвращается *ValidationError с описанием ошибки по каждому полю.
+// Если анкета для карты уже создана, возвращается ErrCardHolderExists.
+func (c *Client) CreateCardHolder(accessToken AccessToken, personalData PersonalData, opts ...CallOption) error {
+	personalData, err := personalData.prepare()
+	if err != nil {
+		return err
+	}
 
-	u := c.basePath + "/cwaapiinterface/resources/cardholders"
+	u := c.endpoint("/resources/cardholders")
 
-	reqBytes, err := json.Marshal(&personalData)
+	req, err := c.newJSONRequest("PUT", u, &personalData)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("PUT", u, bytes.NewBuffer(reqBytes))
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("CreateCardHolder", req, opts...)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	req.Header.Add("Authorization", c.makeAuthHeader(accessToken))
-	for cookieName, cookieValue := range accessToken.Cookies {
-		req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
+	if err := checkResponse(resp); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return ErrCardHolderExists
+		}
+
+		return asValidationError(err)
+	}
+
+	return nil
+}
+
+// GetCardHolder получает анкету владельца карты
+func (c *Client) GetCardHolder(accessToken AccessToken, opts ...CallOption) (*PersonalData, error) {
+	u := c.endpoint("/resources/cardholders")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetCardHolder", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var personalData PersonalData
+	if err := c.decodeResponse(resp, &personalData); err != nil {
+		return nil, err
+	}
+
+	return &personalData, nil
+}
+
+// UpdateCardHolder обновляет анкету владельца карты. Комарх перезаписывает анкету целиком, поэтому
+// personalData должна содержать все поля, а не только измененные. Ошибки полей возвращаются как *ValidationError.
+func (c *Client) UpdateCardHolder(accessToken AccessToken, personalData PersonalData, opts ...CallOption) error {
+	personalData, err := personalData.prepare()
+	if err != nil {
+		return err
+	}
+
+	u := c.endpoint("/resources/cardholders")
+
+	req, err := c.newJSONRequest("POST", u, &personalData)
+	if err != nil {
+		return err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do("UpdateCardHolder", req, opts...)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return ErrBadResponse
+	if err := checkResponse(resp); err != nil {
+		return asValidationError(err)
 	}
 
 	return nil
 }
 
 // SignOut разлогин переданного токена
-func (c *Client) SignOut(accessToken AccessToken) error {
-	u := c.basePath + "/cwaapiinterface/logout"
+func (c *Client) SignOut(accessToken AccessToken, opts ...CallOption) error {
+	return c.SignOutContext(context.Background(), accessToken, opts...)
+}
 
-	req, err := http.NewRequest("POST", u, nil)
+// SignOutContext разлогин переданного токена. Если комарх отвечает 401, токен уже недействителен,
+// сессии нет и разлогин считается успешным. Если accessToken пустой, используется токен из контекста.
+func (c *Client) SignOutContext(ctx context.Context, accessToken AccessToken, opts ...CallOption) error {
+	u := c.endpoint("/logout")
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Add("Authorization", c.makeAuthHeader(accessToken))
-	for cookieName, cookieValue := range accessToken.Cookies {
-		req.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do("SignOut", req, opts...)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return ErrBadResponse
+	if resp.StatusCode != http.StatusUnauthorized {
+		if err := checkResponse(resp); err != nil {
+			return err
+		}
 	}
 
+	accessToken = c.resolveToken(ctx, accessToken)
+	c.balanceCache.invalidate(accessToken.Value)
+	c.forgetPassword(accessToken)
+	c.emit(EventLogout, "SignOut", tokenSubject(accessToken), nil)
+
 	return nil
 }
 
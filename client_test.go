@@ -1,10 +1,16 @@
 package comarch_test
 
 import (
-	"github.com/kazhuravlev/lib-comarch/comarch"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -16,12 +22,35 @@ const (
 
 	testCredentialsCardNo   = "1111222233334444"
 	testCredentialsPassword = "123456"
+
+	testToken = `{"access_token":"token","token_type":"bearer","expires_in":3600}`
 )
 
 var (
 	log = logrus.New()
+
+	testAccessToken = comarch.AccessToken{Value: "token", Cookies: map[string]string{"JSESSIONID": "session"}}
 )
 
+// newTestServer поднимает фейковый комарх и клиент, настроенный на него
+func newTestServer(t testing.TB, handler http.HandlerFunc, opts ...comarch.Option) *comarch.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, &http.Client{Timeout: time.Second * 1}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// tokenHandler отвечает на логин валидным токеном и сессионной кукой
+func tokenHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session"})
+	w.Write([]byte(testToken))
+}
+
 func TestNew(t *testing.T) {
 	c, err := comarch.New(log, testBasePath, testUsername, testPassword, nil)
 	assert.Nil(t, err)
@@ -34,8 +63,21 @@ func TestNewWithClient(t *testing.T) {
 	assert.NotNil(t, c)
 }
 
+func TestNewWithNilLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(tokenHandler))
+	defer srv.Close()
+
+	c, err := comarch.New(nil, srv.URL, testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	assert.NotPanics(t, func() {
+		_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	})
+	assert.Nil(t, err)
+}
+
 func TestClient_SignInByCard(t *testing.T) {
-	c, _ := comarch.New(log, testBasePath, testUsername, testPassword, &http.Client{Timeout: time.Second * 1})
+	c := newTestServer(t, tokenHandler)
 
 	accessToken, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
 	assert.Nil(t, err)
@@ -43,3 +85,458 @@ func TestClient_SignInByCard(t *testing.T) {
 	assert.True(t, len(accessToken.Cookies) > 0)
 	assert.True(t, len(accessToken.Value) > 0)
 }
+
+func TestWithRequestTimings(t *testing.T) {
+	var timings []comarch.RequestTimings
+	c := newTestServer(t, tokenHandler, comarch.WithRequestTimings(func(rt comarch.RequestTimings) {
+		timings = append(timings, rt)
+	}))
+
+	_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+
+	if assert.Len(t, timings, 1) {
+		assert.Equal(t, "POST", timings[0].Method)
+		assert.Contains(t, timings[0].URL, "/cwaapiinterface/login")
+		assert.NotContains(t, timings[0].URL, "password="+testCredentialsPassword)
+		assert.Contains(t, timings[0].URL, "password=%5BREDACTED%5D")
+		assert.True(t, timings[0].Connect > 0)
+		assert.True(t, timings[0].Total >= timings[0].FirstByte)
+	}
+}
+
+func TestClient_Reauthenticate(t *testing.T) {
+	var logins []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		logins = append(logins, r.URL.RawQuery)
+		tokenHandler(w, r)
+	}
+
+	t.Run("by_password", func(t *testing.T) {
+		logins = nil
+		c := newTestServer(t, handler, comarch.WithReauthentication())
+
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+		assert.Equal(t, comarch.GrantTypeByCard, token.Grant)
+		assert.Equal(t, map[string]string{"cardNo": testCredentialsCardNo}, token.GrantParams)
+
+		newToken, err := c.Reauthenticate(*token)
+		assert.Nil(t, err)
+		assert.NotNil(t, newToken)
+		if assert.Len(t, logins, 2) {
+			assert.Equal(t, logins[0], logins[1])
+		}
+	})
+
+	t.Run("password_forgotten_on_sign_out", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithReauthentication())
+
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+		assert.Nil(t, c.SignOut(*token))
+
+		_, err = c.Reauthenticate(*token)
+		assert.Equal(t, comarch.ErrReauthenticationUnavailable, err)
+	})
+
+	t.Run("password_forgotten_on_rejection", func(t *testing.T) {
+		logins = nil
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			logins = append(logins, r.URL.RawQuery)
+			if len(logins) > 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokenHandler(w, r)
+		}, comarch.WithReauthentication())
+
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+
+		_, err = c.Reauthenticate(*token)
+		assert.True(t, errors.Is(err, comarch.ErrUnauthorized), "got %v", err)
+
+		_, err = c.Reauthenticate(*token)
+		assert.Equal(t, comarch.ErrReauthenticationUnavailable, err)
+		assert.Len(t, logins, 2)
+	})
+
+	t.Run("password_not_kept", func(t *testing.T) {
+		c := newTestServer(t, handler)
+
+		token, err := c.SignInByPhone("79990001122", testCredentialsPassword)
+		assert.Nil(t, err)
+
+		_, err = c.Reauthenticate(*token)
+		assert.Equal(t, comarch.ErrReauthenticationUnavailable, err)
+	})
+
+	t.Run("passwordless", func(t *testing.T) {
+		logins = nil
+		c := newTestServer(t, handler)
+
+		token, err := c.SignInByPhoneOnly("79990001122")
+		assert.Nil(t, err)
+
+		_, err = c.Reauthenticate(*token)
+		assert.Nil(t, err)
+		assert.Len(t, logins, 2)
+	})
+
+	t.Run("card_activation", func(t *testing.T) {
+		c := newTestServer(t, handler)
+
+		token, err := c.ActivateCardNo(testCredentialsCardNo)
+		assert.Nil(t, err)
+
+		_, err = c.Reauthenticate(*token)
+		assert.Equal(t, comarch.ErrReauthenticationUnavailable, err)
+	})
+}
+
+func TestClient_LogsOperation(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	srv := httptest.NewServer(http.HandlerFunc(tokenHandler))
+	defer srv.Close()
+
+	c, err := comarch.New(logger, srv.URL, testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Equal(t, "SignInByCard", hook.LastEntry().Data["operation"])
+	}
+}
+
+func TestBalanceInfoResp_PointsExpiringWithin(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	balance := comarch.BalanceInfoResp{
+		ExpressPoints: []comarch.ExpressPoints{
+			{Points: 1, ExpiryDate: "2020-03-09 12:00"},
+			{Points: 10, ExpiryDate: "2020-03-10 12:00"},
+			{Points: 100, ExpiryDate: "2020-03-17 12:00"},
+			{Points: 1000, ExpiryDate: "2020-03-17 12:01"},
+			{Points: 10000, ExpiryDate: ""},
+			{Points: 100000, ExpiryDate: "never"},
+		},
+	}
+
+	assert.Equal(t, 110, balance.PointsExpiringWithin(time.Hour*24*7, now))
+	assert.Equal(t, 10, balance.PointsExpiringWithin(0, now))
+	assert.Equal(t, 0, comarch.BalanceInfoResp{}.PointsExpiringWithin(time.Hour, now))
+}
+
+func TestBalanceInfoResp_PointsExpiringWithin_Boundaries(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2020, 3, 10, 15, 30, 0, 0, loc)
+	day := time.Hour * 24
+
+	tests := []struct {
+		name   string
+		expiry string
+		within time.Duration
+		want   int
+	}{
+		{name: "expires_today", expiry: "2020-03-10 23:59", within: day, want: 1},
+		{name: "expires_now", expiry: "2020-03-10 15:30", within: 0, want: 1},
+		{name: "expired_minute_ago", expiry: "2020-03-10 15:29", within: day, want: 0},
+		{name: "expired_yesterday", expiry: "2020-03-09 23:59", within: day, want: 0},
+		{name: "expires_in_30_days", expiry: "2020-04-09 15:30", within: day * 30, want: 1},
+		{name: "expires_after_30_days", expiry: "2020-04-09 15:31", within: day * 30, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance := comarch.BalanceInfoResp{
+				ExpressPoints: []comarch.ExpressPoints{{Points: 1, ExpiryDate: tt.expiry}},
+			}
+
+			assert.Equal(t, tt.want, balance.PointsExpiringWithin(tt.within, now))
+		})
+	}
+}
+
+func TestBalanceInfo_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "numbers", data: `{"balance":1500,"balanceID":2,"balanceRate":10,"lifetimePoints":42000}`},
+		{name: "strings", data: `{"balance":"1500","balanceID":"2","balanceRate":"10","lifetimePoints":"42000"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info comarch.BalanceInfo
+			assert.Nil(t, json.Unmarshal([]byte(tt.data), &info))
+			assert.Equal(t, comarch.BalanceInfo{Balance: 1500, BalanceID: 2, BalanceRate: 10, LifetimePoints: 42000}, info)
+		})
+	}
+
+	// инсталляции без накопленной суммы
+	var legacy comarch.BalanceInfo
+	assert.Nil(t, json.Unmarshal([]byte(`{"balance":1500}`), &legacy))
+	assert.Equal(t, 0, legacy.LifetimePoints)
+
+	var info comarch.BalanceInfo
+	assert.NotNil(t, json.Unmarshal([]byte(`{"balance":"много"}`), &info))
+}
+
+func TestWithSendCookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []comarch.Option
+		cookies int
+	}{
+		{name: "default", opts: nil, cookies: 1},
+		{name: "disabled", opts: []comarch.Option{comarch.WithSendCookies(false)}, cookies: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer "+testAccessToken.Value, r.Header.Get("Authorization"))
+				assert.Len(t, r.Cookies(), tt.cookies)
+				w.Write([]byte(`{}`))
+			}, tt.opts...)
+
+			_, err := c.GetBalanceInfo(testAccessToken)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestClient_GetBalanceInfoByCard(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _, ok := r.BasicAuth()
+		assert.True(t, ok)
+
+		if r.URL.Query().Get("cardNo") != testCredentialsCardNo {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{"cardNo":"` + testCredentialsCardNo + `","balanceInfo":{"balance":"1500"}}`))
+	})
+
+	info, err := c.GetBalanceInfoByCard(testCredentialsCardNo)
+	assert.Nil(t, err)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, testCredentialsCardNo, info.CardNo)
+		assert.Equal(t, 1500, info.BalanceInfo.Balance)
+	}
+
+	_, err = c.GetBalanceInfoByCard("0000")
+	assert.True(t, errors.Is(err, comarch.ErrNotFound))
+	assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+}
+
+func TestClient_SignOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "ok", status: http.StatusOK, err: nil},
+		{name: "already_signed_out", status: http.StatusUnauthorized, err: nil},
+		{name: "failure", status: http.StatusInternalServerError, err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/cwaapiinterface/logout", r.URL.Path)
+				w.WriteHeader(tt.status)
+			})
+
+			err := c.SignOutContext(context.Background(), testAccessToken)
+			if tt.err == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.err))
+			}
+		})
+	}
+}
+
+func TestWithBearerToken(t *testing.T) {
+	_, err := comarch.New(log, testBasePath, "", "", nil)
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer upstream", r.Header.Get("Authorization"))
+		_, _, ok := r.BasicAuth()
+		assert.False(t, ok)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c, err := comarch.New(log, srv.URL, "", "", nil, comarch.WithBearerToken(comarch.AccessToken{Value: "upstream"}))
+	assert.Nil(t, err)
+
+	_, err = c.GetBalanceInfo(comarch.AccessToken{})
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Equal(t, comarch.ErrCredentialsRequired, err)
+}
+
+func TestClient_GetLastVisit(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name      string
+		lastAuth  string
+		lastVisit time.Time
+		err       error
+	}{
+		{name: "visited", lastAuth: "2020-03-10 12:30", lastVisit: time.Date(2020, 3, 10, 12, 30, 0, 0, loc)},
+		{name: "with_seconds", lastAuth: "2020-03-10 12:30:15", lastVisit: time.Date(2020, 3, 10, 12, 30, 15, 0, loc)},
+		{name: "iso_without_zone", lastAuth: "2020-03-10T12:30:15", lastVisit: time.Date(2020, 3, 10, 12, 30, 15, 0, loc)},
+		{name: "never", lastAuth: "", err: comarch.ErrNoPreviousVisit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"lastAuth":"` + tt.lastAuth + `"}`))
+			}, comarch.WithLocation(loc))
+
+			lastVisit, err := c.GetLastVisit(testAccessToken)
+			assert.Equal(t, tt.err, err)
+			assert.True(t, tt.lastVisit.Equal(lastVisit))
+			if err == nil {
+				assert.Equal(t, loc, lastVisit.Location())
+			}
+		})
+	}
+}
+
+func TestWithCredentialProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "rotated", username)
+		assert.Equal(t, "secret-"+r.URL.Query().Get("cardNo"), password)
+		tokenHandler(w, r)
+	}))
+	defer srv.Close()
+
+	var cardNo string
+	errRotation := errors.New("secret is rotating")
+	c, err := comarch.New(log, srv.URL, "", "", nil, comarch.WithCredentialProvider(func(ctx context.Context) (string, string, error) {
+		if cardNo == "" {
+			return "", "", errRotation
+		}
+
+		return "rotated", "secret-" + cardNo, nil
+	}))
+	assert.Nil(t, err)
+
+	for _, cardNo = range []string{"1111", "2222"} {
+		_, err = c.SignInByCard(cardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+	}
+
+	cardNo = ""
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Equal(t, errRotation, err)
+}
+
+func TestWithBasicAuthBuilder(t *testing.T) {
+	var header string
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		tokenHandler(w, r)
+	}, comarch.WithBasicAuthBuilder(func(username, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+";"+password))
+	}))
+
+	_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte(testUsername+";"+testPassword)), header)
+}
+
+func TestWithOperationTimeout(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/resources/balanceinfo" {
+			time.Sleep(time.Millisecond * 100)
+		}
+		w.Write([]byte(`{}`))
+	}, comarch.WithOperationTimeout("GetBalanceInfo", time.Millisecond*20), comarch.WithOperationTimeout("GetCardHolder", time.Second))
+
+	_, err := c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrTimeout))
+
+	// тело ответа читается после возврата из do, таймаут не должен его обрывать
+	_, err = c.GetCardHolder(testAccessToken)
+	assert.Nil(t, err)
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithOperationTimeout("GetBalanceInfo", 0))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestWithRefreshHint(t *testing.T) {
+	var hints []time.Duration
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}, comarch.WithRefreshHint(0.5, func(accessToken comarch.AccessToken, ttl time.Duration) {
+		hints = append(hints, ttl)
+	}))
+
+	now := time.Now()
+	fresh := comarch.AccessToken{Value: "token", IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)}
+	old := comarch.AccessToken{Value: "token", IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Minute)}
+
+	assert.True(t, fresh.TimeToLive() > time.Minute*59)
+	assert.Equal(t, time.Duration(0), comarch.AccessToken{}.TimeToLive())
+
+	_, err := c.GetCardHolder(fresh)
+	assert.Nil(t, err)
+	_, err = c.GetCardHolder(testAccessToken)
+	assert.Nil(t, err)
+	assert.Empty(t, hints)
+
+	_, err = c.GetCardHolder(old)
+	assert.Nil(t, err)
+	if assert.Len(t, hints, 1) {
+		assert.True(t, hints[0] <= time.Minute)
+	}
+}
+
+func TestClient_LogsRedactedDump(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	srv := httptest.NewServer(http.HandlerFunc(tokenHandler))
+	defer srv.Close()
+
+	c, err := comarch.New(logger, srv.URL, testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+
+	_, _ = c.GetBalanceInfo(*token)
+
+	dumps := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Message != "Comarch req-resp" {
+			continue
+		}
+		dumps++
+
+		dump := entry.Data["req"].(string) + entry.Data["resp"].(string)
+		assert.NotContains(t, dump, testCredentialsPassword)
+		assert.NotContains(t, dump, "Bearer")
+		assert.NotContains(t, dump, "Basic")
+		assert.NotContains(t, dump, "session")
+		assert.Contains(t, dump, "[REDACTED]")
+	}
+	assert.Equal(t, 2, dumps)
+}
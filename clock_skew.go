@@ -0,0 +1,79 @@
+package comarch
+
+import (
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// clockSkew последний измеренный сдвиг часов комарха
+type clockSkew struct {
+	// порог из WithClockSkewThreshold, 0 отключено
+	threshold     time.Duration
+	correctExpiry bool
+
+	mu     sync.Mutex
+	offset time.Duration
+	// превышал ли порог последний сдвиг
+	exceeded bool
+}
+
+// ClockOffset возвращает сдвиг часов комарха относительно локальных, измеренный по заголовку Date последнего
+// ответа: положительный, если часы комарха спешат. Точность около секунды. 0 до первого ответа с Date.
+func (c *Client) ClockOffset() time.Duration {
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+
+	return c.clock.offset
+}
+
+// observeClockSkew измеряет сдвиг часов по ответу и предупреждает о превышении порога
+func (c *Client) observeClockSkew(operation string, resp *http.Response) {
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	// Date с точностью до секунды
+	offset := serverTime.Sub(time.Now().Truncate(time.Second))
+	c.metrics.ObserveClockSkew(operation, offset)
+
+	exceeded := c.clock.threshold > 0 && abs(offset) > c.clock.threshold
+
+	c.clock.mu.Lock()
+	c.clock.offset = offset
+	wasExceeded := c.clock.exceeded
+	c.clock.exceeded = exceeded
+	c.clock.mu.Unlock()
+
+	if exceeded && !wasExceeded {
+		c.log.WithFields(logrus.Fields{
+			"operation": operation,
+			"offset":    offset,
+			"threshold": c.clock.threshold,
+		}).Warn("Comarch clock skew exceeds threshold")
+	}
+}
+
+// expiryCorrection величина, на которую уменьшается ExpiresAt нового токена при WithClockSkewThreshold
+func (c *Client) expiryCorrection() time.Duration {
+	if !c.clock.correctExpiry || c.clock.threshold <= 0 {
+		return 0
+	}
+
+	offset := abs(c.ClockOffset())
+	if offset <= c.clock.threshold {
+		return 0
+	}
+
+	return offset
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
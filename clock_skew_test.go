@@ -0,0 +1,62 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type clockSkewMetrics struct {
+	comarch.NopMetrics
+
+	offsets []time.Duration
+}
+
+func (m *clockSkewMetrics) ObserveClockSkew(operation string, offset time.Duration) {
+	m.offsets = append(m.offsets, offset)
+}
+
+func TestClient_ClockSkew(t *testing.T) {
+	const skew = time.Minute * 10
+
+	tests := []struct {
+		name          string
+		correctExpiry bool
+		ttl           time.Duration
+	}{
+		{name: "report_only", correctExpiry: false, ttl: time.Hour},
+		{name: "correct_expiry", correctExpiry: true, ttl: time.Hour - skew},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &clockSkewMetrics{}
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Date", time.Now().Add(-skew).UTC().Format(http.TimeFormat))
+				tokenHandler(w, r)
+			}, comarch.WithClockSkewThreshold(time.Minute, tt.correctExpiry), comarch.WithMetrics(metrics))
+
+			assert.Equal(t, time.Duration(0), c.ClockOffset())
+
+			token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			assert.Nil(t, err)
+
+			assert.InDelta(t, float64(-skew), float64(c.ClockOffset()), float64(time.Second*2))
+			assert.InDelta(t, float64(tt.ttl), float64(token.TimeToLive()), float64(time.Second*2))
+			if assert.Len(t, metrics.offsets, 1) {
+				assert.Equal(t, c.ClockOffset(), metrics.offsets[0])
+			}
+		})
+	}
+}
+
+func TestClient_ClockSkew_WithinThreshold(t *testing.T) {
+	c := newTestServer(t, tokenHandler, comarch.WithClockSkewThreshold(time.Minute, true))
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.InDelta(t, 0, float64(c.ClockOffset()), float64(time.Second*2))
+	assert.InDelta(t, float64(time.Hour), float64(token.TimeToLive()), float64(time.Second*2))
+}
@@ -0,0 +1,34 @@
+package comarch
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Codec реализация json, которую использует клиент. Позволяет подключить более быструю библиотеку
+// (jsoniter, sonic) вместо encoding/json.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Decoder потоковый декодер json
+type Decoder interface {
+	Decode(v interface{}) error
+}
+
+// stdCodec Codec на основе encoding/json
+type stdCodec struct{}
+
+func (stdCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (stdCodec) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
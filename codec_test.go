@@ -0,0 +1,131 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// countingCodec encoding/json, считающий вызовы
+type countingCodec struct {
+	marshal int
+	decode  int
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshal++
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (c *countingCodec) NewDecoder(r io.Reader) comarch.Decoder {
+	c.decode++
+	return json.NewDecoder(r)
+}
+
+func TestWithCodec(t *testing.T) {
+	codec := &countingCodec{}
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			tokenHandler(w, r)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}, comarch.WithCodec(codec))
+
+	_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	assert.Nil(t, c.ChangePassword(testAccessToken, "old", "new"))
+
+	assert.Equal(t, 2, codec.decode)
+	assert.Equal(t, 1, codec.marshal)
+}
+
+// plainDecoder декодер без DisallowUnknownFields
+type plainDecoder struct {
+	dec *json.Decoder
+}
+
+func (d plainDecoder) Decode(v interface{}) error {
+	return d.dec.Decode(v)
+}
+
+type plainCodec struct {
+	countingCodec
+}
+
+func (c *plainCodec) NewDecoder(r io.Reader) comarch.Decoder {
+	return plainDecoder{dec: json.NewDecoder(r)}
+}
+
+func TestWithStrictDecoding(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			tokenHandler(w, r)
+		default:
+			w.Write([]byte(`{"cardNo":"1111222233334444","newTier":"gold"}`))
+		}
+	}
+
+	c := newTestServer(t, handler)
+	_, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+
+	c = newTestServer(t, handler, comarch.WithStrictDecoding(true))
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrUnknownField))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `"newTier"`)
+		assert.Contains(t, err.Error(), "BalanceInfoResp")
+	}
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil,
+		comarch.WithCodec(&plainCodec{}),
+		comarch.WithStrictDecoding(true),
+	)
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestWithStrictDecodingExcept(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"token","expires_in":3600,"session":"extra"}`))
+		default:
+			w.Write([]byte(`{"cardNo":"1111222233334444","newTier":"gold"}`))
+		}
+	}
+
+	c := newTestServer(t, handler,
+		comarch.WithStrictDecoding(true),
+		comarch.WithStrictDecodingExcept("GetBalanceInfo"),
+	)
+
+	info, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "1111222233334444", info.CardNo)
+	}
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.True(t, errors.Is(err, comarch.ErrUnknownField))
+
+	c = newTestServer(t, handler, comarch.WithStrictDecodingExcept("GetBalanceInfo"))
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+}
@@ -1,30 +1,370 @@
 package comarch
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 )
 
-// parseAccessToken парсит тело ответа на предмет наличия токена
-func parseAccessToken(resp *http.Response) (*AccessToken, error) {
-	var token accessToken
-	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+// maxTokenLifetime верхняя граница срока жизни токена. Большие expires_in обрезаются до нее, чтобы
+// не переполнить time.Duration
+const maxTokenLifetime = time.Hour * 24 * 365
+
+// parseAccessToken парсит тело ответа на предмет наличия токена. Если задан WithSessionCookieNames,
+// в токен попадают только куки с этими именами, иначе все куки ответа. Из нескольких кук с одним именем
+// действует последняя, кука с пустым значением или отрицательным Max-Age удаляет предыдущую.
+// Ответ без access_token или с отрицательным expires_in возвращает ошибку, соответствующую ErrBadResponse.
+func (c *Client) parseAccessToken(resp *http.Response) (*AccessToken, error) {
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
 		return nil, err
 	}
 
+	var token accessToken
+	if err := c.decode(resp, bytes.NewReader(raw), &token); err != nil {
+		return nil, fmt.Errorf("token response: %w", err)
+	}
+
+	switch {
+	case token.Token == "":
+		return nil, fmt.Errorf("%w: token response has no access_token", ErrBadResponse)
+	case token.ExpiresIn < 0:
+		return nil, fmt.Errorf("%w: token response has negative expires_in %d", ErrBadResponse, token.ExpiresIn)
+	}
+
+	lifetime := maxTokenLifetime
+	if token.ExpiresIn < int64(maxTokenLifetime/time.Second) {
+		lifetime = time.Second * time.Duration(token.ExpiresIn)
+	}
+
+	extra, err := c.extraTokenFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("token response: %w", err)
+	}
+
 	cookies := map[string]string{}
 	for _, cookie := range resp.Cookies() {
+		if len(c.sessionCookieNames) > 0 {
+			if _, ok := c.sessionCookieNames[cookie.Name]; !ok {
+				continue
+			}
+		}
+
+		if cookie.Value == "" || cookie.MaxAge < 0 {
+			delete(cookies, cookie.Name)
+			continue
+		}
+
 		cookies[cookie.Name] = cookie.Value
 	}
 
-	expiresAt := time.Now().Add(time.Second * time.Duration(token.ExpiresIn))
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(lifetime - c.expiryCorrection())
+
+	scopes := []string(token.Scope)
+	if len(scopes) == 0 {
+		scopes = jwtScopes(token.Token)
+	}
 
 	publicToken := AccessToken{
 		Value:     token.Token,
 		ExpiresAt: expiresAt,
+		IssuedAt:  issuedAt,
 		Cookies:   cookies,
+		Scopes:    scopes,
+		Extra:     extra,
+		Issuer:    c.tokenIssuer(),
+		Scheme:    c.authScheme,
+		Features:  token.Features,
 	}
 
 	return &publicToken, nil
 }
+
+// knownTokenFields поля ответа на логин, которые разбираются в accessToken
+var knownTokenFields = []string{"access_token", "token_type", "expires_in", "scope", "features"}
+
+// extraTokenFields возвращает поля ответа на логин, не описанные в accessToken. Nil, если таких нет
+func (c *Client) extraTokenFields(raw []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := c.codec.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, name := range knownTokenFields {
+		delete(fields, name)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
+
+// decodeResponse декодирует json из тела ответа с учетом его кодировки
+func (c *Client) decodeResponse(resp *http.Response, v interface{}) error {
+	body, err := responseBody(resp)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(resp, body, v)
+}
+
+// decodeOptional декодирует json из тела ответа, как decodeResponse, но пустое тело не считается ошибкой:
+// v остается без изменений, возвращается false
+func (c *Client) decodeOptional(resp *http.Response, v interface{}) (bool, error) {
+	if err := c.decodeResponse(resp, v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// strictDecoder декодер, который умеет запрещать неизвестные поля, как json.Decoder
+type strictDecoder interface {
+	DisallowUnknownFields()
+}
+
+// decode декодирует json ответа resp из r в v. При WithStrictDecoding неизвестные поля возвращают
+// ErrUnknownField, кроме операций из WithStrictDecodingExcept
+func (c *Client) decode(resp *http.Response, r io.Reader, v interface{}) error {
+	dec := c.codec.NewDecoder(r)
+	if !c.isStrictDecoding(resp) {
+		return dec.Decode(v)
+	}
+
+	// поддержка проверена в validate
+	dec.(strictDecoder).DisallowUnknownFields()
+
+	return asUnknownFieldError(dec.Decode(v), v)
+}
+
+// asUnknownFieldError переводит ошибку DisallowUnknownFields при разборе v в ErrUnknownField
+func asUnknownFieldError(err error, v interface{}) error {
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return fmt.Errorf("%w %s in %T", ErrUnknownField, strings.TrimPrefix(err.Error(), "json: unknown field "), v)
+	}
+
+	return err
+}
+
+// strictDecodingOperationKey ключ контекста запроса с именем операции для WithStrictDecodingExcept
+type strictDecodingOperationKey struct{}
+
+// withStrictDecodingOperation сохраняет в контексте запроса имя операции, если заданы исключения
+// WithStrictDecodingExcept. Иначе запрос не меняется
+func (c *Client) withStrictDecodingOperation(operation string, req *http.Request) *http.Request {
+	if !c.strictDecoding || len(c.strictDecodingExcept) == 0 {
+		return req
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), strictDecodingOperationKey{}, operation))
+}
+
+// isStrictDecoding проверяет, нужно ли запрещать неизвестные поля в ответе resp
+func (c *Client) isStrictDecoding(resp *http.Response) bool {
+	if !c.strictDecoding {
+		return false
+	}
+
+	if resp == nil || resp.Request == nil {
+		return true
+	}
+
+	operation, _ := resp.Request.Context().Value(strictDecodingOperationKey{}).(string)
+	_, except := c.strictDecodingExcept[operation]
+
+	return !except
+}
+
+// responseBody возвращает распакованное тело ответа в utf-8. Тело распаковывается, если сервер
+// прислал Content-Encoding: gzip. Кодировка берется из параметра charset заголовка Content-Type,
+// при его отсутствии тело считается utf-8.
+func responseBody(resp *http.Response) (io.Reader, error) {
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		body = gz
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return body, nil
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body, nil
+	}
+
+	charset := strings.ToLower(params["charset"])
+	if charset == "" || charset == "utf-8" || charset == "utf8" {
+		return body, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, ErrUnsupportedCharset
+	}
+
+	return enc.NewDecoder().Reader(body), nil
+}
+
+// maxErrorBodySize сколько байт тела неуспешного ответа читается для разбора ошибки
+const maxErrorBodySize = 4096
+
+// checkResponse возвращает *HTTPError, если комарх ответил неуспешным статусом, и *MaintenanceError
+// для ответа о плановых работах
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	httpErr := &HTTPError{StatusCode: resp.StatusCode, RequestID: requestID(resp.Request)}
+
+	body, err := responseBody(resp)
+	if err != nil {
+		return asMaintenanceError(resp, httpErr)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	if err != nil {
+		return asMaintenanceError(resp, httpErr)
+	}
+
+	httpErr.Body = string(data)
+
+	var apiErr APIError
+	if err := json.Unmarshal(data, &apiErr); err == nil && (apiErr.Code != "" || apiErr.Message != "") {
+		httpErr.APIError = &apiErr
+	}
+
+	return asMaintenanceError(resp, httpErr)
+}
+
+// DateTimeLayouts форматы дат, которые присылают разные методы комарха, в порядке проверки: DATETIME_FMT,
+// с секундами и ISO 8601. Даты без часового пояса разбираются в поясе WithLocation
+var DateTimeLayouts = []string{
+	DATETIME_FMT,
+	DATETIME_SECONDS_FMT,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// ParseDateTime разбирает дату комарха в одном из DateTimeLayouts и возвращает формат, который подошел.
+// Даты без часового пояса разбираются в loc. Пустая строка означает отсутствие даты, для нее возвращается
+// нулевое время и пустой формат. Если не подошел ни один формат, возвращается ошибка ErrBadResponse.
+func ParseDateTime(value string, loc *time.Location) (time.Time, string, error) {
+	if value == "" {
+		return time.Time{}, "", nil
+	}
+
+	for _, layout := range DateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, layout, nil
+		}
+	}
+
+	return time.Time{}, "", fmt.Errorf("%w: date %q does not match any of %q", ErrBadResponse, value, DateTimeLayouts)
+}
+
+// parseDateTime разбирает дату комарха, см. ParseDateTime
+func parseDateTime(value string, loc *time.Location) (time.Time, error) {
+	t, _, err := ParseDateTime(value, loc)
+
+	return t, err
+}
+
+// newJSONRequest создает запрос с телом body в формате json
+func (c *Client) newJSONRequest(method, u string, body interface{}) (*http.Request, error) {
+	reqBytes, err := c.codec.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// flexInt целое число, которое в json может быть как числом, так и строкой
+type flexInt int
+
+func (i *flexInt) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		data = []byte(strings.TrimSpace(s))
+		if len(data) == 0 {
+			*i = 0
+			return nil
+		}
+	}
+
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+
+	*i = flexInt(n)
+
+	return nil
+}
+
+// flexBool логическое значение, которое комарх в разных версиях присылает как true/false,
+// строки "true"/"false", "Y"/"N" или числа 1/0. null и пустая строка означают false.
+// Незнакомое значение считается ошибкой, а не false: от этих полей зависят согласия участника.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "y", "yes", "1":
+		*b = true
+	case "false", "n", "no", "0", "", "null":
+		*b = false
+	default:
+		return fmt.Errorf("%w: invalid boolean %s", ErrBadResponse, data)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetBalanceInfo_Charset(t *testing.T) {
+	body, err := charmap.Windows1251.NewEncoder().String(`{"cardNo":"Карта"}`)
+	assert.Nil(t, err)
+
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=windows-1251")
+		w.Write([]byte(body))
+	})
+
+	info, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "Карта", info.CardNo)
+	}
+}
+
+func TestClient_GetBalanceInfo_DefaultCharset(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cardNo":"Карта"}`))
+	})
+
+	info, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "Карта", info.CardNo)
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name   string
+		value  string
+		time   time.Time
+		layout string
+		err    error
+	}{
+		{name: "minutes", value: "2020-03-10 12:30", time: time.Date(2020, 3, 10, 12, 30, 0, 0, loc), layout: comarch.DATETIME_FMT},
+		{name: "seconds", value: "2020-03-10 12:30:15", time: time.Date(2020, 3, 10, 12, 30, 15, 0, loc), layout: comarch.DATETIME_SECONDS_FMT},
+		{name: "iso_utc", value: "2020-03-10T09:30:15Z", time: time.Date(2020, 3, 10, 12, 30, 15, 0, loc), layout: time.RFC3339Nano},
+		{name: "iso_offset_fraction", value: "2020-03-10T12:30:15.5+03:00", time: time.Date(2020, 3, 10, 12, 30, 15, 5e8, loc), layout: time.RFC3339Nano},
+		{name: "iso_local", value: "2020-03-10T12:30", time: time.Date(2020, 3, 10, 12, 30, 0, 0, loc), layout: "2006-01-02T15:04"},
+		{name: "empty", value: ""},
+		{name: "unknown", value: "10.03.2020 12:30", err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, layout, err := comarch.ParseDateTime(tt.value, loc)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err))
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tt.value)
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.layout, layout)
+			assert.True(t, tt.time.Equal(parsed), parsed)
+		})
+	}
+}
+
+func TestClient_ParseAccessToken_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		cookies []string
+		err     error
+		ttl     time.Duration
+		want    map[string]string
+	}{
+		{name: "no_access_token", body: `{"expires_in":3600}`, err: comarch.ErrBadResponse},
+		{name: "null", body: `null`, err: comarch.ErrBadResponse},
+		{name: "negative_expires_in", body: `{"access_token":"token","expires_in":-1}`, err: comarch.ErrBadResponse},
+		{name: "string_expires_in", body: `{"access_token":"token","expires_in":"3600"}`, err: errors.New("")},
+		{
+			name: "huge_expires_in",
+			body: `{"access_token":"token","expires_in":9223372036854775807}`,
+			ttl:  time.Hour * 24 * 365,
+			want: map[string]string{},
+		},
+		{
+			name:    "duplicate_cookies",
+			body:    testToken,
+			cookies: []string{"JSESSIONID=first", "JSESSIONID=second", "route=a", "route=; Max-Age=0", "stale=x", "stale=x; Max-Age=-1"},
+			ttl:     time.Hour,
+			want:    map[string]string{"JSESSIONID": "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				for _, cookie := range tt.cookies {
+					w.Header().Add("Set-Cookie", cookie)
+				}
+				w.Write([]byte(tt.body))
+			})
+
+			token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			if tt.err != nil {
+				assert.NotNil(t, err)
+				if tt.err.Error() != "" {
+					assert.True(t, errors.Is(err, tt.err), "got %v", err)
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Nil(t, token.Validate())
+			assert.True(t, token.TimeToLive() <= tt.ttl && token.TimeToLive() > tt.ttl-time.Minute, "ttl %s", token.TimeToLive())
+			assert.Equal(t, tt.want, token.Cookies)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package comarch
+
+import (
+	"crypto/tls"
+	"net/url"
+	"strings"
+)
+
+// validate проверяет настройки клиента после применения опций
+func (c *Client) validate() error {
+	switch {
+	case c.concurrency < 1:
+		return &ConfigError{Field: "WithConcurrency", Reason: "must be at least 1"}
+	case c.retryAttempts < 1:
+		return &ConfigError{Field: "WithRetry", Reason: "must be at least 1"}
+	case !(c.firstAttemptShare > 0 && c.firstAttemptShare <= 1):
+		return &ConfigError{Field: "WithFirstAttemptShare", Reason: "must be in (0, 1]"}
+	case c.backoff == nil:
+		return &ConfigError{Field: "WithBackoffStrategy", Reason: "must not be nil"}
+	case c.retryable == nil:
+		return &ConfigError{Field: "WithRetryClassifier", Reason: "must not be nil"}
+	case c.metrics == nil:
+		return &ConfigError{Field: "WithMetrics", Reason: "must not be nil"}
+	case c.codec == nil:
+		return &ConfigError{Field: "WithCodec", Reason: "must not be nil"}
+	case c.requestIDGenerator == nil:
+		return &ConfigError{Field: "WithRequestIDGenerator", Reason: "must not be nil"}
+	case c.location == nil:
+		return &ConfigError{Field: "WithLocation", Reason: "must not be nil"}
+	}
+
+	if c.strictDecoding {
+		if _, ok := c.codec.NewDecoder(strings.NewReader("")).(strictDecoder); !ok {
+			return &ConfigError{Field: "WithStrictDecoding", Reason: "decoder of WithCodec does not support DisallowUnknownFields"}
+		}
+	}
+
+	if c.breakerPerOperation && c.breaker == nil {
+		return &ConfigError{Field: "WithCircuitBreakerPerOperation", Reason: "requires WithCircuitBreaker"}
+	}
+
+	if c.breaker != nil && (c.breaker.threshold < 1 || c.breaker.cooldown <= 0) {
+		return &ConfigError{Field: "WithCircuitBreaker", Reason: "failures must be at least 1 and cooldown positive"}
+	}
+
+	if c.limiter != nil && (c.limiter.rate <= 0 || c.limiter.burst < 1) {
+		return &ConfigError{Field: "WithRateLimit", Reason: "rps must be positive and burst at least 1"}
+	}
+
+	if c.transport.has("WithProxy") && c.transport.proxyURL == nil {
+		return &ConfigError{Field: "WithProxy", Reason: "must not be nil"}
+	}
+
+	if v := c.transport.minTLSVersion; c.transport.has("WithMinTLSVersion") && (v < tls.VersionTLS10 || v > tls.VersionTLS13) {
+		return &ConfigError{Field: "WithMinTLSVersion", Reason: "must be a tls.VersionTLS* constant"}
+	}
+
+	if c.watchInterval <= 0 {
+		return &ConfigError{Field: "WithWatchInterval", Reason: "must be positive"}
+	}
+
+	if c.clock.threshold < 0 {
+		return &ConfigError{Field: "WithClockSkewThreshold", Reason: "must not be negative"}
+	}
+
+	if c.refreshHintThreshold < 0 || c.refreshHintThreshold > 1 {
+		return &ConfigError{Field: "WithRefreshHint", Reason: "threshold must be between 0 and 1"}
+	}
+
+	for operation, timeout := range c.operationTimeouts {
+		if timeout <= 0 {
+			return &ConfigError{Field: "WithOperationTimeout", Reason: "timeout of " + operation + " must be positive"}
+		}
+	}
+
+	for _, backup := range c.backupBasePaths {
+		if err := validateBaseURL("WithBackupBaseURLs", backup); err != nil {
+			return err
+		}
+	}
+
+	if c.hostHeader != "" {
+		if u, err := url.Parse("http://" + c.hostHeader); err != nil || u.Host != c.hostHeader || u.Hostname() == "" {
+			return &ConfigError{Field: "WithHostHeader", Reason: "must be a host name with an optional port"}
+		}
+	}
+
+	for grant, method := range c.loginMethods {
+		switch {
+		case method != "GET" && method != "POST":
+			return &ConfigError{Field: "WithLoginMethod", Reason: "method of " + string(grant) + " must be GET or POST"}
+		case !c.isKnownGrantType(grant):
+			return &ConfigError{Field: "WithLoginMethod", Reason: "unknown grant type " + string(grant)}
+		case method == "GET" && c.isFormLogin(grant):
+			return &ConfigError{Field: "WithLoginMethod", Reason: "GET login of " + string(grant) + " can not be combined with WithFormLogin"}
+		}
+	}
+
+	if c.authScheme == "" || strings.ContainsAny(c.authScheme, " \t\r\n") {
+		return &ConfigError{Field: "WithAuthScheme", Reason: "must be a non-empty token without spaces"}
+	}
+
+	if c.apiPrefix != "" && (!strings.HasPrefix(c.apiPrefix, "/") || strings.HasSuffix(c.apiPrefix, "/")) {
+		return &ConfigError{Field: "WithAPIPrefix", Reason: `must start with "/" and must not end with "/"`}
+	}
+
+	// нужен хотя бы один способ авторизации
+	if c.username == "" && c.password == "" && c.credentialProvider == nil && c.bearerToken == nil {
+		return &ConfigError{Field: "login", Reason: "credentials, WithCredentialProvider or WithBearerToken are required"}
+	}
+
+	return nil
+}
+
+// validateBaseURL проверяет адрес комарха: абсолютный http(s) адрес без "/" в конце
+func validateBaseURL(field, value string) error {
+	if strings.HasSuffix(value, "/") {
+		return &ConfigError{Field: field, Reason: `must not end with "/"`}
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return &ConfigError{Field: field, Reason: err.Error()}
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &ConfigError{Field: field, Reason: "must start with http:// or https://"}
+	}
+
+	if u.Host == "" {
+		return &ConfigError{Field: field, Reason: "must contain a host"}
+	}
+
+	return nil
+}
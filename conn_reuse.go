@@ -0,0 +1,22 @@
+package comarch
+
+import (
+	"net/http"
+	"net/http/httptrace"
+)
+
+// traceConnReuse подключает к запросу трассировку, которая сообщает в Metrics.ObserveConnection, получил ли
+// запрос соединение из пула. Без WithMetrics запрос не меняется
+func (c *Client) traceConnReuse(operation string, req *http.Request) *http.Request {
+	if _, ok := c.metrics.(NopMetrics); ok {
+		return req
+	}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.metrics.ObserveConnection(operation, info.Reused, info.IdleTime)
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
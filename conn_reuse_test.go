@@ -0,0 +1,38 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type connMetrics struct {
+	comarch.NopMetrics
+
+	mu     sync.Mutex
+	reused []bool
+}
+
+func (m *connMetrics) ObserveConnection(operation string, reused bool, idle time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reused = append(m.reused, reused)
+}
+
+func TestClient_ObserveConnection(t *testing.T) {
+	metrics := &connMetrics{}
+	c := newTestServer(t, tokenHandler, comarch.WithMetrics(metrics))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	assert.Equal(t, []bool{false, true}, metrics.reused)
+}
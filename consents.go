@@ -0,0 +1,233 @@
+package comarch
+
+import "errors"
+
+// NotificationPreferences согласия участника на связь с ним и на рекламу
+type NotificationPreferences struct {
+	// связь по почте
+	Post bool `json:"post"`
+	// связь через оператора горячей линии
+	Phone bool `json:"phone"`
+	// связь по электронной почте
+	Mail bool `json:"mail"`
+	// получение SMS
+	SMS bool `json:"sms"`
+	// получение push сообщений
+	Push bool `json:"push"`
+	// согласие на получение рекламы
+	Advertising bool `json:"advertising"`
+	// согласие на обработку и использование персональных данных
+	DataProcessing bool `json:"data_processing"`
+}
+
+// NotificationPreferences возвращает согласия из анкеты
+func (p PersonalData) NotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{
+		Post:           p.PostNotification,
+		Phone:          p.PhoneNotification,
+		Mail:           p.MailNotification,
+		SMS:            p.SmslNotification,
+		Push:           p.PushNotification,
+		Advertising:    p.SmsAdv,
+		DataProcessing: p.AcceptAdv,
+	}
+}
+
+// Правила комарха для рассылок. Без согласия на обработку персональных данных (DataProcessing) участнику
+// ничего не отправляется. Сервисное сообщение требует согласия на канал, рекламное - еще и согласия
+// на рекламу (Advertising), которое общее для всех каналов:
+//
+//	DataProcessing | канал | Advertising | сервисное | рекламное
+//	false          | *     | *           | нет       | нет
+//	true           | false | *           | нет       | нет
+//	true           | true  | false       | да        | нет
+//	true           | true  | true        | да        | да
+//
+// Каналы: SMS - SMS, Mail - электронная почта, Push - push сообщения.
+
+// CanSendSMS можно ли отправить участнику сервисное SMS
+func (p NotificationPreferences) CanSendSMS() bool {
+	return p.DataProcessing && p.SMS
+}
+
+// CanSendAdvertisingSMS можно ли отправить участнику рекламное SMS
+func (p NotificationPreferences) CanSendAdvertisingSMS() bool {
+	return p.CanSendSMS() && p.Advertising
+}
+
+// CanSendEmail можно ли отправить участнику сервисное письмо
+func (p NotificationPreferences) CanSendEmail() bool {
+	return p.DataProcessing && p.Mail
+}
+
+// CanSendAdvertisingEmail можно ли отправить участнику рекламное письмо
+func (p NotificationPreferences) CanSendAdvertisingEmail() bool {
+	return p.CanSendEmail() && p.Advertising
+}
+
+// CanSendPush можно ли отправить участнику сервисное push сообщение
+func (p NotificationPreferences) CanSendPush() bool {
+	return p.DataProcessing && p.Push
+}
+
+// CanSendAdvertisingPush можно ли отправить участнику рекламное push сообщение
+func (p NotificationPreferences) CanSendAdvertisingPush() bool {
+	return p.CanSendPush() && p.Advertising
+}
+
+// CanSendAdvertisingSMS можно ли отправить участнику рекламное SMS, см. NotificationPreferences
+func (p PersonalData) CanSendAdvertisingSMS() bool {
+	return p.NotificationPreferences().CanSendAdvertisingSMS()
+}
+
+// CanSendEmail можно ли отправить участнику сервисное письмо, см. NotificationPreferences
+func (p PersonalData) CanSendEmail() bool {
+	return p.NotificationPreferences().CanSendEmail()
+}
+
+// CanSendPush можно ли отправить участнику сервисное push сообщение, см. NotificationPreferences
+func (p PersonalData) CanSendPush() bool {
+	return p.NotificationPreferences().CanSendPush()
+}
+
+// SetNotificationPreferences переносит согласия в анкету, остальные поля анкеты не меняются
+func (p *PersonalData) SetNotificationPreferences(prefs NotificationPreferences) {
+	p.PostNotification = prefs.Post
+	p.PhoneNotification = prefs.Phone
+	p.MailNotification = prefs.Mail
+	p.SmslNotification = prefs.SMS
+	p.PushNotification = prefs.Push
+	p.SmsAdv = prefs.Advertising
+	p.AcceptAdv = prefs.DataProcessing
+}
+
+// GetConsents получает согласия участника из его анкеты
+func (c *Client) GetConsents(accessToken AccessToken, opts ...CallOption) (*NotificationPreferences, error) {
+	personalData, err := c.GetCardHolder(accessToken, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := personalData.NotificationPreferences()
+
+	return &prefs, nil
+}
+
+// UpdateConsents обновляет согласия участника. Анкета читается заново, в ней меняются только согласия,
+// после чего она сохраняется целиком. Если комарх отклоняет часть согласий, возвращается *ValidationError;
+// сохранить остальные и узнать результат по каждому согласию позволяет UpdateConsentsWithResult.
+func (c *Client) UpdateConsents(accessToken AccessToken, prefs NotificationPreferences, opts ...CallOption) error {
+	personalData, err := c.GetCardHolder(accessToken, opts...)
+	if err != nil {
+		return err
+	}
+
+	personalData.SetNotificationPreferences(prefs)
+
+	return c.UpdateCardHolder(accessToken, *personalData, opts...)
+}
+
+// ConsentsUpdateResult итог UpdateConsentsWithResult по каждому измененному согласию. Согласия называются
+// так же, как поля NotificationPreferences в json: post, phone, mail, sms, push, advertising, data_processing
+type ConsentsUpdateResult struct {
+	// измененные и сохраненные согласия
+	Applied []string
+	// отклоненные комархом согласия: имя -> причина. Такие согласия остались прежними
+	Rejected map[string]string
+	// согласия участника после обновления
+	Preferences NotificationPreferences
+}
+
+// consentFields согласия NotificationPreferences и соответствующие им поля анкеты, в порядке ConsentsUpdateResult.Applied
+var consentFields = []struct {
+	name  string
+	field string
+	value func(p *NotificationPreferences) *bool
+}{
+	{name: "post", field: "postNotification", value: func(p *NotificationPreferences) *bool { return &p.Post }},
+	{name: "phone", field: "phoneNotification", value: func(p *NotificationPreferences) *bool { return &p.Phone }},
+	{name: "mail", field: "mailNotification", value: func(p *NotificationPreferences) *bool { return &p.Mail }},
+	{name: "sms", field: "smslNotification", value: func(p *NotificationPreferences) *bool { return &p.SMS }},
+	{name: "push", field: "pushNotification", value: func(p *NotificationPreferences) *bool { return &p.Push }},
+	{name: "advertising", field: "smsAdv", value: func(p *NotificationPreferences) *bool { return &p.Advertising }},
+	{name: "data_processing", field: "acceptAdv", value: func(p *NotificationPreferences) *bool { return &p.DataProcessing }},
+}
+
+// UpdateConsentsWithResult обновляет согласия участника, как UpdateConsents, но отказ комарха в части
+// согласий (например, SMS без подтвержденного телефона) не считается ошибкой: отклоненные согласия
+// остаются прежними, остальные сохраняются повторным запросом, а результат по каждому согласию
+// возвращается в ConsentsUpdateResult. Ошибка возвращается, если комарх отклонил поля анкеты, которые
+// не являются измененными согласиями, или запрос не удался.
+func (c *Client) UpdateConsentsWithResult(accessToken AccessToken, prefs NotificationPreferences, opts ...CallOption) (*ConsentsUpdateResult, error) {
+	personalData, err := c.GetCardHolder(accessToken, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	current := personalData.NotificationPreferences()
+	result := &ConsentsUpdateResult{Rejected: map[string]string{}, Preferences: current}
+
+	changed := changedConsents(current, prefs)
+	if len(changed) == 0 {
+		return result, nil
+	}
+
+	personalData.SetNotificationPreferences(prefs)
+	err = c.UpdateCardHolder(accessToken, *personalData, opts...)
+
+	var validationErr *ValidationError
+	if err != nil && errors.As(err, &validationErr) {
+		for _, consent := range consentFields {
+			reason, ok := validationErr.Fields[consent.field]
+			if !ok {
+				continue
+			}
+			if !containsString(changed, consent.name) {
+				return nil, err
+			}
+
+			result.Rejected[consent.name] = reason
+			*consent.value(&prefs) = *consent.value(&current)
+		}
+		if len(result.Rejected) != len(validationErr.Fields) {
+			return nil, err
+		}
+
+		changed = changedConsents(current, prefs)
+		err = nil
+		if len(changed) > 0 {
+			personalData.SetNotificationPreferences(prefs)
+			err = c.UpdateCardHolder(accessToken, *personalData, opts...)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Applied = changed
+	result.Preferences = prefs
+
+	return result, nil
+}
+
+// changedConsents возвращает имена согласий, которые отличаются в from и to
+func changedConsents(from, to NotificationPreferences) []string {
+	var changed []string
+	for _, consent := range consentFields {
+		if *consent.value(&from) != *consent.value(&to) {
+			changed = append(changed, consent.name)
+		}
+	}
+
+	return changed
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,225 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_UpdateConsents(t *testing.T) {
+	var updated comarch.PersonalData
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"name":"Иван","mailNotification":true,"smsAdv":true,"acceptAdv":true}`))
+		case "POST":
+			json.NewDecoder(r.Body).Decode(&updated)
+		}
+	})
+
+	prefs, err := c.GetConsents(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, &comarch.NotificationPreferences{Mail: true, Advertising: true, DataProcessing: true}, prefs)
+
+	prefs.Mail = false
+	prefs.Push = true
+	assert.Nil(t, c.UpdateConsents(testAccessToken, *prefs))
+
+	assert.Equal(t, "Иван", updated.Name)
+	assert.False(t, updated.MailNotification)
+	assert.True(t, updated.PushNotification)
+	assert.True(t, updated.SmsAdv)
+	assert.True(t, updated.AcceptAdv)
+}
+
+func TestClient_UpdateConsentsWithResult(t *testing.T) {
+	const current = `{"name":"Иван","mailNotification":true,"acceptAdv":true}`
+	const smsRejected = `{"fieldErrors":[{"field":"smslNotification","message":"phone is not verified"}]}`
+
+	tests := []struct {
+		name     string
+		prefs    comarch.NotificationPreferences
+		answers  []string
+		saved    []comarch.NotificationPreferences
+		result   *comarch.ConsentsUpdateResult
+		rejected bool
+	}{
+		{
+			name:    "all_applied",
+			prefs:   comarch.NotificationPreferences{Mail: true, Push: true, DataProcessing: true},
+			answers: []string{""},
+			saved:   []comarch.NotificationPreferences{{Mail: true, Push: true, DataProcessing: true}},
+			result: &comarch.ConsentsUpdateResult{
+				Applied:     []string{"push"},
+				Rejected:    map[string]string{},
+				Preferences: comarch.NotificationPreferences{Mail: true, Push: true, DataProcessing: true},
+			},
+		},
+		{
+			name:    "partially_rejected",
+			prefs:   comarch.NotificationPreferences{SMS: true, Push: true, DataProcessing: true},
+			answers: []string{smsRejected, ""},
+			saved: []comarch.NotificationPreferences{
+				{SMS: true, Push: true, DataProcessing: true},
+				{Push: true, DataProcessing: true},
+			},
+			result: &comarch.ConsentsUpdateResult{
+				Applied:     []string{"mail", "push"},
+				Rejected:    map[string]string{"sms": "phone is not verified"},
+				Preferences: comarch.NotificationPreferences{Push: true, DataProcessing: true},
+			},
+		},
+		{
+			name:    "all_rejected",
+			prefs:   comarch.NotificationPreferences{Mail: true, SMS: true, DataProcessing: true},
+			answers: []string{smsRejected},
+			saved:   []comarch.NotificationPreferences{{Mail: true, SMS: true, DataProcessing: true}},
+			result: &comarch.ConsentsUpdateResult{
+				Rejected:    map[string]string{"sms": "phone is not verified"},
+				Preferences: comarch.NotificationPreferences{Mail: true, DataProcessing: true},
+			},
+		},
+		{
+			name:  "unchanged",
+			prefs: comarch.NotificationPreferences{Mail: true, DataProcessing: true},
+			result: &comarch.ConsentsUpdateResult{
+				Rejected:    map[string]string{},
+				Preferences: comarch.NotificationPreferences{Mail: true, DataProcessing: true},
+			},
+		},
+		{
+			name:     "other_field_rejected",
+			prefs:    comarch.NotificationPreferences{Mail: true, Push: true, DataProcessing: true},
+			answers:  []string{`{"fieldErrors":[{"field":"birthday","message":"required"}]}`},
+			saved:    []comarch.NotificationPreferences{{Mail: true, Push: true, DataProcessing: true}},
+			rejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved []comarch.NotificationPreferences
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case "GET":
+					w.Write([]byte(current))
+				case "POST":
+					var updated comarch.PersonalData
+					json.NewDecoder(r.Body).Decode(&updated)
+					assert.Equal(t, "Иван", updated.Name)
+					saved = append(saved, updated.NotificationPreferences())
+
+					if answer := tt.answers[len(saved)-1]; answer != "" {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(answer))
+					}
+				}
+			})
+
+			result, err := c.UpdateConsentsWithResult(testAccessToken, tt.prefs)
+			assert.Equal(t, tt.saved, saved)
+			if tt.rejected {
+				var validationErr *comarch.ValidationError
+				assert.True(t, errors.As(err, &validationErr))
+				assert.Nil(t, result)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestPersonalData_UnmarshalJSON_Booleans(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{name: "bool_true", value: `true`, want: true},
+		{name: "bool_false", value: `false`, want: false},
+		{name: "string_true", value: `"true"`, want: true},
+		{name: "string_false", value: `"false"`, want: false},
+		{name: "string_upper", value: `"TRUE"`, want: true},
+		{name: "yes", value: `"Y"`, want: true},
+		{name: "no", value: `"N"`, want: false},
+		{name: "number_one", value: `1`, want: true},
+		{name: "number_zero", value: `0`, want: false},
+		{name: "string_one", value: `"1"`, want: true},
+		{name: "null", value: `null`, want: false},
+		{name: "empty", value: `""`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data comarch.PersonalData
+			assert.Nil(t, json.Unmarshal([]byte(`{"name":"Иван","smsAdv":`+tt.value+`,"acceptAdv":`+tt.value+`}`), &data))
+			assert.Equal(t, "Иван", data.Name)
+			assert.Equal(t, tt.want, data.SmsAdv)
+			assert.Equal(t, tt.want, data.AcceptAdv)
+		})
+	}
+
+	var data comarch.PersonalData
+	assert.NotNil(t, json.Unmarshal([]byte(`{"acceptAdv":"maybe"}`), &data))
+}
+
+func TestClient_GetConsents_StringBooleans(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"mailNotification":"Y","smsAdv":"true","acceptAdv":"N","pushNotification":1}`))
+	})
+
+	prefs, err := c.GetConsents(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, &comarch.NotificationPreferences{Mail: true, Advertising: true, Push: true}, prefs)
+}
+
+func TestNotificationPreferences_CanSend(t *testing.T) {
+	tests := []struct {
+		name           string
+		dataProcessing bool
+		channel        bool
+		advertising    bool
+		service        bool
+		adv            bool
+	}{
+		{name: "no_data_processing", dataProcessing: false, channel: true, advertising: true, service: false, adv: false},
+		{name: "no_channel", dataProcessing: true, channel: false, advertising: true, service: false, adv: false},
+		{name: "service_only", dataProcessing: true, channel: true, advertising: false, service: true, adv: false},
+		{name: "all", dataProcessing: true, channel: true, advertising: true, service: true, adv: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := comarch.NotificationPreferences{
+				SMS:            tt.channel,
+				Mail:           tt.channel,
+				Push:           tt.channel,
+				Advertising:    tt.advertising,
+				DataProcessing: tt.dataProcessing,
+			}
+
+			assert.Equal(t, tt.service, prefs.CanSendSMS())
+			assert.Equal(t, tt.adv, prefs.CanSendAdvertisingSMS())
+			assert.Equal(t, tt.service, prefs.CanSendEmail())
+			assert.Equal(t, tt.adv, prefs.CanSendAdvertisingEmail())
+			assert.Equal(t, tt.service, prefs.CanSendPush())
+			assert.Equal(t, tt.adv, prefs.CanSendAdvertisingPush())
+
+			var personalData comarch.PersonalData
+			personalData.SetNotificationPreferences(prefs)
+			assert.Equal(t, tt.adv, personalData.CanSendAdvertisingSMS())
+			assert.Equal(t, tt.service, personalData.CanSendEmail())
+			assert.Equal(t, tt.service, personalData.CanSendPush())
+		})
+	}
+
+	// рекламное SMS требует согласия именно на SMS, а не на другой канал
+	prefs := comarch.NotificationPreferences{Mail: true, Advertising: true, DataProcessing: true}
+	assert.False(t, prefs.CanSendAdvertisingSMS())
+	assert.True(t, prefs.CanSendAdvertisingEmail())
+}
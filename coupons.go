@@ -0,0 +1,174 @@
+package comarch
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Coupon персональный купон участника
+type Coupon struct {
+	// код купона
+	Code string
+	// описание предложения
+	Description string
+	// размер скидки
+	Discount float64
+	// начало и окончание действия в часовом поясе WithLocation. Нулевое время, если комарх не вернул дату
+	ValidFrom time.Time
+	ValidTo   time.Time
+}
+
+type coupon struct {
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Discount    float64 `json:"discount"`
+	ValidFrom   string  `json:"validFrom"`
+	ValidTo     string  `json:"validTo"`
+}
+
+// GetCoupons получает список персональных купонов участника
+func (c *Client) GetCoupons(accessToken AccessToken, opts ...CallOption) ([]Coupon, error) {
+	u := c.endpoint("/resources/coupons")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetCoupons", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var rawCoupons []coupon
+	if err := c.decodeResponse(resp, &rawCoupons); err != nil {
+		return nil, err
+	}
+
+	return c.toCoupons(rawCoupons)
+}
+
+// CouponsPage страница списка купонов
+type CouponsPage struct {
+	Coupons []Coupon
+	PageInfo
+}
+
+// GetCouponsPage получает страницу page (начиная с 1) размером size списка персональных купонов участника.
+// Общее число купонов и номер следующей страницы берутся из тела ответа или из заголовков X-Total-Count и Link,
+// в зависимости от того, как их передает комарх.
+func (c *Client) GetCouponsPage(accessToken AccessToken, page, size int, opts ...CallOption) (*CouponsPage, error) {
+	if err := validatePage(page, size); err != nil {
+		return nil, err
+	}
+
+	u := c.endpoint("/resources/coupons") + "?" + pageQuery(page, size).Encode()
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetCouponsPage", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var rawCoupons []coupon
+	info, err := c.decodePage(resp, page, size, &rawCoupons)
+	if err != nil {
+		return nil, err
+	}
+
+	coupons, err := c.toCoupons(rawCoupons)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CouponsPage{Coupons: coupons, PageInfo: info}, nil
+}
+
+// toCoupons разбирает даты купонов в часовом поясе WithLocation
+func (c *Client) toCoupons(rawCoupons []coupon) ([]Coupon, error) {
+	coupons := make([]Coupon, 0, len(rawCoupons))
+	for _, raw := range rawCoupons {
+		validFrom, err := parseDateTime(raw.ValidFrom, c.location)
+		if err != nil {
+			return nil, err
+		}
+
+		validTo, err := parseDateTime(raw.ValidTo, c.location)
+		if err != nil {
+			return nil, err
+		}
+
+		coupons = append(coupons, Coupon{
+			Code:        raw.Code,
+			Description: raw.Description,
+			Discount:    raw.Discount,
+			ValidFrom:   validFrom,
+			ValidTo:     validTo,
+		})
+	}
+
+	return coupons, nil
+}
+
+// ActivateCoupon привязывает купон к карте участника.
+// Возвращает ErrCouponAlreadyActivated, если купон уже привязан, и ErrCouponExpired, если срок его действия истек.
+func (c *Client) ActivateCoupon(accessToken AccessToken, couponCode string, opts ...CallOption) error {
+	u := c.endpoint("/resources/coupons/activation")
+
+	req, err := c.newJSONRequest("POST", u, map[string]string{"code": couponCode})
+	if err != nil {
+		return err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("ActivateCoupon", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			switch httpErr.StatusCode {
+			case http.StatusConflict:
+				return ErrCouponAlreadyActivated
+			case http.StatusGone:
+				return ErrCouponExpired
+			}
+		}
+
+		return err
+	}
+
+	// платные купоны списывают баллы
+	c.balanceCache.invalidate(c.resolveToken(req.Context(), accessToken).Value)
+
+	return nil
+}
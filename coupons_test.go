@@ -0,0 +1,122 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetCoupons(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/coupons", r.URL.Path)
+		assert.Equal(t, "Bearer "+testAccessToken.Value, r.Header.Get("Authorization"))
+		w.Write([]byte(`[
+			{"code":"C1","description":"Скидка на кофе","discount":10.5,"validFrom":"2020-01-01 00:00","validTo":"2020-02-01 23:59"},
+			{"code":"C2","description":"Бессрочный"}
+		]`))
+	})
+
+	coupons, err := c.GetCoupons(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, []comarch.Coupon{
+		{
+			Code:        "C1",
+			Description: "Скидка на кофе",
+			Discount:    10.5,
+			ValidFrom:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local),
+			ValidTo:     time.Date(2020, 2, 1, 23, 59, 0, 0, time.Local),
+		},
+		{Code: "C2", Description: "Бессрочный"},
+	}, coupons)
+}
+
+func TestClient_ActivateCoupon(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "ok", status: http.StatusOK, err: nil},
+		{name: "already_activated", status: http.StatusConflict, err: comarch.ErrCouponAlreadyActivated},
+		{name: "expired", status: http.StatusGone, err: comarch.ErrCouponExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				w.WriteHeader(tt.status)
+			})
+
+			assert.Equal(t, tt.err, c.ActivateCoupon(testAccessToken, "C1"))
+		})
+	}
+}
+
+func TestClient_GetCouponsPage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		body   string
+		info   comarch.PageInfo
+	}{
+		{
+			name:   "headers",
+			header: map[string]string{"X-Total-Count": "5", "Link": `<https://comarch/cwaapiinterface/resources/coupons?page=3&size=2>; rel="next", <https://comarch/cwaapiinterface/resources/coupons?page=1&size=2>; rel="prev"`},
+			body:   `[{"code":"C3"},{"code":"C4"}]`,
+			info:   comarch.PageInfo{Page: 2, TotalCount: 5, NextPage: 3},
+		},
+		{
+			name:   "headers_total_only",
+			header: map[string]string{"X-Total-Count": "4"},
+			body:   `[{"code":"C3"},{"code":"C4"}]`,
+			info:   comarch.PageInfo{Page: 2, TotalCount: 4},
+		},
+		{
+			name: "body",
+			body: `{"items":[{"code":"C3"},{"code":"C4"}],"totalCount":"5","nextPage":3}`,
+			info: comarch.PageInfo{Page: 2, TotalCount: 5, NextPage: 3},
+		},
+		{
+			name: "body_total_only",
+			body: `{"items":[{"code":"C3"},{"code":"C4"}],"totalCount":5}`,
+			info: comarch.PageInfo{Page: 2, TotalCount: 5, NextPage: 3},
+		},
+		{
+			name: "unknown",
+			body: `[{"code":"C3"},{"code":"C4"}]`,
+			info: comarch.PageInfo{Page: 2, TotalCount: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "2", r.URL.Query().Get("page"))
+				assert.Equal(t, "2", r.URL.Query().Get("size"))
+				for name, value := range tt.header {
+					w.Header().Set(name, value)
+				}
+				w.Write([]byte(tt.body))
+			})
+
+			page, err := c.GetCouponsPage(testAccessToken, 2, 2)
+			assert.Nil(t, err)
+			if assert.NotNil(t, page) {
+				assert.Equal(t, []comarch.Coupon{{Code: "C3"}, {Code: "C4"}}, page.Coupons)
+				assert.Equal(t, tt.info, page.PageInfo)
+				assert.Equal(t, tt.info.NextPage > 0, page.HasNext())
+			}
+		})
+	}
+}
+
+func TestClient_GetCouponsPage_Validation(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	_, err := c.GetCouponsPage(testAccessToken, 0, 10)
+	assert.IsType(t, &comarch.ValidationError{}, err)
+}
@@ -0,0 +1,308 @@
+package comarch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// debugRedacted заменяет секреты в выводе DebugTransport
+const debugRedacted = "[REDACTED]"
+
+// debugSecretHeaders заголовки, значения которых DebugTransport не выводит
+var debugSecretHeaders = map[string]struct{}{
+	"Authorization": {},
+	"Cookie":        {},
+	"Set-Cookie":    {},
+}
+
+// debugSecretFields поля json и параметры запроса, значения которых DebugTransport не выводит
+var debugSecretFields = map[string]struct{}{
+	"password":      {},
+	"oldPass":       {},
+	"newPass":       {},
+	"tempPassword":  {},
+	"access_token":  {},
+	"refresh_token": {},
+	"otp":           {},
+}
+
+// DebugTransport http.RoundTripper, который выводит каждый запрос к комарху и ответ на него в читаемом виде:
+// заголовки и отформатированный json с подсветкой. Пароли, токены, Basic Auth и куки заменяются на [REDACTED].
+//
+// Только для локальной разработки: транспорт читает тела запросов и ответов целиком в память
+// и пишет персональные данные участников. Подключается через WithTransport.
+type DebugTransport struct {
+	// транспорт, который выполняет запросы. nil означает http.DefaultTransport
+	Base http.RoundTripper
+	// куда писать вывод. nil означает os.Stderr
+	Out io.Writer
+	// отключить подсветку ANSI цветами, например при выводе в файл
+	NoColor bool
+
+	mu sync.Mutex
+}
+
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var out bytes.Buffer
+
+	// RoundTripper не должен менять исходный запрос, тело подменяется в копии
+	req = req.Clone(req.Context())
+
+	reqBody, err := debugReadBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&out, "--> %s %s\n", req.Method, debugURL(req.URL))
+	t.writeHeaders(&out, req.Header)
+	t.writeBody(&out, req.Header, reqBody)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(&out, "<-- error: %s\n\n", err)
+		t.flush(out.Bytes())
+
+		return nil, err
+	}
+
+	respBody, err := debugReadBody(&resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	fmt.Fprintf(&out, "<-- %s\n", resp.Status)
+	t.writeHeaders(&out, resp.Header)
+	t.writeBody(&out, resp.Header, respBody)
+	out.WriteString("\n")
+	t.flush(out.Bytes())
+
+	return resp, nil
+}
+
+func (t *DebugTransport) flush(data []byte) {
+	w := t.Out
+	if w == nil {
+		w = os.Stderr
+	}
+
+	// запросы выполняются параллельно, вывод одного запроса не должен перемешиваться с другим
+	t.mu.Lock()
+	w.Write(data)
+	t.mu.Unlock()
+}
+
+func (t *DebugTransport) writeHeaders(out *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if _, ok := debugSecretHeaders[http.CanonicalHeaderKey(name)]; ok {
+			value = debugRedacted
+		}
+
+		fmt.Fprintf(out, "    %s: %s\n", name, value)
+	}
+}
+
+func (t *DebugTransport) writeBody(out *bytes.Buffer, header http.Header, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	// тело WithFormLogin с паролем
+	if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			fmt.Fprintf(out, "    %s\n", debugRedactValues(form).Encode())
+			return
+		}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		fmt.Fprintf(out, "%s\n", body)
+		return
+	}
+
+	pretty, err := json.MarshalIndent(debugRedact(value), "    ", "  ")
+	if err != nil {
+		fmt.Fprintf(out, "%s\n", body)
+		return
+	}
+
+	if !t.NoColor {
+		pretty = debugHighlight(pretty)
+	}
+
+	fmt.Fprintf(out, "    %s\n", pretty)
+}
+
+// debugReadBody читает тело целиком и подменяет его копией, чтобы запрос или ответ можно было прочитать дальше
+func debugReadBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = ioutil.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// debugDumpRequest возвращает копию запроса для вывода в лог: секретные заголовки и параметры адреса скрыты.
+// Тело запроса не копируется
+func debugDumpRequest(req *http.Request) *http.Request {
+	dump := *req
+	dump.Header = transcriptHeader(req.Header)
+	dump.Body = nil
+
+	redactedURL := *req.URL
+	redactedURL.RawQuery = debugQuery(req.URL)
+	dump.URL = &redactedURL
+
+	return &dump
+}
+
+// debugDumpResponse возвращает копию ответа для вывода в лог со скрытыми секретными заголовками.
+// Тело ответа не копируется
+func debugDumpResponse(resp *http.Response) *http.Response {
+	dump := *resp
+	dump.Header = transcriptHeader(resp.Header)
+	dump.Body = nil
+
+	return &dump
+}
+
+// debugURL возвращает адрес запроса со скрытыми секретными параметрами
+func debugURL(u *url.URL) string {
+	redacted := *u
+	redacted.RawQuery = debugQuery(u)
+
+	return redacted.String()
+}
+
+// debugQuery возвращает параметры адреса со скрытыми секретными значениями
+func debugQuery(u *url.URL) string {
+	return debugRedactValues(u.Query()).Encode()
+}
+
+// debugRedactValues заменяет значения секретных параметров запроса или формы
+func debugRedactValues(values url.Values) url.Values {
+	for name := range values {
+		if _, ok := debugSecretFields[name]; ok {
+			values.Set(name, debugRedacted)
+		}
+	}
+
+	return values
+}
+
+// debugRedact заменяет значения секретных полей json
+func debugRedact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := debugSecretFields[key]; ok {
+				v[key] = debugRedacted
+				continue
+			}
+
+			v[key] = debugRedact(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = debugRedact(item)
+		}
+	}
+
+	return value
+}
+
+const (
+	debugColorKey     = "\x1b[36m"
+	debugColorString  = "\x1b[32m"
+	debugColorNumber  = "\x1b[33m"
+	debugColorLiteral = "\x1b[35m"
+	debugColorReset   = "\x1b[0m"
+)
+
+// debugHighlight раскрашивает отформатированный json: ключи, строки, числа и true/false/null
+func debugHighlight(data []byte) []byte {
+	var out bytes.Buffer
+
+	for i := 0; i < len(data); {
+		switch ch := data[i]; {
+		case ch == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			if end > len(data) {
+				end = len(data)
+			}
+
+			color := debugColorString
+			if rest := bytes.TrimLeft(data[end:], " "); len(rest) > 0 && rest[0] == ':' {
+				color = debugColorKey
+			}
+
+			out.WriteString(color)
+			out.Write(data[i:end])
+			out.WriteString(debugColorReset)
+			i = end
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			end := i
+			for end < len(data) && strings.IndexByte("+-.0123456789eE", data[end]) >= 0 {
+				end++
+			}
+
+			out.WriteString(debugColorNumber)
+			out.Write(data[i:end])
+			out.WriteString(debugColorReset)
+			i = end
+		case ch == 't' || ch == 'f' || ch == 'n':
+			end := i
+			for end < len(data) && data[end] >= 'a' && data[end] <= 'z' {
+				end++
+			}
+
+			out.WriteString(debugColorLiteral)
+			out.Write(data[i:end])
+			out.WriteString(debugColorReset)
+			i = end
+		default:
+			out.WriteByte(ch)
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
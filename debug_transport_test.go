@@ -0,0 +1,100 @@
+package comarch_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDebugTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		tokenHandler(w, r)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil,
+		comarch.WithTransport(&comarch.DebugTransport{Out: &out, NoColor: true}),
+	)
+	assert.Nil(t, err)
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	// ответ доступен клиенту после вывода
+	assert.Equal(t, "token", token.Value)
+
+	assert.Nil(t, c.ChangePassword(*token, "old-secret", "new-secret"))
+
+	dump := out.String()
+	assert.Contains(t, dump, "--> POST "+srv.URL+"/cwaapiinterface/login?")
+	assert.Contains(t, dump, "cardNo="+testCredentialsCardNo)
+	assert.Contains(t, dump, "<-- 200 OK")
+	assert.Contains(t, dump, `"token_type": "bearer"`)
+	assert.Contains(t, dump, "Authorization: [REDACTED]")
+	for _, secret := range []string{testCredentialsPassword, "old-secret", "new-secret", `"token"`, "session"} {
+		assert.NotContains(t, dump, secret)
+	}
+}
+
+func TestWithTransport_Conflicts(t *testing.T) {
+	proxyURL, err := url.Parse("http://proxy.invalid:3128")
+	assert.Nil(t, err)
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil,
+		comarch.WithTransport(&comarch.DebugTransport{}),
+		comarch.WithProxy(proxyURL),
+	)
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, http.DefaultClient,
+		comarch.WithTransport(&comarch.DebugTransport{}),
+	)
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestDebugTransport_FormLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, testCredentialsPassword, r.PostForm.Get("password"))
+		tokenHandler(w, r)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil,
+		comarch.WithTransport(&comarch.DebugTransport{Out: &out, NoColor: true}),
+		comarch.WithFormLogin(),
+	)
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+
+	dump := out.String()
+	assert.Contains(t, dump, "cardNo="+testCredentialsCardNo)
+	assert.Contains(t, dump, "password=%5BREDACTED%5D")
+	assert.NotContains(t, dump, "password="+testCredentialsPassword)
+}
+
+func TestDebugTransport_KeepsRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader(`{}`))
+	assert.Nil(t, err)
+	body := req.Body
+
+	resp, err := (&comarch.DebugTransport{Out: ioutil.Discard}).RoundTrip(req)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+	}
+	assert.True(t, body == req.Body)
+}
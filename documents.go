@@ -0,0 +1,88 @@
+package comarch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// maxDocumentSize максимальный размер документа, если не задан WithMaxTransferSize
+const maxDocumentSize = 10 << 20
+
+// UploadCardHolderDocument загружает документ участника (фото, скан удостоверения личности) в анкету.
+// docType тип документа в терминах комарха, например PHOTO или PASSPORT. Тип содержимого определяется
+// по первым байтам файла. Документ больше WithMaxTransferSize (по умолчанию 10МБ) не отправляется,
+// возвращается ErrTransferTooLarge.
+func (c *Client) UploadCardHolderDocument(accessToken AccessToken, docType string, r io.Reader, filename string, opts ...CallOption) error {
+	limit := int64(maxDocumentSize)
+	if c.maxTransferSize > 0 && c.maxTransferSize < limit {
+		limit = c.maxTransferSize
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(data)) > limit {
+		return ErrTransferTooLarge
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("docType", docType); err != nil {
+		return err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(filename)))
+	header.Set("Content-Type", http.DetectContentType(data))
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint("/resources/cardholders/documents"), &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("UploadCardHolderDocument", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return asValidationError(err)
+	}
+
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes экранирует имя файла для заголовка Content-Disposition, как это делает mime/multipart
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
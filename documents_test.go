@@ -0,0 +1,40 @@
+package comarch_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClient_UploadCardHolderDocument(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 32))
+
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/cardholders/documents", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Nil(t, r.ParseMultipartForm(1<<20))
+
+		assert.Equal(t, "PHOTO", r.FormValue("docType"))
+
+		file, header, err := r.FormFile("file")
+		if assert.Nil(t, err) {
+			defer file.Close()
+			assert.Equal(t, "me.png", header.Filename)
+			assert.Equal(t, "image/png", header.Header.Get("Content-Type"))
+
+			data, err := ioutil.ReadAll(file)
+			assert.Nil(t, err)
+			assert.Equal(t, png, data)
+		}
+	}, comarch.WithMaxTransferSize(1024))
+
+	assert.Nil(t, c.UploadCardHolderDocument(testAccessToken, "PHOTO", bytes.NewReader(png), "me.png"))
+
+	err := c.UploadCardHolderDocument(testAccessToken, "PHOTO", bytes.NewReader(make([]byte, 2048)), "big.png")
+	assert.True(t, errors.Is(err, comarch.ErrTransferTooLarge))
+}
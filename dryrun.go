@@ -0,0 +1,62 @@
+package comarch
+
+import (
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// writeOperations операции, которые меняют данные в комархе и не отправляются в режиме WithDryRun
+var writeOperations = map[string]struct{}{
+	"CreateCardHolder":             {},
+	"UpdateCardHolder":             {},
+	"ChangePassword":               {},
+	"ResetPasswordByCardNo":        {},
+	"ResetPasswordByPhoneNo":       {},
+	"ActivateCoupon":               {},
+	"UploadCardHolderDocument":     {},
+	"CancelSMSChallenge":           {},
+	"SetPreferredStore":            {},
+	"SetGoldenCard":                {},
+	"MergeCards":                   {},
+	"SetPrimaryCard":               {},
+	"AnonymizeCardHolder":          {},
+	"ResendActivationConfirmation": {},
+}
+
+// isDryRun проверяет, что запрос операции operation нельзя отправлять из-за WithDryRun
+func (c *Client) isDryRun(operation string) bool {
+	if !c.dryRun {
+		return false
+	}
+
+	_, ok := writeOperations[operation]
+
+	return ok
+}
+
+// dryRunResponse пишет запрос в лог и возвращает вместо ответа комарха пустой успешный ответ
+func (c *Client) dryRunResponse(operation string, req *http.Request) *http.Response {
+	c.log.WithFields(logrus.Fields{
+		"operation":  operation,
+		"request_id": requestID(req),
+		"method":     req.Method,
+		"url":        debugURL(req.URL),
+	}).Info("Comarch dry run: request is not sent")
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+}
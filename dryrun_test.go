@@ -0,0 +1,68 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithDryRun(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"name":"Иван"}`))
+	}))
+	defer srv.Close()
+
+	logger, hook := test.NewNullLogger()
+	c, err := comarch.New(logger, srv.URL, testUsername, testPassword, nil, comarch.WithDryRun(true))
+	assert.Nil(t, err)
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	}
+
+	assert.Nil(t, c.CreateCardHolder(testAccessToken, comarch.PersonalData{Name: "Иван"}))
+	assert.Nil(t, c.UpdateCardHolder(testAccessToken, comarch.PersonalData{Name: "Иван"}))
+	assert.Nil(t, c.ChangePassword(testAccessToken, "old", "new"))
+	assert.Nil(t, c.ResetPasswordByCardNo(testCredentialsCardNo))
+	assert.Nil(t, c.ActivateCoupon(testAccessToken, "SALE"))
+	assert.Empty(t, paths)
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Equal(t, "ActivateCoupon", hook.LastEntry().Data["operation"])
+	}
+
+	// проверка аргументов выполняется и в режиме dry run
+	err = c.ChangePassword(testAccessToken, "old", "new\x00")
+	assert.IsType(t, &comarch.ValidationError{}, err)
+
+	// методы чтения отправляются
+	data, err := c.GetCardHolder(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "Иван", data.Name)
+	assert.Equal(t, []string{"/cwaapiinterface/resources/cardholders"}, paths)
+}
+
+func TestWithDryRun_RedactsURL(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	c, err := comarch.New(logger, testBasePath, testUsername, testPassword, nil,
+		comarch.WithDryRun(true),
+		comarch.WithRequestInterceptor(func(operation string, req *http.Request) error {
+			query := req.URL.Query()
+			query.Set("password", testCredentialsPassword)
+			req.URL.RawQuery = query.Encode()
+			return nil
+		}),
+	)
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.ActivateCoupon(testAccessToken, "SALE"))
+	if assert.NotNil(t, hook.LastEntry()) {
+		u, _ := hook.LastEntry().Data["url"].(string)
+		assert.Contains(t, u, "password=%5BREDACTED%5D")
+		assert.NotContains(t, u, "password="+testCredentialsPassword)
+	}
+}
@@ -1,6 +1,15 @@
 package comarch
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
 
 var (
 	// ErrInvalidConfiguration некорректная конфигурация
@@ -8,4 +17,322 @@ var (
 
 	// ErrBadResponse некорректный ответ от сервера
 	ErrBadResponse = errors.New("Invalid server response")
+
+	// ErrNotFound запрошенный объект не найден в комархе
+	ErrNotFound = errors.New("Not found")
+
+	// ErrForbidden учетным данным не хватает прав на операцию (статус 403)
+	ErrForbidden = errors.New("Forbidden")
+
+	// ErrConflict комарх отклонил запрос, потому что он противоречит текущему состоянию объекта (статус 409)
+	ErrConflict = errors.New("Conflict")
+
+	// ErrTimeout истек таймаут запроса на стороне клиента. Таймаут шлюза комарха (504) возвращается как *HTTPError
+	ErrTimeout = errors.New("Request timeout")
+
+	// ErrTransferTooLarge тело запроса или ответа больше WithMaxTransferSize
+	ErrTransferTooLarge = errors.New("Transfer size limit exceeded")
+
+	// ErrCredentialsRequired метод требует логин и пароль приложения, а клиент создан только с токеном
+	ErrCredentialsRequired = errors.New("Client credentials are required")
+
+	// ErrReauthenticationUnavailable нет данных для повторного входа по токену
+	ErrReauthenticationUnavailable = errors.New("Reauthentication is not available for this token")
+
+	// ErrUnsupportedCharset ответ сервера в неизвестной кодировке
+	ErrUnsupportedCharset = errors.New("Unsupported response charset")
+
+	// ErrCouponAlreadyActivated купон уже привязан к карте
+	ErrCouponAlreadyActivated = errors.New("Coupon is already activated")
+
+	// ErrCouponExpired срок действия купона истек
+	ErrCouponExpired = errors.New("Coupon is expired")
+
+	// ErrCardHolderExists у карты уже есть анкета владельца
+	ErrCardHolderExists = errors.New("Card holder already exists")
+
+	// ErrNoPreviousVisit комарх не знает о предыдущих посещениях участника
+	ErrNoPreviousVisit = errors.New("No previous visit")
+
+	// ErrUnknownCardStatus комарх вернул неизвестное состояние карты
+	ErrUnknownCardStatus = errors.New("Unknown card status")
+
+	// ErrUnknownField в ответе комарха есть поле, которого нет в модели клиента. Только при WithStrictDecoding
+	ErrUnknownField = errors.New("Unknown response field")
+
+	// ErrUnknownGrantType grant_type не стандартный и не зарегистрирован в клиенте
+	ErrUnknownGrantType = errors.New("Unknown grant type")
+
+	// ErrTokenIssuerMismatch токен выдан другим комархом (другим basePath). Только при WithTokenIssuerCheck(true)
+	ErrTokenIssuerMismatch = errors.New("Access token was issued by another Comarch")
+
+	// ErrServiceUnavailable комарх закрыт на плановые работы. Подробности в *MaintenanceError
+	ErrServiceUnavailable = errors.New("Comarch is under maintenance")
+
+	// ErrCircuitOpen запрос не отправлен, так как circuit breaker разомкнут после серии ошибок, см. WithCircuitBreaker
+	ErrCircuitOpen = errors.New("Circuit breaker is open")
+
+	// ErrMergeDifferentOwners объединяемые карты принадлежат разным участникам
+	ErrMergeDifferentOwners = errors.New("Cards belong to different owners")
+
+	// ErrCardAlreadyMerged одна из объединяемых карт уже объединена с другой картой
+	ErrCardAlreadyMerged = errors.New("Card is already merged")
+
+	// ErrInvalidToken токен поврежден: не хватает значения, срока жизни или кук, см. AccessToken.Validate
+	ErrInvalidToken = errors.New("Invalid access token")
+
+	// ErrCardHolderAnonymized участник уже обезличен, см. AnonymizeCardHolder
+	ErrCardHolderAnonymized = errors.New("Card holder is already anonymized")
+
+	// ErrCardNotLinked карта не привязана к участнику
+	ErrCardNotLinked = errors.New("Card is not linked to the member")
+
+	// ErrCardAlreadyActivated карта уже активирована, см. ResendActivationConfirmation
+	ErrCardAlreadyActivated = errors.New("Card is already activated")
+
+	// ErrUnauthorized комарх не принял токен участника (401), либо AuthenticatedClient не смог получить
+	// действующий токен
+	ErrUnauthorized = errors.New("Unauthorized")
+
+	// ErrInvalidOTP одноразовый код неверный или просрочен
+	ErrInvalidOTP = errors.New("Invalid or expired one-time password")
+
+	// ErrMemberNotRegistered участника с таким номером нет, его нужно сначала зарегистрировать. Возвращается
+	// входом по SMS (authbysms)
+	ErrMemberNotRegistered = errors.New("Member is not registered")
+
+	// ErrInvalidPhone номер телефона не удалось привести к формату комарха, см. NormalizePhone
+	ErrInvalidPhone = errors.New("Invalid phone number")
 )
+
+// ConfigError некорректная настройка клиента в New. Соответствует ErrInvalidConfiguration через errors.Is
+type ConfigError struct {
+	// аргумент New или опция, например basePath или WithRetry
+	Field string
+	// что не так со значением
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidConfiguration, e.Field, e.Reason)
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfiguration
+}
+
+// APIError ошибка в формате комарха, которую сервер возвращает в теле неуспешного ответа
+type APIError struct {
+	// код ошибки
+	Code string `json:"errorCode"`
+	// описание ошибки
+	Message string `json:"errorMessage"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("comarch error %s: %s", e.Code, e.Message)
+}
+
+// HTTPError неуспешный http статус ответа комарха. Соответствует ErrBadResponse через errors.Is,
+// при статусе 401 также ErrUnauthorized, при статусе 403 также ErrForbidden, при статусе 404 также ErrNotFound, при статусе 409 также ErrConflict
+type HTTPError struct {
+	StatusCode int
+	// начало тела ответа, не более 4КБ
+	Body string
+	// ошибка комарха, если тело ответа удалось разобрать
+	APIError *APIError
+	// внутренний идентификатор запроса из логов клиента (request_id)
+	RequestID string
+}
+
+func (e *HTTPError) Error() string {
+	if e.APIError != nil {
+		return fmt.Sprintf("%s: status %d: %s", ErrBadResponse, e.StatusCode, e.APIError)
+	}
+
+	return fmt.Sprintf("%s: status %d", ErrBadResponse, e.StatusCode)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrBadResponse:
+		return true
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+
+	return false
+}
+
+func (e *HTTPError) Unwrap() error {
+	if e.APIError != nil {
+		return e.APIError
+	}
+
+	return nil
+}
+
+// unauthorizedError неудачное обновление токена. Соответствует ErrUnauthorized, причина доступна через errors.Is/As
+type unauthorizedError struct {
+	err error
+}
+
+func (e *unauthorizedError) Error() string {
+	return ErrUnauthorized.Error() + ": " + e.err.Error()
+}
+
+func (e *unauthorizedError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+func (e *unauthorizedError) Unwrap() error {
+	return e.err
+}
+
+// maintenanceErrorCode код ошибки комарха в ответе 503 во время плановых работ
+const maintenanceErrorCode = "MAINTENANCE"
+
+// MaintenanceError ответ 503 во время плановых работ комарха. Соответствует ErrServiceUnavailable через errors.Is,
+// исходный *HTTPError доступен через errors.As. Обычные 503 без признаков плановых работ возвращаются как *HTTPError.
+type MaintenanceError struct {
+	// ожидаемое время окончания работ из заголовка Retry-After. Нулевое, если комарх его не сообщил
+	RetryAt time.Time
+
+	httpErr *HTTPError
+}
+
+func (e *MaintenanceError) Error() string {
+	if e.RetryAt.IsZero() {
+		return ErrServiceUnavailable.Error()
+	}
+
+	return fmt.Sprintf("%s until %s", ErrServiceUnavailable, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *MaintenanceError) Is(target error) bool {
+	return target == ErrServiceUnavailable
+}
+
+func (e *MaintenanceError) Unwrap() error {
+	return e.httpErr
+}
+
+// asMaintenanceError распознает ответ 503 о плановых работах: заголовок X-Maintenance или код ошибки
+// MAINTENANCE в теле. Для остальных ответов возвращает httpErr без изменений
+func asMaintenanceError(resp *http.Response, httpErr *HTTPError) error {
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		return httpErr
+	}
+
+	flag := strings.ToLower(strings.TrimSpace(resp.Header.Get("X-Maintenance")))
+	byHeader := flag != "" && flag != "false" && flag != "0"
+	byBody := httpErr.APIError != nil && strings.EqualFold(httpErr.APIError.Code, maintenanceErrorCode)
+	if !byHeader && !byBody {
+		return httpErr
+	}
+
+	maintenanceErr := &MaintenanceError{httpErr: httpErr}
+	if d, ok := RetryAfter(resp); ok {
+		maintenanceErr.RetryAt = time.Now().Add(d)
+	}
+
+	return maintenanceErr
+}
+
+// ValidationError некорректные значения полей запроса. Ошибка может быть обнаружена клиентом до отправки
+// запроса или вернуться от комарха, тогда исходный *HTTPError доступен через errors.As
+type ValidationError struct {
+	// имя поля -> описание ошибки
+	Fields map[string]string
+
+	err error
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field, message := range e.Fields {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+
+	return "Validation failed: " + strings.Join(fields, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// fieldErrorsResponse тело ответа комарха при ошибках валидации полей
+type fieldErrorsResponse struct {
+	FieldErrors []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"fieldErrors"`
+}
+
+// asValidationError превращает ответ комарха с ошибками полей в *ValidationError.
+// Остальные ошибки возвращаются без изменений.
+func asValidationError(err error) error {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+
+	if httpErr.StatusCode != http.StatusBadRequest && httpErr.StatusCode != http.StatusUnprocessableEntity {
+		return err
+	}
+
+	var body fieldErrorsResponse
+	if json.Unmarshal([]byte(httpErr.Body), &body) != nil || len(body.FieldErrors) == 0 {
+		return err
+	}
+
+	fields := make(map[string]string, len(body.FieldErrors))
+	for _, fieldErr := range body.FieldErrors {
+		if fieldErr.Field == "" {
+			continue
+		}
+
+		fields[fieldErr.Field] = fieldErr.Message
+	}
+
+	if len(fields) == 0 {
+		return err
+	}
+
+	return &ValidationError{Fields: fields, err: httpErr}
+}
+
+// timeoutError ошибка транспорта из-за таймаута. Соответствует ErrTimeout через errors.Is,
+// исходная ошибка доступна через errors.Unwrap
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return ErrTimeout.Error() + ": " + e.err.Error()
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+// classifyTransportError оборачивает таймауты транспорта в timeoutError
+func classifyTransportError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &timeoutError{err: err}
+	}
+
+	return err
+}
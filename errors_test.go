@@ -0,0 +1,244 @@
+package comarch_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAPIError = `{"errorCode":"E500","errorMessage":"Внутренняя ошибка"}`
+
+func TestClient_HTTPError(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(testAPIError))
+	})
+
+	_, err := c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+
+	var httpErr *comarch.HTTPError
+	if assert.True(t, errors.As(err, &httpErr)) {
+		assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+		assert.Equal(t, testAPIError, httpErr.Body)
+	}
+
+	var apiErr *comarch.APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, "E500", apiErr.Code)
+		assert.Equal(t, "Внутренняя ошибка", apiErr.Message)
+	}
+}
+
+func TestClient_HTTPError_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(testAPIError))
+	gz.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	// без DisableCompression транспорт сам распакует ответ и уберет Content-Encoding
+	httpClient := &http.Client{Timeout: time.Second, Transport: &http.Transport{DisableCompression: true}}
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, httpClient)
+	assert.Nil(t, err)
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+
+	var httpErr *comarch.HTTPError
+	if assert.True(t, errors.As(err, &httpErr)) {
+		assert.Equal(t, testAPIError, httpErr.Body)
+		if assert.NotNil(t, httpErr.APIError) {
+			assert.Equal(t, "E500", httpErr.APIError.Code)
+		}
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 100)
+	}))
+	defer srv.Close()
+
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, &http.Client{Timeout: time.Millisecond * 10})
+	assert.Nil(t, err)
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrTimeout))
+	assert.False(t, errors.Is(err, comarch.ErrBadResponse))
+}
+
+func TestClient_GatewayTimeout(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+
+	_, err := c.GetBalanceInfo(testAccessToken)
+	assert.False(t, errors.Is(err, comarch.ErrTimeout))
+
+	var httpErr *comarch.HTTPError
+	if assert.True(t, errors.As(err, &httpErr)) {
+		assert.Equal(t, http.StatusGatewayTimeout, httpErr.StatusCode)
+	}
+}
+
+func TestClient_CreateCardHolder_ValidationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		fields map[string]string
+	}{
+		{
+			name:   "field_errors",
+			status: http.StatusBadRequest,
+			body:   `{"errorCode":"VALIDATION","fieldErrors":[{"field":"email","message":"invalid format"},{"field":"birthday","message":"required"}]}`,
+			fields: map[string]string{"email": "invalid format", "birthday": "required"},
+		},
+		{name: "other_shape", status: http.StatusBadRequest, body: testAPIError},
+		{name: "not_json", status: http.StatusUnprocessableEntity, body: "bad request"},
+		{name: "server_error", status: http.StatusInternalServerError, body: `{"fieldErrors":[{"field":"email","message":"x"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			err := c.CreateCardHolder(testAccessToken, comarch.PersonalData{})
+			assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+
+			var validationErr *comarch.ValidationError
+			if tt.fields == nil {
+				assert.False(t, errors.As(err, &validationErr))
+				return
+			}
+
+			if assert.True(t, errors.As(err, &validationErr)) {
+				assert.Equal(t, tt.fields, validationErr.Fields)
+			}
+		})
+	}
+}
+
+func TestClient_CreateCardHolder_Exists(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/login" {
+			tokenHandler(w, r)
+			return
+		}
+
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	err := c.CreateCardHolder(testAccessToken, comarch.PersonalData{})
+	assert.Equal(t, comarch.ErrCardHolderExists, err)
+
+	err = c.RegisterNewCardHolder(testCredentialsCardNo, comarch.PersonalData{})
+	assert.True(t, errors.Is(err, comarch.ErrCardHolderExists))
+
+	err = c.UpdateCardHolder(testAccessToken, comarch.PersonalData{})
+	assert.True(t, errors.Is(err, comarch.ErrConflict))
+	assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+}
+
+func TestNew_ConfigError(t *testing.T) {
+	tests := []struct {
+		name     string
+		basePath string
+		login    string
+		opts     []comarch.Option
+		field    string
+	}{
+		{name: "trailing_slash", basePath: testBasePath + "/", login: testUsername, field: "basePath"},
+		{name: "no_scheme", basePath: "comarch.local", login: testUsername, field: "basePath"},
+		{name: "no_credentials", basePath: testBasePath, login: "", field: "login"},
+		{name: "retry", basePath: testBasePath, login: testUsername, opts: []comarch.Option{comarch.WithRetry(0)}, field: "WithRetry"},
+		{name: "backup", basePath: testBasePath, login: testUsername, opts: []comarch.Option{comarch.WithBackupBaseURLs([]string{"dr.local"})}, field: "WithBackupBaseURLs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := comarch.New(log, tt.basePath, tt.login, "", nil, tt.opts...)
+			assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+
+			var configErr *comarch.ConfigError
+			if assert.True(t, errors.As(err, &configErr)) {
+				assert.Equal(t, tt.field, configErr.Field)
+				assert.NotEmpty(t, configErr.Reason)
+				assert.Contains(t, err.Error(), tt.field)
+			}
+		})
+	}
+}
+
+func TestClient_MaintenanceError(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      map[string]string
+		body        string
+		maintenance bool
+		retryAt     bool
+	}{
+		{name: "header", header: map[string]string{"X-Maintenance": "true", "Retry-After": "1800"}, maintenance: true, retryAt: true},
+		{name: "body", body: `{"errorCode":"MAINTENANCE","errorMessage":"Технические работы"}`, maintenance: true},
+		{name: "transient", header: map[string]string{"Retry-After": "1"}, body: testAPIError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				for name, value := range tt.header {
+					w.Header().Set(name, value)
+				}
+				// без keep-alive транспорт не повторяет запрос сам и не добавляет лишних вызовов
+				w.Header().Set("Connection", "close")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(tt.body))
+			}, comarch.WithRetry(2), comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration { return 0 }))
+
+			started := time.Now()
+			_, err := c.GetBalanceInfo(testAccessToken)
+			assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+			assert.Equal(t, tt.maintenance, errors.Is(err, comarch.ErrServiceUnavailable))
+
+			var httpErr *comarch.HTTPError
+			if assert.True(t, errors.As(err, &httpErr)) {
+				assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+			}
+
+			var maintenanceErr *comarch.MaintenanceError
+			if !tt.maintenance {
+				assert.False(t, errors.As(err, &maintenanceErr))
+				assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+				return
+			}
+
+			// во время плановых работ повторы не выполняются
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+			if assert.True(t, errors.As(err, &maintenanceErr)) {
+				if tt.retryAt {
+					assert.WithinDuration(t, started.Add(time.Minute*30), maintenanceErr.RetryAt, time.Second*5)
+				} else {
+					assert.True(t, maintenanceErr.RetryAt.IsZero())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package comarch
+
+import (
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// EventType тип события жизненного цикла токена
+type EventType string
+
+const (
+	// успешный логин
+	EventLogin EventType = "login"
+	// неудачный логин
+	EventLoginFailed EventType = "login_failed"
+	// токен получен заново через Reauthenticate
+	EventRefresh EventType = "refresh"
+	// Reauthenticate не удался
+	EventRefreshFailed EventType = "refresh_failed"
+	// токен разлогинен
+	EventLogout EventType = "logout"
+	// обнаружен истекший токен: комарх отклонил его в ValidateToken или истек ExpiresAt
+	// в ReauthenticatingTokenProvider
+	EventTokenExpired EventType = "token_expired"
+)
+
+// Event событие жизненного цикла токена для WithEventChannel. Не содержит паролей и токенов
+type Event struct {
+	Type EventType
+	// имя метода клиента, например SignInByCard
+	Operation string
+	// замаскированный номер карты или телефона участника, например ************4444.
+	// Пустой, если участника не удалось определить
+	Subject string
+	// время события
+	Time time.Time
+	// ошибка для *_failed событий
+	Err error
+}
+
+// emit отправляет событие в канал WithEventChannel. Если канал заполнен, событие отбрасывается
+func (c *Client) emit(eventType EventType, operation, subject string, err error) {
+	if c.events == nil {
+		return
+	}
+
+	event := Event{
+		Type:      eventType,
+		Operation: operation,
+		Subject:   maskSubject(subject),
+		Time:      time.Now(),
+		Err:       err,
+	}
+
+	select {
+	case c.events <- event:
+	default:
+		c.log.WithFields(logrus.Fields{
+			"operation": operation,
+			"event":     eventType,
+		}).Debug("Comarch event dropped, channel is full")
+	}
+}
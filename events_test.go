@@ -0,0 +1,75 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithEventChannel(t *testing.T) {
+	events := make(chan comarch.Event, 10)
+	failLogin := false
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			if failLogin {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokenHandler(w, r)
+		case "/cwaapiinterface/resources/cards/status":
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}, comarch.WithEventChannel(events))
+
+	started := time.Now()
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	_, err = c.Reauthenticate(*token)
+	assert.True(t, errors.Is(err, comarch.ErrReauthenticationUnavailable))
+	valid, err := c.ValidateToken(*token)
+	assert.Nil(t, err)
+	assert.False(t, valid)
+	assert.Nil(t, c.SignOut(*token))
+
+	failLogin = true
+	_, err = c.SignInByPhone("79990001122", "secret")
+	assert.NotNil(t, err)
+
+	close(events)
+
+	var received []comarch.Event
+	for event := range events {
+		assert.False(t, event.Time.Before(started))
+		event.Time = time.Time{}
+		received = append(received, event)
+	}
+
+	if assert.Len(t, received, 5) {
+		assert.Equal(t, comarch.Event{Type: comarch.EventLogin, Operation: "SignInByCard", Subject: "************4444"}, received[0])
+		assert.Equal(t, comarch.EventRefreshFailed, received[1].Type)
+		assert.Equal(t, "************4444", received[1].Subject)
+		assert.True(t, errors.Is(received[1].Err, comarch.ErrReauthenticationUnavailable))
+		assert.Equal(t, comarch.Event{Type: comarch.EventTokenExpired, Operation: "ValidateToken", Subject: "************4444"}, received[2])
+		assert.Equal(t, comarch.Event{Type: comarch.EventLogout, Operation: "SignOut", Subject: "************4444"}, received[3])
+		assert.Equal(t, comarch.EventLoginFailed, received[4].Type)
+		assert.Equal(t, "*******1122", received[4].Subject)
+		assert.NotNil(t, received[4].Err)
+	}
+}
+
+func TestWithEventChannel_DropsWhenFull(t *testing.T) {
+	events := make(chan comarch.Event, 1)
+	c := newTestServer(t, tokenHandler, comarch.WithEventChannel(events))
+
+	for i := 0; i < 3; i++ {
+		_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+	}
+
+	assert.Len(t, events, 1)
+}
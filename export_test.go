@@ -0,0 +1,13 @@
+package comarch
+
+import "time"
+
+// SetLimiterClock подменяет часы лимитера WithRateLimit, чтобы тесты не зависели от времени выполнения
+func SetLimiterClock(c *Client, now func() time.Time) {
+	c.limiter.now = now
+}
+
+// SetBreakerClock подменяет часы circuit breaker из WithCircuitBreaker, в том числе breaker-ов операций
+func SetBreakerClock(c *Client, now func() time.Time) {
+	c.breaker.now = now
+}
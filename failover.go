@@ -0,0 +1,86 @@
+package comarch
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sendWithFailover отправляет запрос на основной адрес комарха, а при ошибке транспорта или ответе 5xx
+// повторяет его на резервных адресах из WithBackupBaseURLs по порядку
+func (c *Client) sendWithFailover(operation string, req *http.Request) (*http.Response, error) {
+	resp, err := c.send(operation, req)
+
+	for _, backup := range c.backupBasePaths {
+		if !c.needsFailover(req, resp, err) {
+			break
+		}
+
+		backupReq, ok := c.failoverRequest(req, backup)
+		if !ok {
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		c.log.WithFields(logrus.Fields{
+			"operation":  operation,
+			"request_id": requestID(req),
+			"backup":     backup,
+			"error":      err,
+		}).Warn("Comarch failover to backup")
+		c.metrics.ObserveFailover(operation, backup)
+
+		resp, err = c.send(operation, backupReq)
+	}
+
+	return resp, err
+}
+
+// needsFailover проверяет, что запрос не удался по вине адреса, а не из-за отмены или ограничений клиента
+func (c *Client) needsFailover(req *http.Request, resp *http.Response, err error) bool {
+	if err == nil {
+		return resp.StatusCode >= http.StatusInternalServerError
+	}
+
+	if req.Context().Err() != nil || errors.Is(err, ErrTransferTooLarge) {
+		return false
+	}
+
+	return true
+}
+
+// failoverRequest копирует запрос с заменой основного адреса на backup. Заголовки, в том числе авторизация
+// и куки, сохраняются, тело переоткрывается через GetBody. Заголовок Host из WithHostHeader сохраняется,
+// иначе берется из адреса backup. Запрос с телом, которое нельзя переоткрыть, не копируется.
+func (c *Client) failoverRequest(req *http.Request, backup string) (*http.Request, bool) {
+	u, err := url.Parse(backup + strings.TrimPrefix(req.URL.String(), c.basePath))
+	if err != nil {
+		return nil, false
+	}
+
+	backupReq := req.Clone(req.Context())
+	backupReq.URL = u
+	if c.hostHeader == "" {
+		backupReq.Host = ""
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, false
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false
+		}
+
+		backupReq.Body = body
+	}
+
+	return backupReq, true
+}
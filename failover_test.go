@@ -0,0 +1,64 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type failoverMetrics struct {
+	comarch.NopMetrics
+
+	backups []string
+}
+
+func (m *failoverMetrics) ObserveFailover(operation string, backup string) {
+	m.backups = append(m.backups, backup)
+}
+
+func TestWithBackupBaseURLs(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+
+	// адрес, который обрывает соединение, не ответив
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if assert.Nil(t, err) {
+			conn.Close()
+		}
+	}))
+	defer down.Close()
+
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/cards/password", r.URL.Path)
+		assert.Equal(t, "comarch.example.com", r.Host)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		_, err := r.Cookie("JSESSIONID")
+		assert.Nil(t, err)
+
+		var body map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "new", body["newPass"])
+	}))
+	defer backup.Close()
+
+	metrics := &failoverMetrics{}
+	c, err := comarch.New(log, primary.URL, testUsername, testPassword, nil,
+		comarch.WithBackupBaseURLs([]string{down.URL, backup.URL}),
+		comarch.WithHostHeader("comarch.example.com"),
+		comarch.WithMetrics(metrics),
+	)
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.ChangePassword(testAccessToken, "old", "new"))
+	assert.Equal(t, []string{down.URL, backup.URL}, metrics.backups)
+
+	_, err = comarch.New(log, primary.URL, testUsername, testPassword, nil, comarch.WithBackupBaseURLs([]string{backup.URL + "/"}))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
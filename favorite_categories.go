@@ -0,0 +1,120 @@
+package comarch
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// favoriteCategoriesDictionary словарь комарха с сегментами любимых продуктов
+const favoriteCategoriesDictionary = "PRD_SGM_FAVORITE"
+
+// FavoriteCategory сегмент любимых продуктов из словаря PRD_SGM_FAVORITE
+type FavoriteCategory struct {
+	// код сегмента
+	Code FavCategory
+	// название сегмента для показа участнику
+	Label string
+}
+
+// DefaultFavoriteCategories возвращает встроенный в библиотеку словарь сегментов, соответствующий
+// константам FavCategory*. Словарь комарха со временем пополняется, актуальный получает GetFavoriteCategories
+func DefaultFavoriteCategories() []FavoriteCategory {
+	return []FavoriteCategory{
+		{Code: FavCategory1, Label: "Бакалея и горячие напитки (чай, кофе)"},
+		{Code: FavCategory2, Label: "Вина, ликеры и крепкий алкоголь"},
+		{Code: FavCategory3, Label: "Все для дома, дачи, спорта"},
+		{Code: FavCategory4, Label: "Готовая кулинария и замороженные полуфабрикаты"},
+		{Code: FavCategory5, Label: "Диабетическое и Здоровое питание"},
+		{Code: FavCategory6, Label: "Молочная гастрономия"},
+		{Code: FavCategory7, Label: "Мясо и птица"},
+		{Code: FavCategory8, Label: "Овощи и фрукты"},
+		{Code: FavCategory9, Label: "Рыба и морепродукты"},
+		{Code: FavCategory10, Label: "Соки, воды и слабоалкогольные напитки"},
+		{Code: FavCategory11, Label: "Сыры, колбасы, мясная гастрономия"},
+		{Code: FavCategory12, Label: "Товары и продукты для детей"},
+		{Code: FavCategory13, Label: "Хлеб и кондитерские изделия"},
+	}
+}
+
+// ValidateFavoriteCategory проверяет, что code есть в словаре categories, например полученном из
+// GetFavoriteCategories. Для неизвестного сегмента возвращает *ValidationError
+func ValidateFavoriteCategory(categories []FavoriteCategory, code FavCategory) error {
+	for _, category := range categories {
+		if category.Code == code {
+			return nil
+		}
+	}
+
+	return &ValidationError{Fields: map[string]string{"favPrdSegment": "unknown category " + string(code)}}
+}
+
+type dictionaryEntry struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// GetFavoriteCategories получает актуальный словарь сегментов любимых продуктов с названиями от комарха.
+// Если комарх недоступен (ошибка транспорта, таймаут, ответ 5xx или разомкнутый WithCircuitBreaker),
+// возвращается встроенный словарь DefaultFavoriteCategories без ошибки, а в лог пишется предупреждение.
+// Остальные ошибки, например недействительный токен, возвращаются как есть.
+func (c *Client) GetFavoriteCategories(accessToken AccessToken, opts ...CallOption) ([]FavoriteCategory, error) {
+	categories, err := c.getFavoriteCategories(accessToken, opts...)
+	if err == nil || !isUnavailable(err) {
+		return categories, err
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"operation":  "GetFavoriteCategories",
+		"request_id": RequestID(err),
+		"error":      err,
+	}).Warn("Comarch is unavailable, using built-in favorite categories")
+
+	return DefaultFavoriteCategories(), nil
+}
+
+func (c *Client) getFavoriteCategories(accessToken AccessToken, opts ...CallOption) ([]FavoriteCategory, error) {
+	u := c.endpoint("/resources/dictionaries/" + favoriteCategoriesDictionary)
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetFavoriteCategories", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var entries []dictionaryEntry
+	if err := c.decodeResponse(resp, &entries); err != nil {
+		return nil, err
+	}
+
+	categories := make([]FavoriteCategory, 0, len(entries))
+	for _, entry := range entries {
+		categories = append(categories, FavoriteCategory{Code: FavCategory(entry.Code), Label: entry.Label})
+	}
+
+	return categories, nil
+}
+
+// isUnavailable проверяет, что ошибка означает недоступность комарха, а не ошибку в запросе
+func isUnavailable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var reqErr *requestError
+	return errors.As(err, &reqErr) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrTimeout)
+}
@@ -0,0 +1,64 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_GetFavoriteCategories(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/dictionaries/PRD_SGM_FAVORITE", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Write([]byte(`[{"code":"73:X_FD_Ов.фр","label":"Овощи и фрукты"},{"code":"73:X_FD_Pets","label":"Зоотовары"}]`))
+	})
+
+	categories, err := c.GetFavoriteCategories(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, []comarch.FavoriteCategory{
+		{Code: comarch.FavCategory8, Label: "Овощи и фрукты"},
+		{Code: "73:X_FD_Pets", Label: "Зоотовары"},
+	}, categories)
+
+	assert.Nil(t, comarch.ValidateFavoriteCategory(categories, "73:X_FD_Pets"))
+	err = comarch.ValidateFavoriteCategory(categories, comarch.FavCategory1)
+	assert.IsType(t, &comarch.ValidationError{}, err)
+}
+
+func TestClient_GetFavoriteCategories_Fallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		fallback bool
+	}{
+		{name: "unavailable", status: http.StatusServiceUnavailable, fallback: true},
+		{name: "unauthorized", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			categories, err := c.GetFavoriteCategories(testAccessToken)
+			if tt.fallback {
+				assert.Nil(t, err)
+				assert.Equal(t, comarch.DefaultFavoriteCategories(), categories)
+				assert.Len(t, categories, 13)
+				return
+			}
+
+			assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+			assert.Nil(t, categories)
+		})
+	}
+
+	offline, err := comarch.New(log, "http://127.0.0.1:1", testUsername, testPassword, nil)
+	assert.Nil(t, err)
+	categories, err := offline.GetFavoriteCategories(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, comarch.DefaultFavoriteCategories(), categories)
+}
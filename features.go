@@ -0,0 +1,43 @@
+package comarch
+
+import "encoding/json"
+
+// HasFeature проверяет, что комарх включил участнику функцию name. false, если флаг выключен или не пришел
+func (t AccessToken) HasFeature(name string) bool {
+	return t.Features[name]
+}
+
+// featureFlags флаги функций участника из ответа на логин: объект с признаками ({"beta": true, "promo": "Y"})
+// или массив имен включенных функций. Значения других типов игнорируются, чтобы незнакомый формат флагов
+// не ломал логин
+type featureFlags map[string]bool
+
+func (f *featureFlags) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil && names != nil {
+		flags := featureFlags{}
+		for _, name := range names {
+			flags[name] = true
+		}
+		*f = flags
+
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+		return nil
+	}
+
+	flags := featureFlags{}
+	for name, value := range raw {
+		var enabled flexBool
+		if err := enabled.UnmarshalJSON(value); err != nil {
+			continue
+		}
+		flags[name] = bool(enabled)
+	}
+	*f = flags
+
+	return nil
+}
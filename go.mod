@@ -1,5 +1,17 @@
 module github.com/kazhuravlev/go-comarch
 
-go 1.13
+go 1.18
 
-require github.com/sirupsen/logrus v1.4.2
+require (
+	github.com/sirupsen/logrus v1.4.2
+	github.com/stretchr/testify v1.2.2
+	golang.org/x/net v0.25.0
+	golang.org/x/text v0.16.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+)
@@ -0,0 +1,54 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestWithRequestInterceptor(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "trace-id", r.Header.Get("X-Trace-Id"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, bodies[len(bodies)-1], string(body))
+	}, comarch.WithRequestInterceptor(func(operation string, req *http.Request) error {
+		assert.Equal(t, "ChangePassword", operation)
+		requests = append(requests, req)
+
+		body, err := req.GetBody()
+		assert.Nil(t, err)
+		data, err := ioutil.ReadAll(body)
+		assert.Nil(t, err)
+		bodies = append(bodies, string(data))
+
+		req.Header.Set("X-Trace-Id", "trace-id")
+
+		return nil
+	}))
+
+	assert.Nil(t, c.ChangePassword(testAccessToken, "old", "new"))
+
+	if assert.Len(t, requests, 1) {
+		req := requests[0]
+		assert.Equal(t, "PUT", req.Method)
+		assert.Equal(t, "/cwaapiinterface/resources/cards/password", req.URL.Path)
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, `{"newPass":"new","oldPass":"old"}`, bodies[0])
+	}
+
+	errDenied := errors.New("denied")
+	c = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request must not be sent")
+	}, comarch.WithRequestInterceptor(func(string, *http.Request) error {
+		return errDenied
+	}))
+	assert.Equal(t, errDenied, c.ChangePassword(testAccessToken, "old", "new"))
+}
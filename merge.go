@@ -0,0 +1,129 @@
+package comarch
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// коды ошибок комарха при объединении карт
+const (
+	mergeDifferentOwnersCode = "DIFFERENT_OWNERS"
+	mergeAlreadyMergedCode   = "ALREADY_MERGED"
+)
+
+// MergeResult итог объединения карт
+type MergeResult struct {
+	// карта, на которую перенесены баллы
+	CardNo string
+	// баланс карты после объединения
+	Balance int
+	// состояние карты после объединения
+	Status CardStatus
+}
+
+type mergeResult struct {
+	CardNo  string  `json:"cardNo"`
+	Balance flexInt `json:"balance"`
+	Status  string  `json:"status"`
+}
+
+// MergeCards объединяет дублирующий аккаунт участника: баллы карты secondaryCardNo переносятся на карту
+// primaryCardNo, дубль закрывается. Операция служебная и выполняется с учетными данными клиента.
+// Если карты принадлежат разным участникам, возвращается ErrMergeDifferentOwners, если одна из карт
+// уже объединена с другой - ErrCardAlreadyMerged. Для неизвестной карты возвращается ошибка, соответствующая
+// ErrNotFound, если учетным данным не хватает прав - ErrForbidden. В режиме WithDryRun запрос не отправляется
+// и возвращается результат с пустыми балансом и состоянием.
+func (c *Client) MergeCards(primaryCardNo, secondaryCardNo string, opts ...CallOption) (*MergeResult, error) {
+	if err := validateMergeCards(primaryCardNo, secondaryCardNo); err != nil {
+		return nil, err
+	}
+
+	u := c.endpoint("/common/cards/merge")
+
+	req, err := c.newJSONRequest("POST", u, map[string]string{
+		"primaryCardNo":   primaryCardNo,
+		"secondaryCardNo": secondaryCardNo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.basicAuth("MergeCards", req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("MergeCards", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, asMergeError(err)
+	}
+
+	c.audit("MergeCards", primaryCardNo)
+	c.balanceCache.invalidateCard(primaryCardNo)
+	c.balanceCache.invalidateCard(secondaryCardNo)
+
+	var raw mergeResult
+	found, err := c.decodeOptional(resp, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return &MergeResult{CardNo: primaryCardNo}, nil
+	}
+
+	result := &MergeResult{CardNo: raw.CardNo, Balance: int(raw.Balance)}
+	if result.CardNo == "" {
+		result.CardNo = primaryCardNo
+	}
+
+	if raw.Status != "" {
+		result.Status, err = parseCardStatus(raw.Status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// validateMergeCards проверяет номера объединяемых карт
+func validateMergeCards(primaryCardNo, secondaryCardNo string) error {
+	fields := map[string]string{}
+	if strings.TrimSpace(primaryCardNo) == "" {
+		fields["primaryCardNo"] = "must not be empty"
+	}
+	if strings.TrimSpace(secondaryCardNo) == "" {
+		fields["secondaryCardNo"] = "must not be empty"
+	}
+	if len(fields) == 0 && primaryCardNo == secondaryCardNo {
+		fields["secondaryCardNo"] = "must differ from primaryCardNo"
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	return nil
+}
+
+// asMergeError переводит ответ 409 с кодом ошибки комарха в типизированные ошибки MergeCards
+func asMergeError(err error) error {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusConflict || httpErr.APIError == nil {
+		return err
+	}
+
+	switch httpErr.APIError.Code {
+	case mergeDifferentOwnersCode:
+		return ErrMergeDifferentOwners
+	case mergeAlreadyMergedCode:
+		return ErrCardAlreadyMerged
+	}
+
+	return err
+}
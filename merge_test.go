@@ -0,0 +1,72 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_MergeCards(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		result *comarch.MergeResult
+		err    error
+	}{
+		{
+			name:   "ok",
+			status: http.StatusOK,
+			body:   `{"cardNo":"1111222233334444","balance":"2500","status":"A"}`,
+			result: &comarch.MergeResult{CardNo: "1111222233334444", Balance: 2500, Status: comarch.CardStatusActive},
+		},
+		{name: "different_owners", status: http.StatusConflict, body: `{"errorCode":"DIFFERENT_OWNERS"}`, err: comarch.ErrMergeDifferentOwners},
+		{name: "already_merged", status: http.StatusConflict, body: `{"errorCode":"ALREADY_MERGED"}`, err: comarch.ErrCardAlreadyMerged},
+		{name: "other_conflict", status: http.StatusConflict, body: `{"errorCode":"LOCKED"}`, err: comarch.ErrConflict},
+		{name: "unknown_card", status: http.StatusNotFound, err: comarch.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/cwaapiinterface/common/cards/merge", r.URL.Path)
+
+				username, _, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, testUsername, username)
+
+				var body map[string]string
+				json.NewDecoder(r.Body).Decode(&body)
+				assert.Equal(t, map[string]string{"primaryCardNo": testCredentialsCardNo, "secondaryCardNo": "5555666677778888"}, body)
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			result, err := c.MergeCards(testCredentialsCardNo, "5555666677778888")
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err))
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestClient_MergeCards_Validation(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not be sent")
+	})
+
+	_, err := c.MergeCards(testCredentialsCardNo, testCredentialsCardNo)
+	var validationErr *comarch.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Contains(t, validationErr.Fields, "secondaryCardNo")
+	}
+}
@@ -0,0 +1,57 @@
+package comarch
+
+import "time"
+
+// RetryOutcome итог запроса после всех попыток
+type RetryOutcome string
+
+const (
+	RetryOutcomeSuccess RetryOutcome = "success"
+	RetryOutcomeFailure RetryOutcome = "failure"
+)
+
+// Metrics получает метрики клиента. Набор методов будет расширяться, поэтому реализации
+// стоит встраивать NopMetrics, чтобы не ломаться при добавлении новых метрик.
+type Metrics interface {
+	// ObserveRetries вызывается после завершения запроса операции operation. retries число
+	// повторных попыток (0, если запрос выполнился с первой попытки), outcome итог последней попытки.
+	ObserveRetries(operation string, outcome RetryOutcome, retries int)
+	// ObserveTransfer вызывается после закрытия тела ответа каждой попытки запроса. sent размер тела
+	// запроса, received число прочитанных байт тела ответа (после распаковки транспортом).
+	ObserveTransfer(operation string, sent, received int64)
+	// ObserveLimiterWait вызывается перед каждой попыткой запроса, если задан WithRateLimit.
+	// wait время ожидания лимитера, в том числе нулевое и прерванное отменой контекста.
+	ObserveLimiterWait(operation string, wait time.Duration)
+	// ObserveFailover вызывается, когда запрос операции operation повторяется на резервном адресе backup
+	ObserveFailover(operation string, backup string)
+	// ObserveClockSkew вызывается для каждого ответа с заголовком Date. offset сдвиг часов комарха
+	// относительно локальных, положительный, если часы комарха спешат.
+	ObserveClockSkew(operation string, offset time.Duration)
+	// ObserveCircuitState вызывается при каждой смене состояния circuit breaker из WithCircuitBreaker
+	ObserveCircuitState(state BreakerState)
+	// ObserveOperationCircuitState вызывается при каждой смене состояния circuit breaker операции operation
+	// при WithCircuitBreakerPerOperation
+	ObserveOperationCircuitState(operation string, state BreakerState)
+	// ObserveConnection вызывается, когда попытка запроса получает соединение. reused true, если соединение
+	// взято из пула keep-alive без нового dial и tls рукопожатия, idle сколько оно простаивало в пуле.
+	ObserveConnection(operation string, reused bool, idle time.Duration)
+}
+
+// NopMetrics реализация Metrics, которая ничего не делает
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveRetries(string, RetryOutcome, int) {}
+
+func (NopMetrics) ObserveTransfer(string, int64, int64) {}
+
+func (NopMetrics) ObserveLimiterWait(string, time.Duration) {}
+
+func (NopMetrics) ObserveFailover(string, string) {}
+
+func (NopMetrics) ObserveClockSkew(string, time.Duration) {}
+
+func (NopMetrics) ObserveCircuitState(BreakerState) {}
+
+func (NopMetrics) ObserveOperationCircuitState(string, BreakerState) {}
+
+func (NopMetrics) ObserveConnection(string, bool, time.Duration) {}
@@ -0,0 +1,509 @@
+package comarch
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Option дополнительная настройка клиента, передается в New
+type Option func(*Client)
+
+// WithRequestTimings задает callback, который получает длительности фаз каждого запроса к комарху.
+// Callback вызывается синхронно после получения заголовков ответа (или ошибки транспорта).
+func WithRequestTimings(fn func(RequestTimings)) Option {
+	return func(c *Client) {
+		c.onTimings = fn
+	}
+}
+
+// WithReauthentication включает хранение паролей пользователей в памяти клиента, чтобы Reauthenticate
+// мог повторить вход по паролю. Пароль удаляется при SignOut, при отказе комарха в повторном входе и
+// через время жизни токена после его истечения (у токенов без ExpiresAt - только при SignOut или отказе).
+// Опция предназначена для фоновых обработчиков с небольшим числом учетных записей.
+func WithReauthentication() Option {
+	return func(c *Client) {
+		c.keepPasswords = true
+	}
+}
+
+// WithConcurrency ограничивает число одновременных запросов, которые выполняет одна пакетная операция
+// (GetBalanceInfoBatch, GetBalanceInfoBatchPartial, SignOutBatch, SignOutBatchPartial, ActivateCardsBatch).
+// По умолчанию 4. Ограничение мягкое и действует в пределах одного вызова: несколько параллельных пакетных
+// операций суммарно могут выполнять больше запросов. Жесткий предел частоты запросов всего клиента задает
+// WithRateLimit: запросы пакета сверх него ждут лимитер.
+func WithConcurrency(n int) Option {
+	return func(c *Client) {
+		c.concurrency = n
+	}
+}
+
+// WithAPIPrefix заменяет префикс путей api комарха, по умолчанию /cwaapiinterface. Нужен инсталляциям,
+// где api опубликовано под другим путем. Префикс должен начинаться с "/" и не заканчиваться на "/",
+// пустой префикс означает, что api находится в корне basePath.
+func WithAPIPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.apiPrefix = prefix
+	}
+}
+
+// WithBackupBaseURLs задает резервные адреса комарха в том же формате, что и basePath в New.
+// Если основной адрес недоступен (ошибка транспорта) или отвечает 5xx, запрос сразу повторяется на резервных
+// адресах по порядку с теми же заголовками, авторизацией и телом. Запрос, тело которого нельзя отправить
+// повторно (без GetBody), на резервный адрес не переключается. Каждое переключение сообщается
+// в Metrics.ObserveFailover. Переключение происходит в рамках одной попытки WithRetry, каждая следующая
+// попытка снова начинается с основного адреса.
+func WithBackupBaseURLs(urls []string) Option {
+	return func(c *Client) {
+		c.backupBasePaths = append([]string(nil), urls...)
+	}
+}
+
+// WithRetry включает повторные попытки запросов при ошибках транспорта и ответах 5xx (см. WithRetryClassifier).
+// maxAttempts общее число попыток, включая первую. По умолчанию 1, то есть без повторов.
+// Перед каждой повторной попыткой клиент ждет экспоненциально растущую задержку со случайным джиттером.
+func WithRetry(maxAttempts int) Option {
+	return func(c *Client) {
+		c.retryAttempts = maxAttempts
+	}
+}
+
+// WithFirstAttemptShare делит время до дедлайна между первой попыткой и повторами WithRetry, чтобы одна
+// медленная попытка не съедала весь бюджет. Если в момент первой попытки до дедлайна контекста (или таймаута
+// WithOperationTimeout / WithCallTimeout) осталось R, первая попытка ограничивается R*share, а повторы с
+// задержками между ними выполняются в оставшиеся R*(1-share) до того же дедлайна. Например, при таймауте 1с
+// и share 0.6 первая попытка прерывается через 600мс, а на повторы остается 400мс. share от 0 (не включая)
+// до 1; по умолчанию 1, то есть первая попытка может занять весь бюджет. Без дедлайна и без WithRetry
+// опция ни на что не влияет.
+func WithFirstAttemptShare(share float64) Option {
+	return func(c *Client) {
+		c.firstAttemptShare = share
+	}
+}
+
+// WithBackoffStrategy заменяет задержку между повторными попытками WithRetry. По умолчанию задержка растет
+// экспоненциально от 100мс до 5с со случайным джиттером. Для учета заголовка Retry-After используйте RetryAfter.
+func WithBackoffStrategy(strategy BackoffStrategy) Option {
+	return func(c *Client) {
+		c.backoff = strategy
+	}
+}
+
+// WithRetryClassifier заменяет правило, по которому WithRetry решает, повторять ли попытку. По умолчанию
+// DefaultRetryClassifier: ошибки транспорта и ответы 5xx. Ответы 200 и плановые работы (ErrServiceUnavailable)
+// не повторяются независимо от классификатора. На circuit breaker классификатор не влияет: неудачей для него
+// по-прежнему считаются только ошибки транспорта и ответы 5xx.
+func WithRetryClassifier(classifier RetryClassifier) Option {
+	return func(c *Client) {
+		c.retryable = classifier
+	}
+}
+
+// WithOnRetry задает callback, который вызывается перед каждой повторной попыткой.
+// attempt номер завершившейся неудачной попытки, err ее ошибка.
+func WithOnRetry(fn func(operation string, attempt int, err error)) Option {
+	return func(c *Client) {
+		c.onRetry = fn
+	}
+}
+
+// WithMetrics задает получателя метрик клиента
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Client) {
+		c.metrics = metrics
+	}
+}
+
+// WithGrantTypes регистрирует дополнительные grant_type, которые есть в конкретной инсталляции комарха.
+// Зарегистрированные grant_type можно использовать в SignIn наравне со стандартными.
+func WithGrantTypes(grants ...GrantType) Option {
+	return func(c *Client) {
+		for _, grant := range grants {
+			c.grantTypes[grant] = struct{}{}
+		}
+	}
+}
+
+// WithAuthResponseHeaders задает заголовки ответа на логин, которые сохраняются в AccessToken.ResponseHeaders.
+// Ключи в ResponseHeaders приводятся к каноническому виду (X-Ratelimit-Remaining). По умолчанию заголовки не сохраняются.
+func WithAuthResponseHeaders(names ...string) Option {
+	return func(c *Client) {
+		for _, name := range names {
+			c.authResponseHeaders = append(c.authResponseHeaders, http.CanonicalHeaderKey(name))
+		}
+	}
+}
+
+// WithSendCookies задает, отправлять ли куки из AccessToken.Cookies в запросах с токеном. По умолчанию true.
+// Шлюзы комарха без состояния ожидают только bearer токен и могут отвечать 403 на запросы с куками сессии.
+func WithSendCookies(send bool) Option {
+	return func(c *Client) {
+		c.sendCookies = send
+	}
+}
+
+// WithSessionCookieNames задает имена кук сессии, которые сохраняются в AccessToken.Cookies после логина.
+// Остальные куки ответа (аналитика, маркетинг) отбрасываются. По умолчанию сохраняются все куки.
+func WithSessionCookieNames(names []string) Option {
+	return func(c *Client) {
+		c.sessionCookieNames = map[string]struct{}{}
+		for _, name := range names {
+			c.sessionCookieNames[name] = struct{}{}
+		}
+	}
+}
+
+// WithCodec задает реализацию json, которой клиент кодирует запросы и декодирует ответы. По умолчанию encoding/json
+func WithCodec(codec Codec) Option {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// WithPasswordNormalization задает, приводить ли пароли к NFC перед отправкой в комарх. По умолчанию true:
+// одна и та же кириллица может быть набрана разными последовательностями кодовых точек, а комарх сравнивает байты.
+func WithPasswordNormalization(normalize bool) Option {
+	return func(c *Client) {
+		c.nfcPasswords = normalize
+	}
+}
+
+// WithMaxTransferSize ограничивает размер тела запроса и ответа в байтах. Запрос с большим телом не отправляется,
+// а чтение слишком большого ответа прерывается, в обоих случаях возвращается ErrTransferTooLarge.
+// По умолчанию размер не ограничен.
+func WithMaxTransferSize(n int64) Option {
+	return func(c *Client) {
+		c.maxTransferSize = n
+	}
+}
+
+// WithBalanceCache включает кэширование ответов GetBalanceInfo в памяти клиента. Ключ кэша значение токена.
+// В течение ttl баланс отдается из кэша без запроса. После ttl, если комарх прислал ETag или Last-Modified,
+// отправляется условный запрос, и на ответ 304 возвращается закэшированный баланс.
+// Записи удаляются после истечения токена (или через ttl для токенов без срока действия и валидаторов).
+//
+// Гарантии согласованности: операции клиента, меняющие баллы (ActivateCoupon), и SignOut сбрасывают кэш токена
+// после успешного ответа, поэтому изменения, сделанные через этот клиент, видны в следующем GetBalanceInfo.
+// Изменения в обход клиента (начисления на кассе, другой экземпляр клиента) видны не позже чем через ttl,
+// либо сразу после InvalidateBalance / InvalidateBalanceByCard.
+func WithBalanceCache(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.balanceCache = newBalanceCache(ttl)
+	}
+}
+
+// WithBearerToken задает токен, полученный вне клиента, например от вышестоящего сервиса авторизации.
+// Методы, принимающие AccessToken, используют его, если им передан токен без значения.
+// С этой опцией клиент можно создать без логина и пароля, тогда методы с Basic Auth (логин, сброс пароля,
+// GetBalanceInfoByCard, GetStores) возвращают ErrCredentialsRequired.
+func WithBearerToken(accessToken AccessToken) Option {
+	return func(c *Client) {
+		c.bearerToken = &accessToken
+	}
+}
+
+// WithRateLimit ограничивает частоту запросов клиента: в среднем не больше rps запросов в секунду,
+// с всплесками до burst запросов. Ограничение общее для всех методов и учитывает повторные попытки.
+// Запрос ждет лимитер с учетом своего контекста и при его отмене возвращает ctx.Err().
+// Время ожидания сообщается в Metrics.ObserveLimiterWait. Лимитер жесткий предел и для пакетных операций:
+// WithConcurrency задает только число их одновременных запросов, а частоту ограничивает лимитер.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(rps, burst)
+	}
+}
+
+// WithCircuitBreaker включает circuit breaker: после failures неудачных попыток подряд (ошибки транспорта
+// и ответы 5xx, повторы WithRetry считаются отдельными попытками) клиент перестает обращаться к комарху и
+// сразу возвращает ErrCircuitOpen, не дожидаясь таймаутов. Через cooldown пропускается один пробный запрос:
+// если он успешен, работа восстанавливается, иначе breaker снова размыкается на cooldown. Разомкнутый breaker
+// прерывает и оставшиеся повторы. Состояние доступно через CircuitState и Metrics.ObserveCircuitState.
+// По умолчанию breaker общий для всех операций, отдельный для каждой операции включает WithCircuitBreakerPerOperation.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(failures, cooldown)
+	}
+}
+
+// WithCircuitBreakerPerOperation ведет circuit breaker из WithCircuitBreaker отдельно для каждой операции
+// (метода клиента) с теми же failures и cooldown: сбои одного метода комарха, например баланса, не блокируют
+// остальные, например логин. Состояние операции доступно через CircuitStateOf, смены состояний передаются
+// в Metrics.ObserveOperationCircuitState вместо ObserveCircuitState. Без WithCircuitBreaker New возвращает
+// ошибку конфигурации.
+func WithCircuitBreakerPerOperation() Option {
+	return func(c *Client) {
+		c.breakerPerOperation = true
+	}
+}
+
+// WithLocation задает часовой пояс, в котором комарх отдает даты без указания пояса (DATETIME_FMT).
+// По умолчанию time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(c *Client) {
+		c.location = loc
+	}
+}
+
+// WithDryRun включает режим, в котором методы, меняющие данные (CreateCardHolder, UpdateCardHolder,
+// ChangePassword, ResetPassword*, ActivateCoupon, UploadCardHolderDocument), проверяют аргументы и собирают
+// запрос, но не отправляют его, а возвращают успешный результат. Каждый неотправленный запрос пишется в лог
+// с уровнем Info.
+// Методы чтения и логин работают как обычно. Предназначен для стендов и нагрузочного тестирования.
+func WithDryRun(dryRun bool) Option {
+	return func(c *Client) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithRequestInterceptor задает функцию, которая получает каждый запрос клиента полностью собранным: с адресом,
+// телом, авторизацией (Basic Auth или токен и куки) и всеми заголовками, которые выставляет клиент.
+// Вызывается один раз на вызов метода до первой попытки, в том числе в режиме WithDryRun. Может дополнить
+// запрос, например заголовками трассировки. Ошибка interceptor прерывает запрос и возвращается из метода.
+// Тело запроса можно прочитать через req.GetBody, не затрагивая req.Body.
+func WithRequestInterceptor(interceptor func(operation string, req *http.Request) error) Option {
+	return func(c *Client) {
+		c.interceptor = interceptor
+	}
+}
+
+// WithFormLogin отправляет параметры логина (grant_type, номер карты или телефона, пароль) в теле запроса
+// application/x-www-form-urlencoded вместо query string. Нужен инсталляциям, которые не принимают учетные
+// данные в адресе. Без аргументов действует для всех grant_type, иначе только для перечисленных.
+func WithFormLogin(grants ...GrantType) Option {
+	return func(c *Client) {
+		c.formLoginGrants = map[GrantType]struct{}{}
+		for _, grant := range grants {
+			c.formLoginGrants[grant] = struct{}{}
+		}
+	}
+}
+
+// WithLoginMethod задает http метод запроса на логин для grant: GET или POST (по умолчанию). Нужен
+// инсталляциям, которые принимают логин по некоторым grant_type только через GET. grant должен быть
+// стандартным или зарегистрированным через WithGrantTypes, GET нельзя сочетать с WithFormLogin для того же grant.
+func WithLoginMethod(grant GrantType, method string) Option {
+	return func(c *Client) {
+		if c.loginMethods == nil {
+			c.loginMethods = map[GrantType]string{}
+		}
+		c.loginMethods[grant] = strings.ToUpper(method)
+	}
+}
+
+// WithProxy направляет запросы к комарху через прокси proxyURL: http://, https:// или socks5://
+// (socks5h://), логин и пароль прокси берутся из proxyURL. Для остальных схем New возвращает ошибку
+// конфигурации. Применяется только к http.Client, который создает клиент: вместе с собственным
+// http.Client в New возвращается ошибка конфигурации.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *Client) {
+		c.transport.proxyURL = proxyURL
+		c.transport.set("WithProxy")
+	}
+}
+
+// WithHostHeader отправляет все запросы с заголовком Host host (имя или имя:порт), например когда комарх
+// адресуется по ip через балансировщик с маршрутизацией по виртуальным хостам. Адрес соединения по-прежнему
+// берется из basePath. Для https имя из host также используется как ServerName tls (SNI и проверка сертификата),
+// если ServerName не задан в WithTLSClientConfig. С собственным http.Client или WithTransport ServerName нужно
+// задать в их транспорте.
+func WithHostHeader(host string) Option {
+	return func(c *Client) {
+		c.hostHeader = host
+	}
+}
+
+// WithAuthScheme задает схему заголовка Authorization, с которой отправляется токен участника, вместо Bearer.
+// Схема сохраняется в выданных токенах, и AccessToken.AuthorizationHeader возвращает тот же заголовок.
+// Схема не должна быть пустой и содержать пробелы.
+func WithAuthScheme(scheme string) Option {
+	return func(c *Client) {
+		c.authScheme = scheme
+	}
+}
+
+// WithTLSClientConfig задает настройки tls соединений с комархом, например корневые сертификаты стенда.
+// Применяется только к http.Client, который создает клиент: вместе с собственным http.Client в New
+// возвращается ошибка конфигурации.
+func WithTLSClientConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.transport.tlsConfig = config
+		c.transport.set("WithTLSClientConfig")
+	}
+}
+
+// WithMinTLSVersion запрещает соединения с комархом по версиям tls ниже version (tls.VersionTLS12 и т.п.)
+// и, если переданы cipherSuites, ограничивает наборы шифров для tls 1.2 и ниже. Наборы шифров tls 1.3
+// не настраиваются. Дополняет WithTLSClientConfig и, как и он, применяется только к http.Client,
+// который создает клиент.
+func WithMinTLSVersion(version uint16, cipherSuites ...uint16) Option {
+	return func(c *Client) {
+		c.transport.minTLSVersion = version
+		c.transport.cipherSuites = cipherSuites
+		c.transport.set("WithMinTLSVersion")
+	}
+}
+
+// WithClockSkewThreshold предупреждает в лог, когда часы комарха (по заголовку Date ответов) расходятся
+// с локальными больше чем на threshold. Предупреждение пишется при превышении порога, а не на каждый ответ.
+// Если correctExpiry, ExpiresAt новых токенов уменьшается на величину расхождения, превышающего порог,
+// чтобы клиент не использовал токены, которые комарх уже считает истекшими.
+func WithClockSkewThreshold(threshold time.Duration, correctExpiry bool) Option {
+	return func(c *Client) {
+		c.clock.threshold = threshold
+		c.clock.correctExpiry = correctExpiry
+	}
+}
+
+// WithStrictDecoding запрещает неизвестные поля в ответах комарха: вместо молчаливого пропуска возвращается
+// ошибка ErrUnknownField с именем поля и типом ответа. Нужна для контрактных тестов, в рабочем окружении
+// лучше оставить выключенной, чтобы новые поля комарха не ломали клиент. Ответ на логин тоже проверяется,
+// поэтому AccessToken.Extra при ней всегда пустой. Поля анкеты (PersonalData) не проверяются: она
+// разбирается собственным UnmarshalJSON. Декодер WithCodec должен поддерживать DisallowUnknownFields.
+// Отдельные операции можно исключить из проверки через WithStrictDecodingExcept.
+func WithStrictDecoding(strict bool) Option {
+	return func(c *Client) {
+		c.strictDecoding = strict
+	}
+}
+
+// WithStrictDecodingExcept исключает операции (имена методов клиента, например GetBalanceInfo) из проверки
+// WithStrictDecoding: их ответы разбираются без ошибок на неизвестные поля. Позволяет включать строгий
+// разбор постепенно, оставляя в стороне операции, ответы которых часто меняются. Без WithStrictDecoding
+// ничего не делает. Повторные вызовы дополняют список.
+func WithStrictDecodingExcept(operations ...string) Option {
+	return func(c *Client) {
+		if c.strictDecodingExcept == nil {
+			c.strictDecodingExcept = map[string]struct{}{}
+		}
+		for _, operation := range operations {
+			c.strictDecodingExcept[operation] = struct{}{}
+		}
+	}
+}
+
+// WithEventChannel публикует в events события жизненного цикла токенов: логин, неудачный логин, повторный
+// вход, разлогин и обнаружение истекшего токена. Отправка не блокирует клиент: если канал заполнен,
+// событие отбрасывается, поэтому канал стоит делать буферизованным. Клиент канал не закрывает.
+func WithEventChannel(events chan<- Event) Option {
+	return func(c *Client) {
+		c.events = events
+	}
+}
+
+// WithRequestIDGenerator задает генератор внутренних идентификаторов запросов. Идентификатор присваивается
+// каждому вызову метода, пишется в поле request_id всех логов запроса (дампы, повторы, ошибки) и доступен
+// из ошибки через RequestID. По умолчанию 8 случайных шестнадцатеричных символов.
+func WithRequestIDGenerator(generator func() string) Option {
+	return func(c *Client) {
+		c.requestIDGenerator = generator
+	}
+}
+
+// WithWatchInterval задает период опроса баланса в WatchBalance и время, которое комарх держит long-poll
+// запрос без изменений (с точностью до секунды, не меньше секунды). По умолчанию 30 секунд.
+func WithWatchInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.watchInterval = interval
+	}
+}
+
+// WithAuditLogger задает получателя событий аудита чувствительных операций: сброса пароля (ResetPassword*),
+// смены пароля (ChangePassword), признака золотой карты (SetGoldenCard), объединения карт (MergeCards), смены
+// основной карты (SetPrimaryCard), обезличивания участника (AnonymizeCardHolder) и повторной отправки
+// подтверждения активации (ResendActivationConfirmation). События отправляются только
+// после успешного ответа комарха и содержат имя операции, замаскированный номер карты или телефона и время,
+// без паролей и токенов.
+// Аудит не зависит от уровня логирования.
+func WithAuditLogger(logger AuditLogger) Option {
+	return func(c *Client) {
+		c.auditLogger = logger
+	}
+}
+
+// WithCredentialProvider задает источник учетных данных приложения для Basic Auth, например менеджер секретов
+// с ротацией. provider вызывается перед каждым запросом с Basic Auth с контекстом запроса, поэтому
+// кэширование, если оно нужно, на стороне provider. Логин и пароль, переданные в New, при этом не используются
+// и могут быть пустыми. Ошибка provider возвращается из метода клиента.
+func WithCredentialProvider(provider func(ctx context.Context) (username, password string, err error)) Option {
+	return func(c *Client) {
+		c.credentialProvider = provider
+	}
+}
+
+// WithBasicAuthBuilder заменяет стандартный Basic Auth приложения: builder получает логин и пароль
+// (из New или WithCredentialProvider) и возвращает значение заголовка Authorization целиком, например
+// для шлюза с нестандартным разделителем. По умолчанию используется http.Request.SetBasicAuth.
+func WithBasicAuthBuilder(builder func(username, password string) string) Option {
+	return func(c *Client) {
+		c.basicAuthBuilder = builder
+	}
+}
+
+// WithOperationTimeout ограничивает время выполнения операции operation (имя метода клиента, например
+// GetBalanceInfo) значением timeout, включая все повторные попытки и чтение ответа. Действует поверх таймаута
+// http.Client и контекста вызова: срабатывает наименьший. По истечении возвращается ошибка ErrTimeout.
+func WithOperationTimeout(operation string, timeout time.Duration) Option {
+	return func(c *Client) {
+		c.operationTimeouts[operation] = timeout
+	}
+}
+
+// WithRefreshHint включает подсказку об обновлении токена: если метод вызван с токеном, у которого осталось меньше
+// threshold (доля от 0 до 1, например 0.5) полного времени жизни, вызывается hook с токеном и оставшимся временем
+// (AccessToken.TimeToLive). При nil hook пишется сообщение в лог с уровнем Debug. Токен сам не обновляется.
+// Подсказка работает только для токенов с IssuedAt и ExpiresAt, то есть полученных этим пакетом.
+func WithRefreshHint(threshold float64, hook func(accessToken AccessToken, ttl time.Duration)) Option {
+	return func(c *Client) {
+		c.refreshHintThreshold = threshold
+		c.onRefreshHint = hook
+	}
+}
+
+// WithTransport задает http.RoundTripper для http.Client, который создает клиент, например DebugTransport
+// или транспорт с собственным пулом соединений. Как и другие опции транспорта, несовместима с собственным
+// http.Client в New, а также с WithProxy, WithTLSClientConfig и WithMinTLSVersion: их нужно настроить
+// в самом транспорте.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport.roundTripper = transport
+		c.transport.set("WithTransport")
+	}
+}
+
+// WithAnonymousOperations отправляет перечисленные операции (имена методов клиента) без Basic Auth приложения.
+// Нужна для публичных методов, которые на некоторых инсталляциях отклоняют запросы с учетными данными (403).
+// Из встроенных методов публичный только GetStores, остальные методы с Basic Auth (логин, ResetPassword*,
+// GetBalanceInfoByCard) без учетных данных не работают.
+func WithAnonymousOperations(operations ...string) Option {
+	return func(c *Client) {
+		c.anonymousOperations = map[string]struct{}{}
+		for _, operation := range operations {
+			c.anonymousOperations[operation] = struct{}{}
+		}
+	}
+}
+
+// WithTranscriptSink передает в sink запись о каждой попытке http запроса к комарху: метод, адрес,
+// заголовки, статус, длительность и sha256 тел запроса и ответа. Секреты (Authorization, куки, пароли и
+// токены в параметрах адреса) скрываются до записи. Запись об ответе отправляется при закрытии его тела,
+// о неудачном запросе сразу после ошибки. В отличие от логов и DebugTransport, предназначена для журнала аудита.
+func WithTranscriptSink(sink func(RequestRecord)) Option {
+	return func(c *Client) {
+		c.transcriptSink = sink
+	}
+}
+
+// WithTokenIssuerCheck включает проверку, что токен выдан этим же комархом: клиент сравнивает
+// AccessToken.Issuer с собственным адресом api и при несовпадении пишет предупреждение в лог, а при strict
+// возвращает ErrTokenIssuerMismatch без отправки запроса. Ловит токены продакшена, переданные клиенту
+// стенда, и наоборот. Токены без Issuer (собранные вручную или полученные старыми версиями библиотеки)
+// не проверяются. По умолчанию выключено, чтобы не ломать схемы с пересылкой токенов между окружениями.
+func WithTokenIssuerCheck(strict bool) Option {
+	return func(c *Client) {
+		c.tokenIssuerCheck = true
+		c.strictTokenIssuer = strict
+	}
+}
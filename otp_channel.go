@@ -0,0 +1,79 @@
+package comarch
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OTPChannel способ доставки одноразового кода участнику
+type OTPChannel string
+
+const (
+	// OTPChannelText код приходит в SMS. Используется по умолчанию
+	OTPChannelText OTPChannel = "text"
+	// OTPChannelVoice код сообщается голосовым звонком, для участников, которые не могут получить SMS
+	OTPChannelVoice OTPChannel = "voice"
+)
+
+// otpChannelParam параметр логина, в котором комарху передается способ доставки кода
+const otpChannelParam = "channel"
+
+// WithOTPChannel задает способ доставки одноразового кода для входа по SMS (SignInByPhoneOnly,
+// SignInByCardNoOnly, SignIn с GrantTypeBySMS) и для отправки кода активации карты (ActivateCardNo).
+// Для остальных вызовов опция игнорируется. Тот же способ можно передать в SignIn параметром channel
+func WithOTPChannel(channel OTPChannel) CallOption {
+	return func(o *callOptions) {
+		o.otpChannel = channel
+	}
+}
+
+// sendsOTP проверяет, что логин с grant и params отправляет участнику одноразовый код.
+// Подтверждение активации (cardactivation с otp) код не отправляет
+func sendsOTP(grant GrantType, params url.Values) bool {
+	switch grant {
+	case GrantTypeBySMS:
+		return true
+	case GrantTypeCardActivation:
+		_, ok := params["otp"]
+		return !ok
+	}
+
+	return false
+}
+
+// applyOTPChannel выставляет в params способ доставки кода из WithOTPChannel. Параметр channel, переданный
+// в SignIn явно, опция не перекрывает
+func applyOTPChannel(grant GrantType, params url.Values, opts []CallOption) {
+	if !sendsOTP(grant, params) {
+		return
+	}
+
+	var call callOptions
+	for _, opt := range opts {
+		opt(&call)
+	}
+
+	if call.otpChannel != "" && params.Get(otpChannelParam) == "" {
+		params.Set(otpChannelParam, string(call.otpChannel))
+	}
+}
+
+// normalizeOTPChannel проверяет способ доставки кода в params и выставляет OTPChannelText, если он не указан
+func normalizeOTPChannel(grant GrantType, params url.Values) error {
+	if !sendsOTP(grant, params) {
+		return nil
+	}
+
+	channel := OTPChannel(strings.ToLower(strings.TrimSpace(params.Get(otpChannelParam))))
+	switch channel {
+	case "":
+		channel = OTPChannelText
+	case OTPChannelText, OTPChannelVoice:
+	default:
+		return &ValidationError{Fields: map[string]string{otpChannelParam: "must be one of: text, voice"}}
+	}
+
+	params.Set(otpChannelParam, string(channel))
+
+	return nil
+}
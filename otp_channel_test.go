@@ -0,0 +1,77 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestWithOTPChannel(t *testing.T) {
+	var channel string
+	var sent bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		channel, sent = r.URL.Query().Get("channel"), true
+		tokenHandler(w, r)
+	}
+
+	tests := []struct {
+		name    string
+		signIn  func(c *comarch.Client) error
+		channel string
+	}{
+		{name: "phone_default_text", signIn: func(c *comarch.Client) error {
+			_, err := c.SignInByPhoneOnly("79990001122")
+			return err
+		}, channel: "text"},
+		{name: "phone_voice", signIn: func(c *comarch.Client) error {
+			_, err := c.SignInByPhoneOnly("79990001122", comarch.WithOTPChannel(comarch.OTPChannelVoice))
+			return err
+		}, channel: "voice"},
+		{name: "card_voice", signIn: func(c *comarch.Client) error {
+			_, err := c.SignInByCardNoOnly(testCredentialsCardNo, comarch.WithOTPChannel(comarch.OTPChannelVoice))
+			return err
+		}, channel: "voice"},
+		{name: "activation_voice", signIn: func(c *comarch.Client) error {
+			_, err := c.ActivateCardNo(testCredentialsCardNo, comarch.WithOTPChannel(comarch.OTPChannelVoice))
+			return err
+		}, channel: "voice"},
+		{name: "unified_param", signIn: func(c *comarch.Client) error {
+			_, err := c.SignIn(comarch.GrantTypeBySMS, map[string]string{"phoneNo": "79990001122", "channel": "Voice"})
+			return err
+		}, channel: "voice"},
+		{name: "password_login_ignores_option", signIn: func(c *comarch.Client) error {
+			_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword, comarch.WithOTPChannel(comarch.OTPChannelVoice))
+			return err
+		}, channel: ""},
+		{name: "confirmation_ignores_option", signIn: func(c *comarch.Client) error {
+			_, err := c.ConfirmCardActivation(testCredentialsCardNo, "123456", comarch.WithOTPChannel(comarch.OTPChannelVoice))
+			return err
+		}, channel: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, sent = "", false
+			c := newTestServer(t, handler)
+
+			assert.Nil(t, tt.signIn(c))
+			assert.True(t, sent)
+			assert.Equal(t, tt.channel, channel)
+		})
+	}
+
+	t.Run("invalid_channel", func(t *testing.T) {
+		sent = false
+		c := newTestServer(t, handler)
+
+		_, err := c.SignInByPhoneOnly("79990001122", comarch.WithOTPChannel("email"))
+
+		var validationErr *comarch.ValidationError
+		if assert.True(t, errors.As(err, &validationErr)) {
+			assert.Contains(t, validationErr.Fields, "channel")
+		}
+		assert.False(t, sent)
+	})
+}
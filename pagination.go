@@ -0,0 +1,150 @@
+package comarch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageInfo сведения о странице списка. Разные версии комарха передают их в теле ответа или в заголовках
+// X-Total-Count и Link, клиент приводит оба варианта к одному виду
+type PageInfo struct {
+	// номер страницы, начиная с 1
+	Page int
+	// общее число элементов, -1 если комарх его не сообщил
+	TotalCount int
+	// номер следующей страницы, 0 если страница последняя
+	NextPage int
+}
+
+// HasNext сообщает, есть ли следующая страница
+func (p PageInfo) HasNext() bool {
+	return p.NextPage > 0
+}
+
+// pagedBody тело ответа с пагинацией в полях. Комарх без пагинации в теле отдает просто массив
+type pagedBody struct {
+	Items      json.RawMessage `json:"items"`
+	TotalCount *flexInt        `json:"totalCount"`
+	NextPage   *flexInt        `json:"nextPage"`
+}
+
+// pageQuery возвращает параметры запроса страницы page размером size
+func pageQuery(page, size int) url.Values {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+	params.Set("size", strconv.Itoa(size))
+
+	return params
+}
+
+// validatePage проверяет номер и размер страницы
+func validatePage(page, size int) error {
+	fields := map[string]string{}
+	if page < 1 {
+		fields["page"] = "must be positive"
+	}
+	if size < 1 {
+		fields["size"] = "must be positive"
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	return nil
+}
+
+// decodePage разбирает страницу списка в items и возвращает сведения о пагинации. Сведения из тела
+// ответа важнее заголовков. Если следующая страница не указана явно, она вычисляется по общему числу элементов.
+func (c *Client) decodePage(resp *http.Response, page, size int, items interface{}) (PageInfo, error) {
+	info := PageInfo{Page: page, TotalCount: -1}
+
+	var raw json.RawMessage
+	if err := c.decodeResponse(resp, &raw); err != nil {
+		return info, err
+	}
+
+	nextKnown := false
+	if total, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("X-Total-Count"))); err == nil && total >= 0 {
+		info.TotalCount = total
+	}
+	if next, ok := linkNextPage(resp.Header); ok {
+		info.NextPage = next
+		nextKnown = true
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		var body pagedBody
+		if err := c.decode(resp, bytes.NewReader(raw), &body); err != nil {
+			return info, err
+		}
+		if body.TotalCount != nil {
+			info.TotalCount = int(*body.TotalCount)
+		}
+		if body.NextPage != nil {
+			info.NextPage = int(*body.NextPage)
+			nextKnown = true
+		}
+		raw = body.Items
+	}
+
+	if len(raw) > 0 {
+		if err := c.decode(resp, bytes.NewReader(raw), items); err != nil {
+			return info, err
+		}
+	}
+
+	if !nextKnown && info.TotalCount >= 0 && page*size < info.TotalCount {
+		info.NextPage = page + 1
+	}
+
+	return info, nil
+}
+
+// linkNextPage достает номер следующей страницы из заголовка Link (RFC 8288) с rel="next"
+func linkNextPage(header http.Header) (int, bool) {
+	for _, value := range header["Link"] {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			isNext := false
+			for _, param := range parts[1:] {
+				name, value := splitLinkParam(param)
+				if name == "rel" {
+					for _, rel := range strings.Fields(value) {
+						isNext = isNext || strings.EqualFold(rel, "next")
+					}
+				}
+			}
+			if !isNext {
+				continue
+			}
+
+			u, err := url.Parse(strings.Trim(target, "<>"))
+			if err != nil {
+				continue
+			}
+			if page, err := strconv.Atoi(u.Query().Get("page")); err == nil && page > 0 {
+				return page, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func splitLinkParam(param string) (string, string) {
+	kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+	if len(kv) != 2 {
+		return strings.ToLower(kv[0]), ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(kv[0])), strings.Trim(strings.TrimSpace(kv[1]), `"`)
+}
@@ -0,0 +1,97 @@
+package comarch
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"unicode"
+)
+
+// DeliveryChannel канал, по которому комарх отправил пользователю данные
+type DeliveryChannel string
+
+const (
+	DeliveryChannelSMS   DeliveryChannel = "SMS"
+	DeliveryChannelEmail DeliveryChannel = "EMAIL"
+)
+
+// PasswordResetResult результат сброса пароля. Поля заполнены только если комарх их вернул
+type PasswordResetResult struct {
+	// временный пароль
+	TemporaryPassword string `json:"tempPassword"`
+	// канал, по которому отправлен новый пароль
+	Channel DeliveryChannel `json:"channel"`
+	// замаскированный телефон или email, на который отправлен пароль. Например +7*****1122
+	MaskedDestination string `json:"destination"`
+}
+
+// resetPassword сбрасывает пароль для карты или телефона из params
+func (c *Client) resetPassword(operation string, params map[string]string, opts ...CallOption) (*PasswordResetResult, error) {
+	if phoneNo, ok := params["phoneNo"]; ok {
+		phoneNo, err := normalizePhoneField("phoneNo", phoneNo)
+		if err != nil {
+			return nil, err
+		}
+
+		params["phoneNo"] = phoneNo
+	}
+
+	u := c.endpoint("/common/passresetting")
+	req, err := c.newJSONRequest("POST", u, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.basicAuth(operation, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(operation, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var result PasswordResetResult
+	// комарх может ответить пустым телом
+	if _, err := c.decodeOptional(resp, &result); err != nil {
+		return nil, err
+	}
+
+	c.audit(operation, params["cardNo"]+params["phoneNo"])
+
+	return &result, nil
+}
+
+// normalizePassword проверяет, что пароль не содержит управляющих символов, и приводит его к NFC,
+// если нормализация не отключена WithPasswordNormalization. field имя поля для ValidationError
+func (c *Client) normalizePassword(field, password string) (string, error) {
+	for _, r := range password {
+		if unicode.IsControl(r) {
+			return "", &ValidationError{Fields: map[string]string{field: "must not contain control characters"}}
+		}
+	}
+
+	if !c.nfcPasswords {
+		return password, nil
+	}
+
+	return norm.NFC.String(password), nil
+}
+
+// normalizePasswords normalizePassword для старого и нового пароля в ChangePassword
+func (c *Client) normalizePasswords(password, newPassword string) (string, string, error) {
+	password, err := c.normalizePassword("oldPass", password)
+	if err != nil {
+		return "", "", err
+	}
+
+	newPassword, err = c.normalizePassword("newPass", newPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	return password, newPassword, nil
+}
@@ -0,0 +1,59 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_ResetPasswordByPhoneNoWithResult(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{"phoneNo": "79990001122"}, body)
+
+		w.Write([]byte(`{"channel":"SMS","destination":"+7*****1122"}`))
+	})
+
+	res, err := c.ResetPasswordByPhoneNoWithResult("79990001122")
+	assert.Nil(t, err)
+	assert.Equal(t, &comarch.PasswordResetResult{Channel: comarch.DeliveryChannelSMS, MaskedDestination: "+7*****1122"}, res)
+}
+
+func TestClient_ResetPasswordByCardNo_EmptyBody(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	assert.Nil(t, c.ResetPasswordByCardNo(testCredentialsCardNo))
+
+	res, err := c.ResetPasswordByCardNoWithResult(testCredentialsCardNo)
+	assert.Nil(t, err)
+	assert.Equal(t, &comarch.PasswordResetResult{}, res)
+}
+
+func TestClient_ChangePassword_Normalization(t *testing.T) {
+	// "й" как "и" + комбинируемая бреве (NFD) и как один символ (NFC)
+	const nfd, nfc = "паро\u0438\u0306", "паро\u0439"
+
+	var body map[string]string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	c := newTestServer(t, handler)
+	assert.Nil(t, c.ChangePassword(testAccessToken, nfd, nfd))
+	assert.Equal(t, map[string]string{"oldPass": nfc, "newPass": nfc}, body)
+
+	err := c.ChangePassword(testAccessToken, nfc, "new\x00pass")
+	var validationErr *comarch.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Contains(t, validationErr.Fields, "newPass")
+	}
+
+	c = newTestServer(t, handler, comarch.WithPasswordNormalization(false))
+	assert.Nil(t, c.ChangePassword(testAccessToken, nfd, nfd))
+	assert.Equal(t, map[string]string{"oldPass": nfd, "newPass": nfd}, body)
+}
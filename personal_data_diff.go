@@ -0,0 +1,105 @@
+package comarch
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldChange изменение одного поля анкеты: значение до и после. Значения имеют тип поля PersonalData
+// (string, int, bool, Sex)
+type FieldChange struct {
+	Old interface{}
+	New interface{}
+}
+
+// personalDataDateLayouts форматы, в которых комарх присылает даты анкеты (birthday, favPrdChangeDate)
+var personalDataDateLayouts = []string{"2006-01-02", "2006-01-02T15:04:05Z07:00", DATETIME_FMT, "02.01.2006"}
+
+// Diff сравнивает анкету p с other и возвращает изменения по полям, ключ - имя поля в json комарха.
+// Old берется из p, New из other. Пол сравнивается по отправляемому значению (Sex, а без него SexRaw),
+// даты в разных форматах считаются равными, если это один день, мобильный телефон сравнивается после
+// нормализации. Пустой результат означает, что анкеты совпадают и UpdateCardHolder можно не вызывать.
+func (p PersonalData) Diff(other PersonalData) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+
+	oldValue, newValue := reflect.ValueOf(p), reflect.ValueOf(other)
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		oldField, newField := oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+
+		var equal bool
+		switch name {
+		case "sex":
+			oldField, newField = p.sentSex(), other.sentSex()
+			equal = oldField == newField
+		case "birthday", "favPrdChangeDate":
+			equal = sameDate(oldField.(string), newField.(string))
+		case "mobilePhone":
+			equal = samePhone(oldField.(string), newField.(string))
+		default:
+			equal = oldField == newField
+		}
+
+		if !equal {
+			changes[name] = FieldChange{Old: oldField, New: newField}
+		}
+	}
+
+	return changes
+}
+
+// sentSex значение пола, которое MarshalJSON отправит комарху
+func (p PersonalData) sentSex() Sex {
+	if p.Sex == "" {
+		return Sex(p.SexRaw)
+	}
+
+	return p.Sex
+}
+
+// sameDate сравнивает даты анкеты с точностью до дня. Нераспознанные даты сравниваются как строки
+func sameDate(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	dateA, okA := parsePersonalDataDate(a)
+	dateB, okB := parsePersonalDataDate(b)
+	if !okA || !okB {
+		return false
+	}
+
+	return dateA.Equal(dateB)
+}
+
+func parsePersonalDataDate(value string) (time.Time, bool) {
+	for _, layout := range personalDataDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			year, month, day := t.Date()
+			return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// samePhone сравнивает телефоны после NormalizePhone. Нераспознанные номера сравниваются как строки
+func samePhone(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	phoneA, errA := NormalizePhone(a)
+	phoneB, errB := NormalizePhone(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return phoneA == phoneB
+}
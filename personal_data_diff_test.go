@@ -0,0 +1,68 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPersonalData_Diff(t *testing.T) {
+	base := comarch.PersonalData{
+		Name:        "Иван",
+		Surname:     "Иванов",
+		Birthday:    "1990-05-17",
+		MobilePhone: "79990001122",
+		Children:    1,
+		SmsAdv:      true,
+		Sex:         comarch.SexMale,
+	}
+
+	t.Run("equal", func(t *testing.T) {
+		assert.Empty(t, base.Diff(base))
+	})
+
+	t.Run("changed_fields", func(t *testing.T) {
+		other := base
+		other.Name = "Пётр"
+		other.Children = 2
+		other.SmsAdv = false
+		other.Sex = comarch.SexFemale
+
+		assert.Equal(t, map[string]comarch.FieldChange{
+			"name":     {Old: "Иван", New: "Пётр"},
+			"children": {Old: 1, New: 2},
+			"smsAdv":   {Old: true, New: false},
+			"sex":      {Old: comarch.SexMale, New: comarch.SexFemale},
+		}, base.Diff(other))
+	})
+
+	t.Run("same_values_in_other_format", func(t *testing.T) {
+		other := base
+		other.Birthday = "17.05.1990"
+		other.MobilePhone = "+7 (999) 000-11-22"
+
+		assert.Empty(t, base.Diff(other))
+	})
+
+	t.Run("date_changed", func(t *testing.T) {
+		other := base
+		other.Birthday = "1990-05-18"
+
+		assert.Equal(t, map[string]comarch.FieldChange{
+			"birthday": {Old: "1990-05-17", New: "1990-05-18"},
+		}, base.Diff(other))
+	})
+
+	t.Run("raw_sex", func(t *testing.T) {
+		read := base
+		read.Sex, read.SexRaw = "", "X"
+
+		assert.Equal(t, map[string]comarch.FieldChange{
+			"sex": {Old: comarch.SexMale, New: comarch.Sex("X")},
+		}, base.Diff(read))
+
+		other := read
+		other.Name = "Пётр"
+		assert.NotContains(t, read.Diff(other), "sex")
+	})
+}
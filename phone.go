@@ -0,0 +1,58 @@
+package comarch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizePhone приводит номер телефона к виду, в котором его ожидает комарх: только цифры, российские
+// номера с кодом 7, например 79991234567. Пробелы, скобки, дефисы и точки отбрасываются. Для российских
+// номеров принимаются варианты +7, 8 и 10 цифр без кода страны. Номера других стран принимаются только
+// с + и возвращаются без него. Для остальных значений возвращается ошибка, соответствующая ErrInvalidPhone.
+// Клиент применяет NormalizePhone ко всем номерам телефонов: при логине, сбросе пароля и в анкете
+// (PersonalData.MobilePhone), поэтому один и тот же номер всегда уходит в комарх одинаково.
+func NormalizePhone(phone string) (string, error) {
+	value := strings.TrimSpace(phone)
+	international := strings.HasPrefix(value, "+")
+	value = strings.TrimPrefix(value, "+")
+
+	digits := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		switch ch := value[i]; {
+		case ch >= '0' && ch <= '9':
+			digits = append(digits, ch)
+		case ch == ' ' || ch == '(' || ch == ')' || ch == '-' || ch == '.':
+		default:
+			return "", fmt.Errorf("%w: unexpected character %q in %q", ErrInvalidPhone, ch, phone)
+		}
+	}
+
+	switch {
+	case len(digits) == 11 && digits[0] == '7':
+		return string(digits), nil
+	case !international && len(digits) == 11 && digits[0] == '8':
+		digits[0] = '7'
+		return string(digits), nil
+	case !international && len(digits) == 10 && digits[0] == '9':
+		return "7" + string(digits), nil
+	case international && len(digits) >= 8 && len(digits) <= 15 && digits[0] != '7':
+		return string(digits), nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrInvalidPhone, phone)
+}
+
+// normalizePhoneField нормализует номер телефона из поля field запроса. Пустой номер не меняется,
+// некорректный возвращается как *ValidationError
+func normalizePhoneField(field, phone string) (string, error) {
+	if strings.TrimSpace(phone) == "" {
+		return phone, nil
+	}
+
+	normalized, err := NormalizePhone(phone)
+	if err != nil {
+		return "", &ValidationError{Fields: map[string]string{field: "invalid phone number"}, err: err}
+	}
+
+	return normalized, nil
+}
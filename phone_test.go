@@ -0,0 +1,82 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		phone string
+		want  string
+		err   bool
+	}{
+		{name: "canonical", phone: "79991234567", want: "79991234567"},
+		{name: "plus_seven", phone: "+79991234567", want: "79991234567"},
+		{name: "eight", phone: "89991234567", want: "79991234567"},
+		{name: "without_code", phone: "9991234567", want: "79991234567"},
+		{name: "spaces", phone: " +7 999 123 45 67 ", want: "79991234567"},
+		{name: "parentheses", phone: "8 (999) 123-45-67", want: "79991234567"},
+		{name: "dots", phone: "+7.999.123.45.67", want: "79991234567"},
+		{name: "international", phone: "+375 29 123-45-67", want: "375291234567"},
+		{name: "plus_seven_short", phone: "+7999123456", err: true},
+		{name: "plus_only", phone: "+", err: true},
+		{name: "short", phone: "123456", err: true},
+		{name: "letters", phone: "+7999ABC4567", err: true},
+		{name: "empty", phone: "", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := comarch.NormalizePhone(tt.phone)
+			if tt.err {
+				assert.True(t, errors.Is(err, comarch.ErrInvalidPhone))
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, phone)
+		})
+	}
+}
+
+func TestClient_NormalizesPhone(t *testing.T) {
+	var loginPhone, resetPhone, mobilePhone string
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			loginPhone = r.URL.Query().Get("phoneNo")
+			tokenHandler(w, r)
+		case "/cwaapiinterface/common/passresetting":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			resetPhone = body["phoneNo"]
+		case "/cwaapiinterface/resources/cardholders":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			mobilePhone, _ = body["mobilePhone"].(string)
+		}
+	})
+
+	_, err := c.SignInByPhoneOnly("8 (999) 000-11-22")
+	assert.Nil(t, err)
+	assert.Equal(t, "79990001122", loginPhone)
+
+	assert.Nil(t, c.ResetPasswordByPhoneNo("+7 999 000 11 22"))
+	assert.Equal(t, "79990001122", resetPhone)
+
+	assert.Nil(t, c.UpdateCardHolder(testAccessToken, comarch.PersonalData{MobilePhone: "9990001122"}))
+	assert.Equal(t, "79990001122", mobilePhone)
+
+	_, err = c.SignInByPhone("12-34", testCredentialsPassword)
+	var validationErr *comarch.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Contains(t, validationErr.Fields, "phoneNo")
+	}
+	assert.True(t, errors.Is(err, comarch.ErrInvalidPhone))
+}
@@ -0,0 +1,54 @@
+package comarch
+
+import "time"
+
+// ValuedPoints лот экспресс-баллов с рублевой стоимостью и разобранными датами
+type ValuedPoints struct {
+	// кол-во баллов
+	Points int
+	// стоимость баллов в рублях по BalanceRate. 0 если курс неизвестен, см. Valued
+	Rubles float64
+	// известен ли курс баллов. false если комарх не прислал BalanceRate или прислал 0
+	Valued bool
+	// дата начисления, нулевое время если комарх ее не прислал или прислал в неизвестном формате
+	IssueDate time.Time
+	// дата сгорания, нулевое время для несгораемых баллов и дат в неизвестном формате
+	ExpiryDate time.Time
+}
+
+// ValuedExpressPoints возвращает лоты экспресс-баллов с рублевой стоимостью и датами начисления и сгорания.
+// BalanceRate считается количеством баллов за один рубль. Даты разбираются в часовом поясе WithLocation клиента,
+// который получил баланс, а для BalanceInfoResp, собранного вручную, в time.Local. Отсутствующий курс и
+// неразборчивые даты не считаются ошибкой: соответствующие поля остаются нулевыми.
+func (b BalanceInfoResp) ValuedExpressPoints() []ValuedPoints {
+	loc := b.location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	rate := b.BalanceInfo.BalanceRate
+
+	res := make([]ValuedPoints, 0, len(b.ExpressPoints))
+	for _, points := range b.ExpressPoints {
+		valued := ValuedPoints{
+			Points: points.Points,
+			Valued: rate > 0,
+		}
+
+		if valued.Valued {
+			valued.Rubles = float64(points.Points) / float64(rate)
+		}
+
+		if issue, err := parseDateTime(points.IssueDate, loc); err == nil {
+			valued.IssueDate = issue
+		}
+
+		if expiry, err := parseDateTime(points.ExpiryDate, loc); err == nil {
+			valued.ExpiryDate = expiry
+		}
+
+		res = append(res, valued)
+	}
+
+	return res
+}
@@ -0,0 +1,50 @@
+package comarch_test
+
+import (
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBalanceInfoResp_ValuedExpressPoints(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"balanceInfo":{"balance":150,"balanceID":1,"balanceRate":10},
+			"expressPoints":[
+				{"points":100,"issueDate":"2020-03-01 10:00","expiryDate":"2020-04-01 10:00"},
+				{"points":50,"issueDate":"","expiryDate":"never"}
+			]
+		}`))
+	}, comarch.WithLocation(loc))
+
+	balance, err := c.GetBalanceInfo(testAccessToken)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []comarch.ValuedPoints{
+		{
+			Points:     100,
+			Rubles:     10,
+			Valued:     true,
+			IssueDate:  time.Date(2020, 3, 1, 10, 0, 0, 0, loc),
+			ExpiryDate: time.Date(2020, 4, 1, 10, 0, 0, 0, loc),
+		},
+		{Points: 50, Rubles: 5, Valued: true},
+	}, balance.ValuedExpressPoints())
+}
+
+func TestBalanceInfoResp_ValuedExpressPoints_NoRate(t *testing.T) {
+	balance := comarch.BalanceInfoResp{
+		ExpressPoints: []comarch.ExpressPoints{{Points: 30, ExpiryDate: "2020-04-01 10:00"}},
+	}
+
+	valued := balance.ValuedExpressPoints()
+	if assert.Len(t, valued, 1) {
+		assert.False(t, valued[0].Valued)
+		assert.Zero(t, valued[0].Rubles)
+		assert.Equal(t, time.Date(2020, 4, 1, 10, 0, 0, 0, time.Local), valued[0].ExpiryDate)
+	}
+	assert.Empty(t, comarch.BalanceInfoResp{}.ValuedExpressPoints())
+}
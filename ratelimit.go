@@ -0,0 +1,93 @@
+package comarch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter token bucket: пополняется со скоростью rate токенов в секунду, вмещает не больше burst токенов.
+// Каждый запрос забирает один токен, при пустом ведре ждет его пополнения.
+type rateLimiter struct {
+	rate  float64
+	burst int
+	// источник текущего времени, в тестах подменяется
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   rate,
+		burst:  burst,
+		now:    time.Now,
+		tokens: float64(burst),
+	}
+}
+
+// reserve забирает токен и возвращает, сколько нужно подождать до его появления.
+// Число токенов может уйти в минус: это очередь ожидающих запросов.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel возвращает токен, который так и не был использован
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
+
+// wait ждет свободный токен. При отмене ctx возвращает ctx.Err() и освобождает зарезервированный токен.
+// Возвращает время ожидания: зарезервированную задержку или, при отмене, фактически прошедшее время.
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+
+	delay := l.reserve(l.now())
+	if delay == 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		l.cancel()
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// waitRateLimit ждет разрешения лимитера WithRateLimit перед отправкой запроса и сообщает время ожидания в метрики
+func (c *Client) waitRateLimit(operation string, req *http.Request) error {
+	if c.limiter == nil {
+		return nil
+	}
+
+	waited, err := c.limiter.wait(req.Context())
+	c.metrics.ObserveLimiterWait(operation, waited)
+
+	return err
+}
@@ -0,0 +1,53 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type limiterMetrics struct {
+	comarch.NopMetrics
+
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (m *limiterMetrics) ObserveLimiterWait(operation string, wait time.Duration) {
+	m.mu.Lock()
+	m.waits = append(m.waits, wait)
+	m.mu.Unlock()
+}
+
+func TestWithRateLimit(t *testing.T) {
+	metrics := &limiterMetrics{}
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {},
+		comarch.WithRateLimit(20, 1),
+		comarch.WithMetrics(metrics),
+	)
+	now := time.Now()
+	comarch.SetLimiterClock(c, func() time.Time { return now })
+
+	assert.Nil(t, c.SignOut(testAccessToken))
+	assert.Nil(t, c.SignOut(testAccessToken))
+
+	if assert.Len(t, metrics.waits, 2) {
+		assert.Equal(t, time.Duration(0), metrics.waits[0])
+		assert.Equal(t, time.Millisecond*50, metrics.waits[1], "wait %s", metrics.waits[1])
+	}
+
+	// ожидание прерывается отменой контекста
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*5)
+	defer cancel()
+
+	err := c.SignOutContext(ctx, testAccessToken)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithRateLimit(0, 1))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
@@ -0,0 +1,80 @@
+package comarch
+
+import (
+	"net/http"
+	"time"
+)
+
+// ReferralInfo реферальный код участника и статистика приглашений
+type ReferralInfo struct {
+	// реферальный код участника
+	Code string
+	// всего приглашенных по коду
+	Count int
+	// приглашенные, которые еще не выполнили условия программы
+	Pending int
+	// приглашенные, выполнившие условия программы
+	Converted int
+	// дата подключения участника к программе и дата последнего засчитанного приглашения в часовом поясе
+	// WithLocation. Нулевое время, если комарх не вернул дату
+	EnrolledAt      time.Time
+	LastConvertedAt time.Time
+}
+
+type referralInfo struct {
+	Code            string  `json:"code"`
+	Count           flexInt `json:"count"`
+	Pending         flexInt `json:"pending"`
+	Converted       flexInt `json:"converted"`
+	EnrolledAt      string  `json:"enrolledAt"`
+	LastConvertedAt string  `json:"lastConvertedAt"`
+}
+
+// GetReferralInfo получает реферальный код участника и статистику приглашений. Если участник не подключен
+// к реферальной программе, возвращается ошибка, соответствующая ErrNotFound.
+func (c *Client) GetReferralInfo(accessToken AccessToken, opts ...CallOption) (*ReferralInfo, error) {
+	u := c.endpoint("/resources/referral")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetReferralInfo", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var raw referralInfo
+	if err := c.decodeResponse(resp, &raw); err != nil {
+		return nil, err
+	}
+
+	enrolledAt, err := parseDateTime(raw.EnrolledAt, c.location)
+	if err != nil {
+		return nil, err
+	}
+
+	lastConvertedAt, err := parseDateTime(raw.LastConvertedAt, c.location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReferralInfo{
+		Code:            raw.Code,
+		Count:           int(raw.Count),
+		Pending:         int(raw.Pending),
+		Converted:       int(raw.Converted),
+		EnrolledAt:      enrolledAt,
+		LastConvertedAt: lastConvertedAt,
+	}, nil
+}
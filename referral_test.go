@@ -0,0 +1,37 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_GetReferralInfo(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/referral", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"code":"FRIEND42","count":5,"pending":"2","converted":3,"enrolledAt":"2020-01-15 10:30","lastConvertedAt":""}`))
+	})
+
+	info, err := c.GetReferralInfo(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, &comarch.ReferralInfo{
+		Code:       "FRIEND42",
+		Count:      5,
+		Pending:    2,
+		Converted:  3,
+		EnrolledAt: time.Date(2020, 1, 15, 10, 30, 0, 0, time.Local),
+	}, info)
+}
+
+func TestClient_GetReferralInfo_NotEnrolled(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := c.GetReferralInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrNotFound))
+}
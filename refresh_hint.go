@@ -0,0 +1,53 @@
+package comarch
+
+import (
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// TimeToLive возвращает оставшееся время жизни токена, отрицательное для истекшего.
+// Для токена без ExpiresAt возвращает 0.
+func (t AccessToken) TimeToLive() time.Duration {
+	if t.ExpiresAt.IsZero() {
+		return 0
+	}
+
+	return time.Until(t.ExpiresAt)
+}
+
+// lifetime возвращает полное время жизни токена или 0, если оно неизвестно
+func (t AccessToken) lifetime() time.Duration {
+	if t.IssuedAt.IsZero() || t.ExpiresAt.IsZero() {
+		return 0
+	}
+
+	return t.ExpiresAt.Sub(t.IssuedAt)
+}
+
+// checkRefreshHint сообщает, что токен запроса прожил больше доли жизни из WithRefreshHint
+func (c *Client) checkRefreshHint(req *http.Request, accessToken AccessToken) {
+	if c.refreshHintThreshold <= 0 {
+		return
+	}
+
+	lifetime := accessToken.lifetime()
+	if lifetime <= 0 {
+		return
+	}
+
+	ttl := accessToken.TimeToLive()
+	if float64(ttl) >= float64(lifetime)*c.refreshHintThreshold {
+		return
+	}
+
+	if c.onRefreshHint != nil {
+		c.onRefreshHint(accessToken, ttl)
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"url": req.URL.Path,
+		"ttl": ttl,
+	}).Debug("Comarch token should be refreshed soon")
+}
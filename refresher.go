@@ -0,0 +1,163 @@
+package comarch
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// minRefreshDelay минимальная задержка между обновлениями токена. Не дает обновлять токен без паузы, если interval
+// не задан, токен уже истек или комарх выдает токены с нулевым сроком жизни
+const minRefreshDelay = time.Second
+
+// TokenRefresher фоновое обновление токена, см. StartTokenRefresher
+type TokenRefresher struct {
+	client   *Client
+	interval time.Duration
+
+	mu    sync.Mutex
+	token AccessToken
+	err   error
+
+	updates chan AccessToken
+	done    chan struct{}
+}
+
+// StartTokenRefresher запускает горутину, которая получает новый токен через Reauthenticate каждые interval,
+// а если токен истекает раньше, то до истечения, пока осталось не меньше 10% его жизни. К задержке добавляется
+// случайный джиттер до 10%, чтобы несколько процессов не обновляли токены одновременно. При ошибке обновление
+// повторяется с задержкой WithBackoffStrategy. Задержка между попытками не меньше секунды, в том числе при
+// interval <= 0 и для истекшего токена. Ограничения Reauthenticate (WithReauthentication для входа
+// по паролю) действуют и здесь.
+//
+// Горутина завершается при отмене ctx, который прерывает и выполняющееся обновление, или если токен нельзя
+// обновить (ErrReauthenticationUnavailable, см. Err). После этого закрывается канал Updates.
+func (c *Client) StartTokenRefresher(ctx context.Context, accessToken AccessToken, interval time.Duration) *TokenRefresher {
+	r := &TokenRefresher{
+		client:   c,
+		interval: interval,
+		token:    accessToken,
+		updates:  make(chan AccessToken, 1),
+		done:     make(chan struct{}),
+	}
+
+	go r.run(ctx)
+
+	return r
+}
+
+// Current возвращает актуальный токен
+func (r *TokenRefresher) Current() AccessToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.token
+}
+
+// Token реализует TokenProvider, чтобы TokenRefresher можно было передать в TokenTransport
+func (r *TokenRefresher) Token(context.Context) (AccessToken, error) {
+	return r.Current(), nil
+}
+
+// Err возвращает ошибку последней попытки обновления или nil, если она была успешной
+func (r *TokenRefresher) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Updates возвращает канал с обновленными токенами. В канале хранится только последний токен:
+// если получатель не успевает читать, промежуточные токены пропускаются.
+func (r *TokenRefresher) Updates() <-chan AccessToken {
+	return r.updates
+}
+
+// Done закрывается после остановки горутины обновления
+func (r *TokenRefresher) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *TokenRefresher) run(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.updates)
+
+	failures := 0
+	for {
+		var delay time.Duration
+		if failures == 0 {
+			delay = r.nextDelay()
+		} else {
+			delay = r.client.backoff(failures, nil)
+		}
+		if delay < minRefreshDelay {
+			delay = minRefreshDelay
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		newToken, err := r.client.Reauthenticate(r.Current(), withCallContext(ctx))
+
+		r.mu.Lock()
+		r.err = err
+		if err == nil {
+			r.token = *newToken
+		}
+		r.mu.Unlock()
+
+		if err != nil {
+			r.client.log.WithFields(logrus.Fields{
+				"operation": "TokenRefresher",
+				"error":     err,
+			}).Warn("Comarch token refresh failed")
+
+			// повторы не помогут
+			if errors.Is(err, ErrReauthenticationUnavailable) {
+				return
+			}
+
+			failures++
+			continue
+		}
+
+		failures = 0
+		r.publish(*newToken)
+	}
+}
+
+// nextDelay возвращает задержку до следующего обновления с джиттером
+func (r *TokenRefresher) nextDelay() time.Duration {
+	delay := r.interval
+
+	token := r.Current()
+	if !token.ExpiresAt.IsZero() {
+		if beforeExpiry := token.TimeToLive() * 9 / 10; beforeExpiry < delay {
+			delay = beforeExpiry
+		}
+	}
+
+	if delay <= minRefreshDelay {
+		return minRefreshDelay
+	}
+
+	return delay - time.Duration(rand.Int63n(int64(delay/10)+1))
+}
+
+// publish заменяет токен в канале Updates новым
+func (r *TokenRefresher) publish(accessToken AccessToken) {
+	select {
+	case <-r.updates:
+	default:
+	}
+
+	r.updates <- accessToken
+}
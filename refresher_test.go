@@ -0,0 +1,109 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_StartTokenRefresher(t *testing.T) {
+	var logins int32
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&logins, 1)
+		if n == 2 {
+			// первое обновление падает и повторяется
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		tokenHandler(w, r)
+	}, comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration {
+		return time.Millisecond
+	}))
+
+	token, err := c.SignInByPhoneOnly("79990001122")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	refresher := c.StartTokenRefresher(ctx, *token, time.Millisecond*10)
+
+	select {
+	case updated := <-refresher.Updates():
+		assert.Equal(t, "token", updated.Value)
+		assert.True(t, updated.IssuedAt.After(token.IssuedAt))
+	case <-time.After(time.Second * 5):
+		t.Fatal("token is not refreshed")
+	}
+
+	assert.Nil(t, refresher.Err())
+	assert.True(t, atomic.LoadInt32(&logins) >= 3)
+
+	current, err := refresher.Token(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, refresher.Current(), current)
+
+	cancel()
+	select {
+	case <-refresher.Done():
+	case <-time.After(time.Second):
+		t.Fatal("refresher is not stopped")
+	}
+
+	// канал закрывается после остановки
+	for range refresher.Updates() {
+	}
+}
+
+func TestClient_StartTokenRefresher_MinDelay(t *testing.T) {
+	var logins int32
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&logins, 1)
+		tokenHandler(w, r)
+	})
+
+	token, err := c.SignInByPhoneOnly("79990001122")
+	assert.Nil(t, err)
+
+	// истекший токен и нулевой interval не приводят к обновлению без паузы
+	token.ExpiresAt = time.Now().Add(-time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	refresher := c.StartTokenRefresher(ctx, *token, 0)
+
+	time.Sleep(time.Millisecond * 300)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+
+	cancel()
+	<-refresher.Done()
+}
+
+func TestClient_StartTokenRefresher_Unavailable(t *testing.T) {
+	var logins int32
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&logins, 1)
+		tokenHandler(w, r)
+	})
+
+	// активацию карты повторить нельзя
+	token, err := c.ActivateCardNo(testCredentialsCardNo)
+	assert.Nil(t, err)
+
+	refresher := c.StartTokenRefresher(context.Background(), *token, time.Millisecond)
+
+	select {
+	case <-refresher.Done():
+	case <-time.After(time.Second * 5):
+		t.Fatal("refresher is not stopped")
+	}
+
+	assert.True(t, errors.Is(refresher.Err(), comarch.ErrReauthenticationUnavailable))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+}
+
+func TestTokenRefresher_TokenProvider(t *testing.T) {
+	var _ comarch.TokenProvider = &comarch.TokenRefresher{}
+}
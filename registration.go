@@ -0,0 +1,32 @@
+package comarch
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+// RegisterNewCardHolder регистрирует нового участника: активирует карту, полученным токеном создает
+// учетную запись с personalData и разлогинивает токен. Ошибки шагов оборачиваются с указанием шага,
+// исходная ошибка доступна через errors.Is/errors.As. Ошибка разлогина не считается ошибкой регистрации
+// и только пишется в лог.
+func (c *Client) RegisterNewCardHolder(cardNo string, personalData PersonalData, opts ...CallOption) error {
+	accessToken, err := c.ActivateCardNo(cardNo, opts...)
+	if err != nil {
+		return fmt.Errorf("Card activation failed: %w", err)
+	}
+
+	defer func() {
+		if err := c.SignOut(*accessToken, opts...); err != nil {
+			c.log.WithFields(logrus.Fields{
+				"operation": "RegisterNewCardHolder",
+				"error":     err,
+			}).Warn("Comarch sign out after registration failed")
+		}
+	}()
+
+	if err := c.CreateCardHolder(*accessToken, personalData, opts...); err != nil {
+		return fmt.Errorf("Card holder creation failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_RegisterNewCardHolder(t *testing.T) {
+	tests := []struct {
+		name        string
+		loginStatus int
+		createCode  int
+		calls       []string
+		failed      bool
+	}{
+		{
+			name:        "ok",
+			loginStatus: http.StatusOK,
+			createCode:  http.StatusOK,
+			calls:       []string{"/cwaapiinterface/login", "/cwaapiinterface/resources/cardholders", "/cwaapiinterface/logout"},
+		},
+		{
+			name:        "create_failed",
+			loginStatus: http.StatusOK,
+			createCode:  http.StatusBadRequest,
+			calls:       []string{"/cwaapiinterface/login", "/cwaapiinterface/resources/cardholders", "/cwaapiinterface/logout"},
+			failed:      true,
+		},
+		{
+			name:        "activation_failed",
+			loginStatus: http.StatusBadRequest,
+			calls:       []string{"/cwaapiinterface/login"},
+			failed:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.URL.Path)
+				switch r.URL.Path {
+				case "/cwaapiinterface/login":
+					assert.Equal(t, string(comarch.GrantTypeCardActivation), r.URL.Query().Get("grant_type"))
+					if tt.loginStatus != http.StatusOK {
+						w.WriteHeader(tt.loginStatus)
+						return
+					}
+					tokenHandler(w, r)
+				case "/cwaapiinterface/resources/cardholders":
+					assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+					w.WriteHeader(tt.createCode)
+				}
+			})
+
+			err := c.RegisterNewCardHolder(testCredentialsCardNo, comarch.PersonalData{Name: "Иван"})
+			assert.Equal(t, tt.calls, calls)
+			if tt.failed {
+				assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package comarch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// requestIDKey ключ контекста запроса с его внутренним идентификатором
+type requestIDKey struct{}
+
+// requestIDFallback счетчик для идентификаторов, если crypto/rand недоступен
+var requestIDFallback uint64
+
+// newRequestID возвращает короткий случайный идентификатор запроса
+func newRequestID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatUint(atomic.AddUint64(&requestIDFallback, 1), 16)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// withRequestID присваивает запросу новый идентификатор
+func (c *Client) withRequestID(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestIDKey{}, c.requestIDGenerator()))
+}
+
+// requestID возвращает идентификатор запроса, присвоенный в do, или пустую строку
+func requestID(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+
+	id, _ := req.Context().Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// requestError ошибка транспорта с идентификатором запроса. Текст и цепочка ошибок не меняются
+type requestError struct {
+	requestID string
+	err       error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// RequestID возвращает внутренний идентификатор запроса, завершившегося ошибкой err, как он записан
+// в поле request_id логов клиента. Пустая строка, если ошибка возникла до отправки запроса.
+func RequestID(err error) string {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RequestID
+	}
+
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.requestID
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package comarch_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_RequestID(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	ids := 0
+	c, err := comarch.New(logger, srv.URL, testUsername, testPassword, nil,
+		comarch.WithRetry(2),
+		comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration { return 0 }),
+		comarch.WithRequestIDGenerator(func() string {
+			ids++
+			return fmt.Sprintf("req-%d", ids)
+		}),
+	)
+	assert.Nil(t, err)
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+	assert.Equal(t, "req-1", comarch.RequestID(err))
+	assert.Equal(t, 2, calls)
+
+	// все строки лога вызова, включая повтор, с одним идентификатором
+	assert.Len(t, hook.AllEntries(), 3)
+	for _, entry := range hook.AllEntries() {
+		assert.Equal(t, "req-1", entry.Data["request_id"], entry.Message)
+	}
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.Equal(t, "req-2", comarch.RequestID(err))
+}
+
+func TestRequestID_TransportError(t *testing.T) {
+	c, err := comarch.New(log, "http://127.0.0.1:1", testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	_, err = c.GetBalanceInfo(testAccessToken)
+	assert.NotNil(t, err)
+	assert.Len(t, comarch.RequestID(err), 8)
+
+	assert.Empty(t, comarch.RequestID(errors.New("other")))
+}
@@ -0,0 +1,114 @@
+package comarch
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// начальная задержка перед повторной попыткой
+	retryBaseDelay = time.Millisecond * 100
+	// максимальная задержка перед повторной попыткой
+	retryMaxDelay = time.Second * 5
+)
+
+// retryDelay экспоненциальная задержка с полным джиттером: случайное значение от 0 до
+// retryBaseDelay * 2^(attempt-1), но не больше retryMaxDelay
+func retryDelay(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 32 {
+		if d := retryBaseDelay << uint(attempt-1); d > 0 && d < retryMaxDelay {
+			delay = d
+		}
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// BackoffStrategy возвращает задержку перед повторной попыткой. attempt номер завершившейся неудачной попытки,
+// resp ее ответ или nil при ошибке транспорта. Тело ответа к этому моменту уже закрыто, доступны только
+// статус и заголовки.
+type BackoffStrategy func(attempt int, resp *http.Response) time.Duration
+
+// defaultBackoff стратегия по умолчанию, см. retryDelay
+func defaultBackoff(attempt int, _ *http.Response) time.Duration {
+	return retryDelay(attempt)
+}
+
+// RetryClassifier решает, повторять ли попытку запроса. resp ответ попытки или nil при ошибке транспорта err.
+// Тело ответа читать нельзя: при повторе его прочитает клиент для ошибки попытки.
+type RetryClassifier func(resp *http.Response, err error) bool
+
+// DefaultRetryClassifier классификатор по умолчанию: повторяются ошибки транспорта и ответы 5xx.
+// Удобен для расширения в WithRetryClassifier, например чтобы дополнительно повторять 409
+func DefaultRetryClassifier(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// RetryAfter разбирает заголовок Retry-After ответа в секундах или в формате http даты.
+// Возвращает false, если заголовка нет или его не удалось разобрать. Предназначен для BackoffStrategy.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if d := time.Until(at); d > 0 {
+		return d, true
+	}
+
+	return 0, true
+}
+
+// waitRetry ждет перед повторной попыткой и готовит тело запроса к повторной отправке
+func (c *Client) waitRetry(req *http.Request, resp *http.Response, attempt int) error {
+	timer := time.NewTimer(c.backoff(attempt, resp))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+
+		req.Body = body
+	}
+
+	return nil
+}
+
+// firstAttemptRequest ограничивает первую попытку долей WithFirstAttemptShare времени, оставшегося до дедлайна
+// контекста запроса. Без дедлайна или с долей 1 возвращает исходный запрос
+func (c *Client) firstAttemptRequest(req *http.Request) (*http.Request, context.CancelFunc) {
+	deadline, ok := req.Context().Deadline()
+	if !ok || c.firstAttemptShare >= 1 {
+		return req, func() {}
+	}
+
+	budget := time.Until(deadline)
+	ctx, cancel := context.WithTimeout(req.Context(), time.Duration(float64(budget)*c.firstAttemptShare))
+
+	return req.WithContext(ctx), cancel
+}
@@ -0,0 +1,273 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type retryMetrics struct {
+	comarch.NopMetrics
+
+	operation string
+	outcome   comarch.RetryOutcome
+	retries   int
+}
+
+func (m *retryMetrics) ObserveRetries(operation string, outcome comarch.RetryOutcome, retries int) {
+	m.operation, m.outcome, m.retries = operation, outcome, retries
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		outcome  comarch.RetryOutcome
+		retries  int
+	}{
+		{name: "first_attempt", failures: 0, outcome: comarch.RetryOutcomeSuccess, retries: 0},
+		{name: "after_retries", failures: 2, outcome: comarch.RetryOutcomeSuccess, retries: 2},
+		{name: "exhausted", failures: 5, outcome: comarch.RetryOutcomeFailure, retries: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var attempts []int
+			metrics := &retryMetrics{}
+
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				tokenHandler(w, r)
+			},
+				comarch.WithRetry(3),
+				comarch.WithMetrics(metrics),
+				comarch.WithOnRetry(func(operation string, attempt int, err error) {
+					assert.Equal(t, "SignInByCard", operation)
+					assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+					attempts = append(attempts, attempt)
+				}),
+			)
+
+			_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			if tt.outcome == comarch.RetryOutcomeSuccess {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+			}
+
+			assert.Len(t, attempts, tt.retries)
+			assert.Equal(t, "SignInByCard", metrics.operation)
+			assert.Equal(t, tt.outcome, metrics.outcome)
+			assert.Equal(t, tt.retries, metrics.retries)
+		})
+	}
+}
+
+func TestWithBackoffStrategy(t *testing.T) {
+	calls := 0
+	var delays []time.Duration
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		tokenHandler(w, r)
+	},
+		comarch.WithRetry(2),
+		comarch.WithBackoffStrategy(func(attempt int, resp *http.Response) time.Duration {
+			assert.Equal(t, 1, attempt)
+			if assert.NotNil(t, resp) {
+				assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+			}
+
+			delay, ok := comarch.RetryAfter(resp)
+			assert.True(t, ok)
+			delays = append(delays, delay)
+
+			return delay
+		}),
+	)
+
+	_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Duration{0}, delays)
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithBackoffStrategy(nil))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{name: "empty", value: "", delay: 0, ok: false},
+		{name: "seconds", value: "120", delay: time.Minute * 2, ok: true},
+		{name: "past_date", value: "Wed, 21 Oct 2015 07:28:00 GMT", delay: 0, ok: true},
+		{name: "garbage", value: "soon", delay: 0, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+
+			delay, ok := comarch.RetryAfter(resp)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.delay, delay)
+		})
+	}
+}
+
+func TestClient_Retry_ReplaysBody(t *testing.T) {
+	var bodies []string
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		bodies = append(bodies, string(body))
+
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}, comarch.WithRetry(2), comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration {
+		return 0
+	}))
+
+	assert.Nil(t, c.ChangePassword(testAccessToken, "old", "new"))
+	if assert.Len(t, bodies, 2) {
+		assert.JSONEq(t, `{"oldPass":"old","newPass":"new"}`, bodies[0])
+		assert.Equal(t, bodies[0], bodies[1])
+	}
+}
+
+func TestWithFirstAttemptShare(t *testing.T) {
+	tests := []struct {
+		name  string
+		share float64
+		ok    bool
+	}{
+		{name: "full_budget", share: 1, ok: false},
+		{name: "half_budget", share: 0.5, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				calls++
+				first := calls == 1
+				mu.Unlock()
+				if first {
+					// первая попытка висит дольше общего таймаута
+					select {
+					case <-r.Context().Done():
+					case <-time.After(time.Second):
+					}
+					return
+				}
+				w.Write([]byte(`{}`))
+			},
+				comarch.WithRetry(2),
+				comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration { return 0 }),
+				comarch.WithOperationTimeout("GetBalanceInfo", time.Millisecond*300),
+				comarch.WithFirstAttemptShare(tt.share),
+			)
+
+			_, err := c.GetBalanceInfo(testAccessToken)
+			if tt.ok {
+				assert.Nil(t, err)
+			} else {
+				// первая попытка съела весь бюджет, на повтор времени не осталось
+				assert.NotNil(t, err)
+			}
+		})
+	}
+
+	for _, share := range []float64{0, -0.5, 1.5} {
+		_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithFirstAttemptShare(share))
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+	}
+}
+
+func TestWithRetryClassifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		classifier comarch.RetryClassifier
+		calls      int
+	}{
+		{name: "default_skips_409", status: http.StatusConflict, calls: 1},
+		{name: "default_retries_502", status: http.StatusBadGateway, calls: 2},
+		{
+			name:   "retry_409",
+			status: http.StatusConflict,
+			classifier: func(resp *http.Response, err error) bool {
+				return comarch.DefaultRetryClassifier(resp, err) || resp.StatusCode == http.StatusConflict
+			},
+			calls: 2,
+		},
+		{
+			name:   "skip_502",
+			status: http.StatusBadGateway,
+			classifier: func(resp *http.Response, err error) bool {
+				return err != nil
+			},
+			calls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			opts := []comarch.Option{
+				comarch.WithRetry(2),
+				comarch.WithBackoffStrategy(func(int, *http.Response) time.Duration { return 0 }),
+			}
+			if tt.classifier != nil {
+				opts = append(opts, comarch.WithRetryClassifier(tt.classifier))
+			}
+
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				tokenHandler(w, r)
+			}, opts...)
+
+			_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			assert.Equal(t, tt.calls, calls)
+			if tt.calls == 2 {
+				assert.Nil(t, err)
+			} else {
+				var httpErr *comarch.HTTPError
+				if assert.True(t, errors.As(err, &httpErr)) {
+					assert.Equal(t, tt.status, httpErr.StatusCode)
+				}
+			}
+		})
+	}
+
+	_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithRetryClassifier(nil))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
@@ -0,0 +1,115 @@
+package comarch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenProvider возвращает токен пользователя для очередного запроса. Реализация отвечает за обновление токена
+type TokenProvider interface {
+	Token(ctx context.Context) (AccessToken, error)
+}
+
+// TokenProviderFunc функция, реализующая TokenProvider
+type TokenProviderFunc func(ctx context.Context) (AccessToken, error)
+
+func (f TokenProviderFunc) Token(ctx context.Context) (AccessToken, error) {
+	return f(ctx)
+}
+
+// reauthenticatingTokenProvider отдает сохраненный токен и получает новый через Reauthenticate, когда он истекает
+type reauthenticatingTokenProvider struct {
+	client *Client
+
+	mu    sync.Mutex
+	token AccessToken
+}
+
+// ReauthenticatingTokenProvider возвращает TokenProvider, который отдает accessToken, пока он не истек,
+// а затем получает новый через Reauthenticate. Токен без ExpiresAt считается бессрочным.
+// Ограничения Reauthenticate (WithReauthentication для входа по паролю) действуют и здесь.
+func (c *Client) ReauthenticatingTokenProvider(accessToken AccessToken) TokenProvider {
+	return &reauthenticatingTokenProvider{client: c, token: accessToken}
+}
+
+func (p *reauthenticatingTokenProvider) Token(ctx context.Context) (AccessToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token.ExpiresAt.IsZero() || time.Now().Before(p.token.ExpiresAt) {
+		return p.token, nil
+	}
+
+	p.client.emit(EventTokenExpired, "ReauthenticatingTokenProvider", tokenSubject(p.token), nil)
+
+	// отмена запроса прерывает и обновление токена
+	newToken, err := p.client.Reauthenticate(p.token, withCallContext(ctx))
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	p.token = *newToken
+
+	return p.token, nil
+}
+
+// authTransport http.RoundTripper, добавляющий в запросы авторизацию комарха
+type authTransport struct {
+	client *Client
+	base   http.RoundTripper
+	// nil для Basic Auth приложения
+	tokens TokenProvider
+}
+
+// BasicAuthTransport возвращает http.RoundTripper, который добавляет в каждый запрос логин и пароль приложения,
+// как это делают методы клиента для /cwaapiinterface/common. base nil означает http.DefaultTransport.
+// Клиенту без логина и пароля транспорт возвращает ErrCredentialsRequired.
+func (c *Client) BasicAuthTransport(base http.RoundTripper) http.RoundTripper {
+	return &authTransport{client: c, base: base}
+}
+
+// TokenTransport возвращает http.RoundTripper, который добавляет в каждый запрос токен пользователя из tokens
+// и куки его сессии (с учетом WithSendCookies). base nil означает http.DefaultTransport.
+// Транспорт позволяет обращаться к методам комарха, которых нет в клиенте, с той же авторизацией.
+func (c *Client) TokenTransport(base http.RoundTripper, tokens TokenProvider) http.RoundTripper {
+	return &authTransport{client: c, base: base, tokens: tokens}
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper не должен менять исходный запрос
+	authReq := req.Clone(req.Context())
+
+	if t.tokens == nil {
+		if err := t.client.basicAuth("", authReq); err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+	} else {
+		accessToken, err := t.tokens.Token(req.Context())
+		if err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+
+		if err := t.client.authorize(authReq, accessToken); err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(authReq)
+}
+
+// closeRequestBody закрывает тело запроса, как того требует контракт http.RoundTripper при ошибке
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,124 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_TokenTransport(t *testing.T) {
+	var logins int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/login" {
+			logins++
+			tokenHandler(w, r)
+			return
+		}
+
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		cookie, err := r.Cookie("JSESSIONID")
+		if assert.Nil(t, err) {
+			assert.Equal(t, "session", cookie.Value)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	token, err := c.SignInByPhoneOnly("79990001122")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, logins)
+
+	// истекший токен обновляется перед запросом
+	token.ExpiresAt = time.Now().Add(-time.Minute)
+	httpClient := &http.Client{Transport: c.TokenTransport(nil, c.ReauthenticatingTokenProvider(*token))}
+
+	req, err := http.NewRequest("GET", srv.URL+"/cwaapiinterface/resources/unknown", nil)
+	assert.Nil(t, err)
+
+	resp, err := httpClient.Do(req)
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+	}
+	assert.Equal(t, 2, logins)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	// обновленный токен переиспользуется
+	resp, err = httpClient.Get(srv.URL + "/cwaapiinterface/resources/unknown")
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+	}
+	assert.Equal(t, 2, logins)
+
+	failing := comarch.TokenProviderFunc(func(context.Context) (comarch.AccessToken, error) {
+		return comarch.AccessToken{}, comarch.ErrReauthenticationUnavailable
+	})
+	_, err = (&http.Client{Transport: c.TokenTransport(nil, failing)}).Get(srv.URL)
+	assert.True(t, errors.Is(err, comarch.ErrReauthenticationUnavailable))
+}
+
+func TestClient_BasicAuthTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, testUsername, username)
+		assert.Equal(t, testPassword, password)
+	}))
+	defer srv.Close()
+
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	resp, err := (&http.Client{Transport: c.BasicAuthTransport(nil)}).Get(srv.URL + "/cwaapiinterface/common/unknown")
+	if assert.Nil(t, err) {
+		resp.Body.Close()
+	}
+}
+
+func TestReauthenticatingTokenProvider_Context(t *testing.T) {
+	release := make(chan struct{})
+	logins := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		if logins > 1 {
+			// обновление токена висит
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		tokenHandler(w, r)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil)
+	assert.Nil(t, err)
+
+	token, err := c.SignInByPhoneOnly("79990001122")
+	assert.Nil(t, err)
+	token.ExpiresAt = time.Now().Add(-time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.ReauthenticatingTokenProvider(*token).Token(ctx)
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	case <-time.After(time.Second * 2):
+		t.Fatal("refresh is not interrupted by the context")
+	}
+}
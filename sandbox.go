@@ -0,0 +1,67 @@
+package comarch
+
+import (
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Переменные окружения с адресом и учетными данными приложения в песочнице комарха для NewSandbox.
+// Задаются один раз в окружении разработчика
+const (
+	SandboxBasePathEnv = "COMARCH_SANDBOX_URL"
+	SandboxLoginEnv    = "COMARCH_SANDBOX_LOGIN"
+	SandboxPasswordEnv = "COMARCH_SANDBOX_PASSWORD"
+)
+
+// sandboxHostMarkers части имени хоста, по которым адрес считается тестовым стендом
+var sandboxHostMarkers = []string{"sandbox", "demo", "test", "stage", "staging", "dev", "localhost"}
+
+// NewSandbox создает клиент для песочницы комарха. Адрес и учетные данные приложения берутся из
+// переменных окружения SandboxBasePathEnv, SandboxLoginEnv и SandboxPasswordEnv, остальные настройки
+// задаются opts так же, как в New (учетные данные можно заменить через WithCredentialProvider).
+// Клиент пишет в stderr логгер logrus по умолчанию. Если адрес похож на рабочий комарх (в имени хоста нет
+// sandbox, demo, test, stage, dev и это не локальный или частный адрес), в лог пишется предупреждение.
+func NewSandbox(opts ...Option) (*Client, error) {
+	basePath := strings.TrimSpace(os.Getenv(SandboxBasePathEnv))
+	if basePath == "" {
+		return nil, &ConfigError{Field: SandboxBasePathEnv, Reason: "must be set to the sandbox base path"}
+	}
+
+	log := logrus.New()
+
+	c, err := New(log, basePath, os.Getenv(SandboxLoginEnv), os.Getenv(SandboxPasswordEnv), nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isSandboxURL(basePath) {
+		log.WithField("base_path", basePath).Warn("Comarch sandbox client points to a production-looking address")
+	}
+
+	return c, nil
+}
+
+// isSandboxURL проверяет, что адрес похож на тестовый стенд: имя хоста содержит один из sandboxHostMarkers
+// или хост локальный либо из частной сети
+func isSandboxURL(basePath string) bool {
+	u, err := url.Parse(basePath)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate()
+	}
+
+	for _, marker := range sandboxHostMarkers {
+		if strings.Contains(host, marker) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func setSandboxEnv(t *testing.T, basePath, login, password string) {
+	for key, value := range map[string]string{
+		comarch.SandboxBasePathEnv: basePath,
+		comarch.SandboxLoginEnv:    login,
+		comarch.SandboxPasswordEnv: password,
+	} {
+		old, ok := os.LookupEnv(key)
+		_ = os.Setenv(key, value)
+
+		key := key
+		t.Cleanup(func() {
+			if ok {
+				_ = os.Setenv(key, old)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+func TestNewSandbox(t *testing.T) {
+	t.Run("from_env", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "demo", username)
+			assert.Equal(t, "demo-password", password)
+
+			tokenHandler(w, r)
+		}))
+		defer srv.Close()
+
+		setSandboxEnv(t, srv.URL, "demo", "demo-password")
+
+		c, err := comarch.NewSandbox(comarch.WithRetry(2))
+		if assert.Nil(t, err) {
+			token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+			assert.Nil(t, err)
+			assert.NotNil(t, token)
+		}
+	})
+
+	t.Run("missing_base_path", func(t *testing.T) {
+		setSandboxEnv(t, "", "demo", "demo-password")
+
+		_, err := comarch.NewSandbox()
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+	})
+
+	t.Run("invalid_base_path", func(t *testing.T) {
+		setSandboxEnv(t, "sandbox.local", "demo", "demo-password")
+
+		_, err := comarch.NewSandbox()
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+	})
+}
@@ -0,0 +1,70 @@
+package comarch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// HasScope проверяет, что токен выдан с правом scope
+func (t AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopeClaim права в формате OAuth: строка через пробел или массив строк. Значения других типов
+// игнорируются, чтобы незнакомый формат прав не ломал логин
+type scopeClaim []string
+
+func (s *scopeClaim) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = strings.Fields(str)
+	}
+
+	return nil
+}
+
+// jwtScopes возвращает права из payload токена, если токен JWT. Подпись не проверяется: права нужны
+// только для того, чтобы не вызывать недоступные методы, проверку выполняет комарх.
+// Для непрозрачных токенов и токенов без прав возвращает nil.
+func jwtScopes(token string) []string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+
+	var claims struct {
+		Scope scopeClaim `json:"scope"`
+		Scp   scopeClaim `json:"scp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+
+	if len(claims.Scope) > 0 {
+		return claims.Scope
+	}
+
+	if len(claims.Scp) > 0 {
+		return claims.Scp
+	}
+
+	return nil
+}
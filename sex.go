@@ -0,0 +1,28 @@
+package comarch
+
+import "strings"
+
+// Sex пол участника в анкете
+type Sex string
+
+const (
+	SexMale   Sex = "M"
+	SexFemale Sex = "F"
+)
+
+// Valid проверяет, что s одно из известных значений
+func (s Sex) Valid() bool {
+	return s == SexMale || s == SexFemale
+}
+
+// parseSex приводит значение пола от комарха к Sex без учета регистра. false для неизвестных значений
+func parseSex(value string) (Sex, bool) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "M", "MALE":
+		return SexMale, true
+	case "F", "FEMALE":
+		return SexFemale, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,68 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestPersonalData_Sex(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		sex  comarch.Sex
+		raw  string
+	}{
+		{name: "male", body: `{"sex":"M"}`, sex: comarch.SexMale},
+		{name: "female_lowercase", body: `{"sex":"f"}`, sex: comarch.SexFemale},
+		{name: "female_word", body: `{"sex":"FEMALE"}`, sex: comarch.SexFemale},
+		{name: "empty", body: `{"sex":""}`},
+		{name: "missing", body: `{}`},
+		{name: "unknown", body: `{"sex":"X"}`, raw: "X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated map[string]interface{}
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case "GET":
+					w.Write([]byte(tt.body))
+				case "POST":
+					json.NewDecoder(r.Body).Decode(&updated)
+				}
+			})
+
+			personalData, err := c.GetCardHolder(testAccessToken)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.sex, personalData.Sex)
+			assert.Equal(t, tt.raw, personalData.SexRaw)
+
+			// анкета сохраняется с тем же значением пола
+			assert.Nil(t, c.UpdateCardHolder(testAccessToken, *personalData))
+			expected := string(tt.sex)
+			if tt.raw != "" {
+				expected = tt.raw
+			}
+			assert.Equal(t, expected, updated["sex"])
+		})
+	}
+}
+
+func TestClient_UpdateCardHolder_InvalidSex(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request must not be sent")
+	})
+
+	err := c.UpdateCardHolder(testAccessToken, comarch.PersonalData{Sex: "male"})
+	var validationErr *comarch.ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Contains(t, validationErr.Fields, "sex")
+	}
+
+	err = c.CreateCardHolder(testAccessToken, comarch.PersonalData{Sex: "X"})
+	assert.True(t, errors.As(err, &validationErr))
+}
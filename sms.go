@@ -0,0 +1,43 @@
+package comarch
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// CancelSMSChallenge отменяет отправленный, но не подтвержденный код входа по SMS (authbysms) для номера
+// телефона phoneNo, чтобы брошенный вход нельзя было завершить позже. Если ожидающего кода нет (комарх
+// отвечает 404), отмена считается успешной. Использует учетные данные клиента, токен участника не нужен.
+func (c *Client) CancelSMSChallenge(phoneNo string, opts ...CallOption) error {
+	phoneNo, err := normalizePhoneField("phoneNo", phoneNo)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("phoneNo", phoneNo)
+
+	u := c.endpoint("/common/smschallenge") + "?" + params.Encode()
+
+	req, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.basicAuth("CancelSMSChallenge", req); err != nil {
+		return err
+	}
+
+	resp, err := c.do("CancelSMSChallenge", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_CancelSMSChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+	}{
+		{name: "cancelled", status: http.StatusOK, err: nil},
+		{name: "no_pending_challenge", status: http.StatusNotFound, err: nil},
+		{name: "server_error", status: http.StatusInternalServerError, err: comarch.ErrBadResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "DELETE", r.Method)
+				assert.Equal(t, "/cwaapiinterface/common/smschallenge", r.URL.Path)
+				assert.Equal(t, "79990001122", r.URL.Query().Get("phoneNo"))
+
+				username, password, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, testUsername, username)
+				assert.Equal(t, testPassword, password)
+
+				w.WriteHeader(tt.status)
+			})
+
+			err := c.CancelSMSChallenge("79990001122")
+			if tt.err == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.err))
+			}
+		})
+	}
+}
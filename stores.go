@@ -0,0 +1,153 @@
+package comarch
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GeoPoint координаты магазина
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Store магазин партнера программы лояльности
+type Store struct {
+	ID      string
+	Name    string
+	Address string
+	City    string
+	// координаты магазина, nil если комарх их не знает
+	Location *GeoPoint
+}
+
+type store struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Address   string   `json:"address"`
+	City      string   `json:"city"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// GetStores получает список магазинов, участвующих в программе лояльности. Токен участника не нужен.
+// Если city не пустой, возвращаются только магазины этого города.
+func (c *Client) GetStores(ctx context.Context, city string, opts ...CallOption) ([]Store, error) {
+	u := c.endpoint("/common/stores")
+	if city != "" {
+		params := url.Values{}
+		params.Set("city", city)
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.basicAuth("GetStores", req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetStores", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var rawStores []store
+	if err := c.decodeResponse(resp, &rawStores); err != nil {
+		return nil, err
+	}
+
+	stores := make([]Store, 0, len(rawStores))
+	for _, raw := range rawStores {
+		stores = append(stores, raw.toStore())
+	}
+
+	return stores, nil
+}
+
+func (s store) toStore() Store {
+	res := Store{
+		ID:      s.ID,
+		Name:    s.Name,
+		Address: s.Address,
+		City:    s.City,
+	}
+	if s.Latitude != nil && s.Longitude != nil {
+		res.Location = &GeoPoint{Latitude: *s.Latitude, Longitude: *s.Longitude}
+	}
+
+	return res
+}
+
+// GetPreferredStore получает домашний магазин участника. Если участник его не выбрал, возвращается nil без ошибки
+func (c *Client) GetPreferredStore(accessToken AccessToken, opts ...CallOption) (*Store, error) {
+	u := c.endpoint("/resources/cardholders/store")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do("GetPreferredStore", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var raw store
+	// комарх может ответить пустым телом
+	if _, err := c.decodeOptional(resp, &raw); err != nil {
+		return nil, err
+	}
+
+	if raw.ID == "" {
+		return nil, nil
+	}
+
+	s := raw.toStore()
+
+	return &s, nil
+}
+
+// SetPreferredStore выбирает домашний магазин участника. Для неизвестного магазина возвращается ошибка,
+// соответствующая ErrNotFound.
+func (c *Client) SetPreferredStore(accessToken AccessToken, storeID string, opts ...CallOption) error {
+	if storeID == "" {
+		return &ValidationError{Fields: map[string]string{"storeId": "must not be empty"}}
+	}
+
+	u := c.endpoint("/resources/cardholders/store")
+
+	req, err := c.newJSONRequest("PUT", u, map[string]string{"storeId": storeID})
+	if err != nil {
+		return err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("SetPreferredStore", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
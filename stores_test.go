@@ -0,0 +1,97 @@
+package comarch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_GetStores(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, testUsername, username)
+		assert.Equal(t, testPassword, password)
+		assert.Equal(t, "Москва", r.URL.Query().Get("city"))
+
+		w.Write([]byte(`[
+			{"id":"1","name":"Магазин","address":"ул. Ленина, 1","city":"Москва","latitude":55.75,"longitude":37.61},
+			{"id":"2","name":"Склад","city":"Москва"}
+		]`))
+	})
+
+	stores, err := c.GetStores(context.Background(), "Москва")
+	assert.Nil(t, err)
+	assert.Equal(t, []comarch.Store{
+		{ID: "1", Name: "Магазин", Address: "ул. Ленина, 1", City: "Москва", Location: &comarch.GeoPoint{Latitude: 55.75, Longitude: 37.61}},
+		{ID: "2", Name: "Склад", City: "Москва"},
+	}, stores)
+}
+
+func TestWithAnonymousOperations(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _, ok := r.BasicAuth()
+		if r.URL.Path == "/cwaapiinterface/common/stores" {
+			if ok {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`[]`))
+			return
+		}
+
+		assert.True(t, ok)
+		tokenHandler(w, r)
+	}, comarch.WithAnonymousOperations("GetStores"))
+
+	_, err := c.GetStores(context.Background(), "")
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+}
+
+func TestClient_PreferredStore(t *testing.T) {
+	preferred := ""
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/cardholders/store", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		switch r.Method {
+		case "GET":
+			if preferred != "" {
+				w.Write([]byte(`{"id":"` + preferred + `","name":"Магазин","city":"Москва"}`))
+			}
+		case "PUT":
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["storeId"] == "404" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			preferred = body["storeId"]
+		}
+	})
+
+	s, err := c.GetPreferredStore(testAccessToken)
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+
+	assert.Nil(t, c.SetPreferredStore(testAccessToken, "1"))
+
+	s, err = c.GetPreferredStore(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, &comarch.Store{ID: "1", Name: "Магазин", City: "Москва"}, s)
+
+	err = c.SetPreferredStore(testAccessToken, "404")
+	assert.True(t, errors.Is(err, comarch.ErrNotFound))
+
+	var validationErr *comarch.ValidationError
+	assert.True(t, errors.As(c.SetPreferredStore(testAccessToken, ""), &validationErr))
+}
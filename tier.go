@@ -0,0 +1,84 @@
+package comarch
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TierLevel уровень участника в программе лояльности
+type TierLevel string
+
+const (
+	TierBronze TierLevel = "BRONZE"
+	TierSilver TierLevel = "SILVER"
+	TierGold   TierLevel = "GOLD"
+)
+
+// Tier уровень участника с порогами и привилегиями. Поля, которые комарх не прислал, пустые
+type Tier struct {
+	// уровень. Для уровней вне констант Tier* приходит код комарха в верхнем регистре
+	Level TierLevel
+	// название уровня для показа участнику
+	Name string
+	// сумма покупок, с которой начинается уровень
+	Threshold int
+	// следующий уровень и сумма, с которой он начинается. Пустые для высшего уровня
+	NextLevel     TierLevel
+	NextThreshold int
+	// описания привилегий уровня
+	Benefits []string
+}
+
+// Golden проверяет, что участник на золотом уровне. Совпадает с признаком PersonalData.Golden из анкеты
+func (t Tier) Golden() bool {
+	return t.Level == TierGold
+}
+
+type tier struct {
+	Code          string   `json:"code"`
+	Name          string   `json:"name"`
+	Threshold     flexInt  `json:"threshold"`
+	NextCode      string   `json:"nextCode"`
+	NextThreshold flexInt  `json:"nextThreshold"`
+	Benefits      []string `json:"benefits"`
+}
+
+// GetMemberTier получает уровень участника в программе лояльности. Если инсталляция комарха не поддерживает
+// уровни, возвращается ошибка, соответствующая ErrNotFound, и уровень можно определить только по
+// PersonalData.Golden.
+func (c *Client) GetMemberTier(accessToken AccessToken, opts ...CallOption) (Tier, error) {
+	u := c.endpoint("/resources/cards/tier")
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return Tier{}, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return Tier{}, err
+	}
+
+	resp, err := c.do("GetMemberTier", req, opts...)
+	if err != nil {
+		return Tier{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return Tier{}, err
+	}
+
+	var raw tier
+	if err := c.decodeResponse(resp, &raw); err != nil {
+		return Tier{}, err
+	}
+
+	return Tier{
+		Level:         TierLevel(strings.ToUpper(raw.Code)),
+		Name:          raw.Name,
+		Threshold:     int(raw.Threshold),
+		NextLevel:     TierLevel(strings.ToUpper(raw.NextCode)),
+		NextThreshold: int(raw.NextThreshold),
+		Benefits:      raw.Benefits,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestClient_GetMemberTier(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/cards/tier", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"code":"silver","name":"Серебро","threshold":"5000","nextCode":"GOLD","nextThreshold":10000,"benefits":["5% cashback"]}`))
+	})
+
+	tier, err := c.GetMemberTier(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, comarch.Tier{
+		Level:         comarch.TierSilver,
+		Name:          "Серебро",
+		Threshold:     5000,
+		NextLevel:     comarch.TierGold,
+		NextThreshold: 10000,
+		Benefits:      []string{"5% cashback"},
+	}, tier)
+	assert.False(t, tier.Golden())
+	assert.True(t, comarch.Tier{Level: comarch.TierGold}.Golden())
+}
+
+func TestClient_GetMemberTier_NotSupported(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := c.GetMemberTier(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrNotFound))
+}
@@ -0,0 +1,18 @@
+package comarch
+
+import "context"
+
+type tokenContextKey struct{}
+
+// WithTokenContext возвращает копию ctx с токеном пользователя. Методы клиента, принимающие контекст,
+// используют этот токен, если им передан пустой AccessToken. Явно переданный токен всегда важнее.
+func WithTokenContext(ctx context.Context, accessToken AccessToken) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, accessToken)
+}
+
+// TokenFromContext возвращает токен, сохраненный в ctx через WithTokenContext
+func TokenFromContext(ctx context.Context) (AccessToken, bool) {
+	accessToken, ok := ctx.Value(tokenContextKey{}).(AccessToken)
+
+	return accessToken, ok
+}
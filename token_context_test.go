@@ -0,0 +1,35 @@
+package comarch_test
+
+import (
+	"context"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestTokenContext(t *testing.T) {
+	_, ok := comarch.TokenFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := comarch.WithTokenContext(context.Background(), comarch.AccessToken{Value: "from-context"})
+	token, ok := comarch.TokenFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "from-context", token.Value)
+
+	var auth []string
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Write([]byte(`{}`))
+	})
+
+	_, err := c.GetBalanceInfoContext(ctx, comarch.AccessToken{})
+	assert.Nil(t, err)
+	assert.Nil(t, c.SignOutContext(ctx, comarch.AccessToken{}))
+
+	// явно переданный токен важнее токена из контекста
+	_, err = c.GetBalanceInfoContext(ctx, testAccessToken)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"Bearer from-context", "Bearer from-context", "Bearer token"}, auth)
+}
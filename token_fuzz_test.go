@@ -0,0 +1,56 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"github.com/kazhuravlev/go-comarch"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// FuzzClient_ParseAccessToken разбор ответа на логин не паникует на произвольных телах и куках, а принятый
+// токен структурно корректен
+func FuzzClient_ParseAccessToken(f *testing.F) {
+	f.Add([]byte(testToken), "JSESSIONID=session")
+	f.Add([]byte(`{"access_token":"token","expires_in":9223372036854775807}`), "JSESSIONID=a\nJSESSIONID=b")
+	f.Add([]byte(`{"access_token":"token","expires_in":-9223372036854775808}`), "")
+	f.Add([]byte(`{"access_token":"token","expires_in":1e400,"scope":["a"],"features":{"x":true}}`), "a=; Max-Age=-1")
+	f.Add([]byte(`{"access_token":"","features":[1,{}],"scope":{}}`), "=")
+	f.Add([]byte(`null`), ";;;")
+
+	var body []byte
+	var cookies string
+	c := newTestServer(f, func(w http.ResponseWriter, r *http.Request) {
+		for _, cookie := range strings.Split(cookies, "\n") {
+			w.Header().Add("Set-Cookie", cookie)
+		}
+		w.Write(body)
+	})
+
+	f.Fuzz(func(t *testing.T, fuzzBody []byte, fuzzCookies string) {
+		body, cookies = fuzzBody, fuzzCookies
+
+		token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		if err != nil {
+			return
+		}
+
+		if err := token.Validate(); err != nil {
+			t.Fatalf("invalid token %+v: %v", token, err)
+		}
+
+		if ttl := token.TimeToLive(); ttl > time.Hour*24*365 {
+			t.Fatalf("token lifetime %s is not clamped", ttl)
+		}
+
+		data, err := json.Marshal(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := comarch.UnmarshalAccessToken(data); err != nil {
+			t.Fatalf("token does not survive storage: %v", err)
+		}
+	})
+}
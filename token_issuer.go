@@ -0,0 +1,27 @@
+package comarch
+
+import "github.com/sirupsen/logrus"
+
+// tokenIssuer адрес api клиента, которым помечаются выданные токены. Резервные адреса WithBackupBaseURLs
+// считаются тем же комархом, поэтому токен всегда помечается основным адресом
+func (c *Client) tokenIssuer() string {
+	return c.endpoint("")
+}
+
+// checkTokenIssuer сверяет Issuer токена с адресом клиента при WithTokenIssuerCheck
+func (c *Client) checkTokenIssuer(accessToken AccessToken) error {
+	if !c.tokenIssuerCheck || accessToken.Issuer == "" || accessToken.Issuer == c.tokenIssuer() {
+		return nil
+	}
+
+	if c.strictTokenIssuer {
+		return ErrTokenIssuerMismatch
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"issuer":   accessToken.Issuer,
+		"expected": c.tokenIssuer(),
+	}).Warn("Comarch access token was issued by another Comarch")
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithTokenIssuerCheck(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/login" {
+			tokenHandler(w, r)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	production := httptest.NewServer(handler)
+	defer production.Close()
+	staging := httptest.NewServer(handler)
+	defer staging.Close()
+
+	prodClient, err := comarch.New(log, production.URL, testUsername, testPassword, &http.Client{Timeout: time.Second})
+	assert.Nil(t, err)
+
+	token, err := prodClient.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, production.URL+"/cwaapiinterface", token.Issuer)
+
+	t.Run("disabled", func(t *testing.T) {
+		c, err := comarch.New(log, staging.URL, testUsername, testPassword, nil)
+		assert.Nil(t, err)
+
+		_, err = c.GetBalanceInfo(*token)
+		assert.Nil(t, err)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		logger, hook := test.NewNullLogger()
+		c, err := comarch.New(logger, staging.URL, testUsername, testPassword, nil, comarch.WithTokenIssuerCheck(false))
+		assert.Nil(t, err)
+
+		_, err = c.GetBalanceInfo(*token)
+		assert.Nil(t, err)
+		if assert.NotNil(t, hook.LastEntry()) {
+			assert.Equal(t, production.URL+"/cwaapiinterface", hook.LastEntry().Data["issuer"])
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		c, err := comarch.New(log, staging.URL, testUsername, testPassword, nil, comarch.WithTokenIssuerCheck(true))
+		assert.Nil(t, err)
+
+		_, err = c.GetBalanceInfo(*token)
+		assert.True(t, errors.Is(err, comarch.ErrTokenIssuerMismatch))
+
+		// токены без Issuer не проверяются
+		_, err = c.GetBalanceInfo(testAccessToken)
+		assert.Nil(t, err)
+
+		_, err = prodClient.GetBalanceInfo(*token)
+		assert.Nil(t, err)
+	})
+}
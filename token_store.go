@@ -0,0 +1,96 @@
+package comarch
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// TokenStore хранилище токенов участников по ключу, например номеру карты. Реализации должны быть
+// безопасны для одновременного использования из нескольких горутин
+type TokenStore interface {
+	// Load возвращает токен по ключу. false, если токена нет
+	Load(ctx context.Context, key string) (AccessToken, bool, error)
+	// Save сохраняет токен, заменяя предыдущий токен с тем же ключом. Реализации, которые читают токены из
+	// внешнего хранилища, проверяют их через AccessToken.Validate или UnmarshalAccessToken
+	Save(ctx context.Context, key string, accessToken AccessToken) error
+	// Delete удаляет токен. Удаление отсутствующего токена не ошибка
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryTokenStore TokenStore в памяти процесса. Кроме методов TokenStore позволяет посмотреть,
+// для каких ключей есть токены, и принудительно удалить их, например после инцидента безопасности
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]AccessToken
+}
+
+// NewMemoryTokenStore создает пустое хранилище токенов в памяти
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]AccessToken{}}
+}
+
+func (s *MemoryTokenStore) Load(_ context.Context, key string) (AccessToken, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accessToken, ok := s.tokens[key]
+
+	return accessToken, ok, nil
+}
+
+// Save сохраняет токен. Поврежденный токен (см. AccessToken.Validate) не сохраняется, возвращается ошибка
+func (s *MemoryTokenStore) Save(_ context.Context, key string, accessToken AccessToken) error {
+	if err := accessToken.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[key] = accessToken
+
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(_ context.Context, key string) error {
+	s.Evict(key)
+
+	return nil
+}
+
+// Keys возвращает отсортированные ключи сохраненных токенов. Значения токенов не раскрываются
+func (s *MemoryTokenStore) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.tokens))
+	for key := range s.tokens {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Evict удаляет токен по ключу. false, если токена не было
+func (s *MemoryTokenStore) Evict(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.tokens[key]
+	delete(s.tokens, key)
+
+	return ok
+}
+
+// Clear удаляет все токены и возвращает их количество
+func (s *MemoryTokenStore) Clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.tokens)
+	s.tokens = map[string]AccessToken{}
+
+	return n
+}
@@ -0,0 +1,76 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// storedToken токен, который проходит AccessToken.Validate
+func storedToken(value string) comarch.AccessToken {
+	return comarch.AccessToken{Value: value, ExpiresAt: time.Now().Add(time.Hour), Cookies: map[string]string{}}
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	ctx := context.Background()
+	var store comarch.TokenStore = comarch.NewMemoryTokenStore()
+	mem := store.(*comarch.MemoryTokenStore)
+
+	_, ok, err := store.Load(ctx, "card-1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, store.Save(ctx, "card-2", storedToken("token-2")))
+	assert.Nil(t, store.Save(ctx, "card-1", storedToken("token-1")))
+
+	token, ok, err := store.Load(ctx, "card-1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "token-1", token.Value)
+
+	assert.Equal(t, []string{"card-1", "card-2"}, mem.Keys())
+
+	assert.True(t, mem.Evict("card-1"))
+	assert.False(t, mem.Evict("card-1"))
+	assert.Equal(t, []string{"card-2"}, mem.Keys())
+
+	err = store.Save(ctx, "broken", comarch.AccessToken{Value: "token"})
+	assert.True(t, errors.Is(err, comarch.ErrInvalidToken))
+	assert.Equal(t, []string{"card-2"}, mem.Keys())
+
+	assert.Nil(t, store.Delete(ctx, "missing"))
+	assert.Equal(t, 1, mem.Clear())
+	assert.Empty(t, mem.Keys())
+}
+
+func TestMemoryTokenStore_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := comarch.NewMemoryTokenStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			key := strconv.Itoa(i)
+			for j := 0; j < 100; j++ {
+				_ = store.Save(ctx, key, storedToken(key))
+				_, _, _ = store.Load(ctx, key)
+				_ = store.Keys()
+				store.Evict(key)
+			}
+			if i%3 == 0 {
+				store.Clear()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Empty(t, store.Keys())
+}
@@ -0,0 +1,43 @@
+package comarch
+
+import "encoding/json"
+
+// Validate проверяет структуру токена, например прочитанного из хранилища: значение не пустое, ExpiresAt
+// задан и не раньше IssuedAt, Cookies инициализирован. Возвращает *ValidationError с описанием по каждому полю
+// (ключи как в json токена), соответствующий ErrInvalidToken. Срок жизни не проверяется: истекший токен
+// структурно корректен, см. TimeToLive.
+func (t AccessToken) Validate() error {
+	fields := map[string]string{}
+	if t.Value == "" {
+		fields["value"] = "must not be empty"
+	}
+	if t.ExpiresAt.IsZero() {
+		fields["expires_at"] = "must be set"
+	} else if !t.IssuedAt.IsZero() && t.ExpiresAt.Before(t.IssuedAt) {
+		fields["expires_at"] = "must not be before issued_at"
+	}
+	if t.Cookies == nil {
+		fields["cookies"] = "must not be null"
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields, err: ErrInvalidToken}
+	}
+
+	return nil
+}
+
+// UnmarshalAccessToken разбирает токен, сохраненный в json (например, в Redis), и проверяет его через Validate.
+// Поврежденный токен обнаруживается при чтении, а не при первом запросе к комарху.
+func UnmarshalAccessToken(data []byte) (*AccessToken, error) {
+	var accessToken AccessToken
+	if err := json.Unmarshal(data, &accessToken); err != nil {
+		return nil, &ValidationError{Fields: map[string]string{"token": err.Error()}, err: ErrInvalidToken}
+	}
+
+	if err := accessToken.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &accessToken, nil
+}
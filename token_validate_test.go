@@ -0,0 +1,67 @@
+package comarch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAccessToken_Validate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		token  comarch.AccessToken
+		fields []string
+	}{
+		{name: "valid", token: comarch.AccessToken{Value: "token", IssuedAt: now, ExpiresAt: now.Add(time.Hour), Cookies: map[string]string{}}},
+		{name: "expired_is_valid", token: comarch.AccessToken{Value: "token", ExpiresAt: now.Add(-time.Hour), Cookies: map[string]string{}}},
+		{name: "empty", token: comarch.AccessToken{}, fields: []string{"value", "expires_at", "cookies"}},
+		{name: "expires_before_issue", token: comarch.AccessToken{Value: "token", IssuedAt: now, ExpiresAt: now.Add(-time.Second), Cookies: map[string]string{}}, fields: []string{"expires_at"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.token.Validate()
+			if len(tt.fields) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, comarch.ErrInvalidToken))
+			var validationErr *comarch.ValidationError
+			if assert.True(t, errors.As(err, &validationErr)) {
+				assert.Len(t, validationErr.Fields, len(tt.fields))
+				for _, field := range tt.fields {
+					assert.Contains(t, validationErr.Fields, field)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalAccessToken(t *testing.T) {
+	c := newTestServer(t, tokenHandler)
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(token)
+	assert.Nil(t, err)
+
+	loaded, err := comarch.UnmarshalAccessToken(data)
+	assert.Nil(t, err)
+	if assert.NotNil(t, loaded) {
+		assert.Equal(t, token.Value, loaded.Value)
+		assert.True(t, token.ExpiresAt.Equal(loaded.ExpiresAt))
+		assert.Equal(t, token.Cookies, loaded.Cookies)
+	}
+
+	for _, data := range []string{`{"value":"token","cookies":{}}`, `{"value":"token","expires_at":"2030-01-01T00:00:00Z","cookies":null}`, `{"value":`} {
+		loaded, err := comarch.UnmarshalAccessToken([]byte(data))
+		assert.Nil(t, loaded)
+		assert.True(t, errors.Is(err, comarch.ErrInvalidToken), data)
+	}
+}
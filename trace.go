@@ -0,0 +1,111 @@
+package comarch
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTimings длительности фаз одного запроса к комарху.
+// Нулевое значение фазы означает, что фаза не выполнялась (например, соединение было переиспользовано).
+type RequestTimings struct {
+	// метод и адрес запроса. Пароли и другие секретные параметры адреса заменены на [REDACTED]
+	Method string
+	URL    string
+	// разрешение имени хоста
+	DNSLookup time.Duration
+	// установка tcp соединения
+	Connect time.Duration
+	// tls рукопожатие
+	TLSHandshake time.Duration
+	// от окончания отправки запроса до первого байта ответа. Время обработки запроса на стороне комарха
+	FirstByte time.Duration
+	// от начала запроса до получения заголовков ответа
+	Total time.Duration
+}
+
+// timingsTracer собирает RequestTimings через httptrace
+type timingsTracer struct {
+	mu sync.Mutex
+
+	method string
+	url    string
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+
+	result RequestTimings
+}
+
+func newTimingsTracer(req *http.Request) *timingsTracer {
+	return &timingsTracer{
+		method: req.Method,
+		url:    debugURL(req.URL),
+	}
+}
+
+// wrap возвращает копию запроса с подключенной трассировкой
+func (t *timingsTracer) wrap(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.measure(&t.dnsStart, &t.result.DNSLookup)
+		},
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.measure(&t.connectStart, &t.result.Connect)
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.measure(&t.tlsStart, &t.result.TLSHandshake)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.measure(&t.wroteRequest, &t.result.FirstByte)
+		},
+	}
+
+	t.start = time.Now()
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+func (t *timingsTracer) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *timingsTracer) measure(since *time.Time, into *time.Duration) {
+	t.mu.Lock()
+	if !since.IsZero() {
+		*into = time.Since(*since)
+	}
+	t.mu.Unlock()
+}
+
+// timings возвращает собранные длительности. Вызывается после завершения httpClient.Do
+func (t *timingsTracer) timings() RequestTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := t.result
+	res.Method = t.method
+	res.URL = t.url
+	res.Total = time.Since(t.start)
+
+	return res
+}
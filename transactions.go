@@ -0,0 +1,150 @@
+package comarch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Transaction операция по счету участника: начисление или списание баллов
+type Transaction struct {
+	ID string
+	// время операции в часовом поясе WithLocation
+	Date time.Time
+	// тип операции в терминах комарха, например ACCRUAL или REDEMPTION
+	Type string
+	// изменение баланса баллов, отрицательное для списаний
+	Points int
+	// сумма покупки
+	Amount float64
+	// магазин, в котором совершена операция
+	StoreID     string
+	Description string
+}
+
+type transaction struct {
+	ID          string  `json:"id"`
+	Date        string  `json:"date"`
+	Type        string  `json:"type"`
+	Points      flexInt `json:"points"`
+	Amount      float64 `json:"amount"`
+	StoreID     string  `json:"storeId"`
+	Description string  `json:"description"`
+}
+
+// tokenDecoder декодер, который умеет разбирать json по токенам, как json.Decoder
+type tokenDecoder interface {
+	Decoder
+	Token() (json.Token, error)
+	More() bool
+}
+
+// StreamTransactions получает операции участника за период [from, to] и передает их в yield по одной по мере
+// чтения ответа, не загружая всю историю в память. Если yield возвращает ошибку, чтение прекращается и
+// StreamTransactions возвращает эту ошибку. Границы периода передаются в часовом поясе WithLocation,
+// from позже to возвращает *ValidationError без запроса к комарху. Если декодер WithCodec не поддерживает
+// разбор по токенам, ответ разбирается целиком.
+func (c *Client) StreamTransactions(ctx context.Context, accessToken AccessToken, from, to time.Time, yield func(Transaction) error, opts ...CallOption) error {
+	if from.After(to) {
+		return &ValidationError{Fields: map[string]string{"from": "must not be after to"}}
+	}
+
+	params := url.Values{}
+	params.Set("from", from.In(c.location).Format(DATETIME_FMT))
+	params.Set("to", to.In(c.location).Format(DATETIME_FMT))
+
+	u := c.endpoint("/resources/transactions") + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return err
+	}
+
+	resp, err := c.do("StreamTransactions", req, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	body, err := responseBody(resp)
+	if err != nil {
+		return err
+	}
+
+	dec := c.codec.NewDecoder(body)
+	if c.isStrictDecoding(resp) {
+		// поддержка проверена в validate
+		dec.(strictDecoder).DisallowUnknownFields()
+	}
+
+	emit := func(raw transaction) error {
+		date, err := parseDateTime(raw.Date, c.location)
+		if err != nil {
+			return err
+		}
+
+		return yield(Transaction{
+			ID:          raw.ID,
+			Date:        date,
+			Type:        raw.Type,
+			Points:      int(raw.Points),
+			Amount:      raw.Amount,
+			StoreID:     raw.StoreID,
+			Description: raw.Description,
+		})
+	}
+
+	tokens, ok := dec.(tokenDecoder)
+	if !ok {
+		var all []transaction
+		if err := asUnknownFieldError(dec.Decode(&all), &all); err != nil {
+			return err
+		}
+		for _, raw := range all {
+			if err := emit(raw); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	start, err := tokens.Token()
+	if err != nil {
+		return err
+	}
+	// null вместо пустого массива
+	if start == nil {
+		return nil
+	}
+	if start != json.Delim('[') {
+		return fmt.Errorf("%w: expected [ in json, got %v", ErrBadResponse, start)
+	}
+
+	for tokens.More() {
+		var raw transaction
+		if err := asUnknownFieldError(tokens.Decode(&raw), &raw); err != nil {
+			return err
+		}
+
+		if err := emit(raw); err != nil {
+			return err
+		}
+	}
+
+	// закрывающая скобка массива
+	_, err = tokens.Token()
+
+	return err
+}
@@ -0,0 +1,117 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_StreamTransactions(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, loc)
+	to := time.Date(2020, 12, 31, 23, 59, 0, 0, loc)
+
+	const body = `[
+		{"id":"t1","date":"2020-03-10 12:30","type":"ACCRUAL","points":"120","amount":1200.5,"storeId":"S1"},
+		{"id":"t2","date":"2020-03-11 09:00:15","type":"REDEMPTION","points":-50},
+		{"id":"t3","date":"2020-03-12 10:00","type":"ACCRUAL","points":10}
+	]`
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/transactions", r.URL.Path)
+		assert.Equal(t, "2020-01-01 00:00", r.URL.Query().Get("from"))
+		assert.Equal(t, "2020-12-31 23:59", r.URL.Query().Get("to"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		w.Write([]byte(body))
+	}
+
+	t.Run("all", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithLocation(loc))
+
+		var got []comarch.Transaction
+		err := c.StreamTransactions(context.Background(), testAccessToken, from, to, func(tr comarch.Transaction) error {
+			got = append(got, tr)
+			return nil
+		})
+		assert.Nil(t, err)
+
+		if assert.Len(t, got, 3) {
+			assert.Equal(t, comarch.Transaction{
+				ID:      "t1",
+				Date:    time.Date(2020, 3, 10, 12, 30, 0, 0, loc),
+				Type:    "ACCRUAL",
+				Points:  120,
+				Amount:  1200.5,
+				StoreID: "S1",
+			}, got[0])
+			assert.Equal(t, -50, got[1].Points)
+			assert.True(t, time.Date(2020, 3, 11, 9, 0, 15, 0, loc).Equal(got[1].Date))
+		}
+	})
+
+	t.Run("stop_early", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithLocation(loc))
+
+		errStop := errors.New("stop")
+		var ids []string
+		err := c.StreamTransactions(context.Background(), testAccessToken, from, to, func(tr comarch.Transaction) error {
+			ids = append(ids, tr.ID)
+			if len(ids) == 2 {
+				return errStop
+			}
+			return nil
+		})
+		assert.Equal(t, errStop, err)
+		assert.Equal(t, []string{"t1", "t2"}, ids)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		for _, body := range []string{`[]`, `null`} {
+			c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			})
+
+			err := c.StreamTransactions(context.Background(), testAccessToken, from, to, func(comarch.Transaction) error {
+				t.Fatal("yield must not be called")
+				return nil
+			})
+			assert.Nil(t, err, body)
+		}
+	})
+
+	t.Run("not_array", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"items":[]}`))
+		})
+
+		err := c.StreamTransactions(context.Background(), testAccessToken, from, to, func(comarch.Transaction) error { return nil })
+		assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+	})
+
+	t.Run("plain_codec", func(t *testing.T) {
+		c := newTestServer(t, handler, comarch.WithLocation(loc), comarch.WithCodec(&plainCodec{}))
+
+		count := 0
+		err := c.StreamTransactions(context.Background(), testAccessToken, from, to, func(comarch.Transaction) error {
+			count++
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("invalid_period", func(t *testing.T) {
+		c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("request must not be sent")
+		})
+
+		err := c.StreamTransactions(context.Background(), testAccessToken, to, from, func(comarch.Transaction) error { return nil })
+		var validationErr *comarch.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+}
@@ -0,0 +1,167 @@
+package comarch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestRecord запись об одном http запросе к комарху для WithTranscriptSink.
+// Секретные заголовки и параметры адреса скрыты, тела запроса и ответа представлены только хешами.
+type RequestRecord struct {
+	// операция клиента, например GetBalanceInfo
+	Operation string
+	// внутренний идентификатор запроса, см. RequestID
+	RequestID string
+	Method    string
+	URL       string
+	// заголовки запроса и ответа со скрытыми Authorization и куками
+	RequestHeader  http.Header
+	ResponseHeader http.Header
+	// http статус ответа, 0 если ответ не получен
+	StatusCode int
+	// время начала запроса
+	Time time.Time
+	// от начала запроса до получения заголовков ответа или ошибки
+	Duration time.Duration
+	// sha256 тел запроса и ответа в hex. Для пустого тела хеш пустых данных,
+	// для неполученного ответа пустая строка
+	RequestBodySHA256  string
+	ResponseBodySHA256 string
+	// ошибка транспорта, nil если ответ получен
+	Err error
+}
+
+// transcript собирает RequestRecord одной попытки запроса
+type transcript struct {
+	record RequestRecord
+	sink   func(RequestRecord)
+}
+
+func (c *Client) newTranscript(operation string, req *http.Request) *transcript {
+	if c.transcriptSink == nil {
+		return nil
+	}
+
+	return &transcript{
+		record: RequestRecord{
+			Operation:         operation,
+			RequestID:         requestID(req),
+			Method:            req.Method,
+			URL:               debugURL(req.URL),
+			RequestHeader:     transcriptHeader(req.Header),
+			Time:              time.Now(),
+			RequestBodySHA256: transcriptRequestBody(req),
+		},
+		sink: c.transcriptSink,
+	}
+}
+
+// fail отправляет запись о запросе, на который не получен ответ
+func (t *transcript) fail(err error) {
+	if t == nil {
+		return
+	}
+
+	t.record.Duration = time.Since(t.record.Time)
+	t.record.Err = err
+	t.sink(t.record)
+}
+
+// finish дополняет запись ответом и подменяет тело ответа хеширующим. Запись отправляется при закрытии тела
+func (t *transcript) finish(resp *http.Response) {
+	if t == nil {
+		return
+	}
+
+	t.record.Duration = time.Since(t.record.Time)
+	t.record.StatusCode = resp.StatusCode
+	t.record.ResponseHeader = transcriptHeader(resp.Header)
+	if resp.Request != nil {
+		// заголовки, добавленные при отправке, например Authorization
+		t.record.RequestHeader = transcriptHeader(resp.Request.Header)
+	}
+
+	resp.Body = &transcriptBody{
+		body: resp.Body,
+		hash: sha256.New(),
+		onClose: func(sum string) {
+			t.record.ResponseBodySHA256 = sum
+			t.sink(t.record)
+		},
+	}
+}
+
+// transcriptHeader возвращает копию заголовков со скрытыми секретными значениями
+func transcriptHeader(header http.Header) http.Header {
+	res := make(http.Header, len(header))
+	for name, values := range header {
+		if _, ok := debugSecretHeaders[http.CanonicalHeaderKey(name)]; ok {
+			res[name] = []string{debugRedacted}
+			continue
+		}
+
+		res[name] = append([]string(nil), values...)
+	}
+
+	return res
+}
+
+// transcriptRequestBody возвращает хеш тела запроса, не трогая само тело.
+// Для тела без GetBody возвращает пустую строку, так как прочитать его повторно нельзя
+func transcriptRequestBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return transcriptSum(sha256.New())
+	}
+
+	if req.GetBody == nil {
+		return ""
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return ""
+	}
+
+	return transcriptSum(h)
+}
+
+func transcriptSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// transcriptBody тело ответа, которое считает хеш прочитанных данных. При закрытии дочитывает
+// непрочитанный остаток, чтобы хеш покрывал ответ целиком, и отдает хеш в onClose
+type transcriptBody struct {
+	body    io.ReadCloser
+	hash    hash.Hash
+	onClose func(sum string)
+	once    sync.Once
+}
+
+func (b *transcriptBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.hash.Write(p[:n])
+
+	return n, err
+}
+
+func (b *transcriptBody) Close() error {
+	b.once.Do(func() {
+		io.Copy(ioutil.Discard, b)
+		b.onClose(transcriptSum(b.hash))
+	})
+
+	return b.body.Close()
+}
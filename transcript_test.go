@@ -0,0 +1,80 @@
+package comarch_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithTranscriptSink(t *testing.T) {
+	var mu sync.Mutex
+	var records []comarch.RequestRecord
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cwaapiinterface/login":
+			tokenHandler(w, r)
+		case "/cwaapiinterface/resources/cardholders/store":
+			w.Write([]byte(`{}`))
+		}
+	}, comarch.WithTranscriptSink(func(record comarch.RequestRecord) {
+		mu.Lock()
+		records = append(records, record)
+		mu.Unlock()
+	}))
+
+	token, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Nil(t, c.SetPreferredStore(*token, "42"))
+
+	sum := func(data string) string {
+		h := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(h[:])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if assert.Len(t, records, 2) {
+		login := records[0]
+		assert.Equal(t, "SignInByCard", login.Operation)
+		assert.NotEmpty(t, login.RequestID)
+		assert.Equal(t, http.StatusOK, login.StatusCode)
+		assert.False(t, login.Time.IsZero())
+		assert.NotContains(t, login.URL, testCredentialsPassword)
+		assert.Equal(t, []string{"[REDACTED]"}, login.RequestHeader["Authorization"])
+		assert.Equal(t, []string{"[REDACTED]"}, login.ResponseHeader["Set-Cookie"])
+		assert.Equal(t, sum(testToken), login.ResponseBodySHA256)
+		assert.Nil(t, login.Err)
+
+		store := records[1]
+		assert.Equal(t, "SetPreferredStore", store.Operation)
+		assert.Equal(t, http.MethodPut, store.Method)
+		assert.True(t, strings.HasSuffix(store.URL, "/resources/cardholders/store"))
+		assert.Equal(t, sum(`{"storeId":"42"}`), store.RequestBodySHA256)
+		assert.Equal(t, sum(`{}`), store.ResponseBodySHA256)
+		assert.Equal(t, []string{"[REDACTED]"}, store.RequestHeader["Cookie"])
+	}
+}
+
+func TestWithTranscriptSink_TransportError(t *testing.T) {
+	var records []comarch.RequestRecord
+	c, err := comarch.New(log, "http://127.0.0.1:1", testUsername, testPassword, nil,
+		comarch.WithTranscriptSink(func(record comarch.RequestRecord) {
+			records = append(records, record)
+		}))
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.NotNil(t, err)
+
+	if assert.NotEmpty(t, records) {
+		assert.NotNil(t, records[0].Err)
+		assert.Zero(t, records[0].StatusCode)
+		assert.Empty(t, records[0].ResponseBodySHA256)
+	}
+}
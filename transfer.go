@@ -0,0 +1,66 @@
+package comarch
+
+import (
+	"io"
+	"sync"
+)
+
+// countingBody тело ответа, которое считает прочитанные байты, прерывает чтение сверх
+// WithMaxTransferSize и отдает объем переданных данных в метрики при закрытии
+type countingBody struct {
+	body io.ReadCloser
+
+	limit    int64
+	received int64
+	onClose  func(received int64)
+	once     sync.Once
+}
+
+func (c *Client) newCountingBody(operation string, sent int64, body io.ReadCloser) io.ReadCloser {
+	if sent < 0 {
+		sent = 0
+	}
+
+	return &countingBody{
+		body:  body,
+		limit: c.maxTransferSize,
+		onClose: func(received int64) {
+			c.metrics.ObserveTransfer(operation, sent, received)
+		},
+	}
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.received += int64(n)
+
+	if b.limit > 0 && b.received > b.limit {
+		// данные сверх лимита не отдаются, чтобы декодер не разобрал ответ целиком и не потерял ошибку
+		n -= int(b.received - b.limit)
+		b.received = b.limit
+		return n, ErrTransferTooLarge
+	}
+
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.once.Do(func() {
+		b.onClose(b.received)
+	})
+
+	return b.body.Close()
+}
+
+// cancelBody тело ответа, которое отменяет контекст запроса при закрытии
+type cancelBody struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,75 @@
+package comarch_test
+
+import (
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type transferMetrics struct {
+	comarch.NopMetrics
+
+	operation string
+	sent      int64
+	received  int64
+}
+
+func (m *transferMetrics) ObserveTransfer(operation string, sent, received int64) {
+	m.operation, m.sent, m.received = operation, sent, received
+}
+
+func TestWithMetrics_Transfer(t *testing.T) {
+	metrics := &transferMetrics{}
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"Иван"}`))
+	}, comarch.WithMetrics(metrics))
+
+	_, err := c.GetCardHolder(testAccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "GetCardHolder", metrics.operation)
+	assert.Equal(t, int64(0), metrics.sent)
+	assert.Equal(t, int64(len(`{"name":"Иван"}`)), metrics.received)
+
+	assert.Nil(t, c.ChangePassword(testAccessToken, "old", "new"))
+	assert.Equal(t, "ChangePassword", metrics.operation)
+	assert.Equal(t, int64(len(`{"newPass":"new","oldPass":"old"}`)), metrics.sent)
+}
+
+func TestWithMaxTransferSize(t *testing.T) {
+	big := `{"name":"` + strings.Repeat("a", 100) + `"}`
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// без Content-Length, чтобы лимит сработал при чтении
+		w.(http.Flusher).Flush()
+		w.Write([]byte(big))
+	}, comarch.WithMaxTransferSize(50))
+
+	_, err := c.GetCardHolder(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrTransferTooLarge))
+
+	err = c.UpdateCardHolder(testAccessToken, comarch.PersonalData{Name: strings.Repeat("a", 100)})
+	assert.True(t, errors.Is(err, comarch.ErrTransferTooLarge))
+}
+
+func TestWithMetrics_Transfer_ServerError(t *testing.T) {
+	metrics := &transferMetrics{}
+	var records []comarch.RequestRecord
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"errorCode":"INTERNAL"}`))
+	}, comarch.WithMetrics(metrics), comarch.WithTranscriptSink(func(record comarch.RequestRecord) {
+		records = append(records, record)
+	}))
+
+	_, err := c.GetCardHolder(testAccessToken)
+	assert.True(t, errors.Is(err, comarch.ErrBadResponse))
+
+	// тело ответа последней попытки с 5xx закрывается, ответ учитывается в метриках и журнале
+	assert.Equal(t, "GetCardHolder", metrics.operation)
+	assert.Equal(t, int64(len(`{"errorCode":"INTERNAL"}`)), metrics.received)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, http.StatusInternalServerError, records[0].StatusCode)
+	}
+}
@@ -0,0 +1,142 @@
+package comarch
+
+import (
+	"crypto/tls"
+	"golang.org/x/net/proxy"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// transportConfig настройки транспорта, которые клиент применяет к собственному http.Client
+type transportConfig struct {
+	// транспорт из WithTransport вместо http.DefaultTransport
+	roundTripper http.RoundTripper
+	// прокси http(s) или socks5 из WithProxy
+	proxyURL  *url.URL
+	tlsConfig *tls.Config
+	// минимальная версия tls и разрешенные наборы шифров из WithMinTLSVersion
+	minTLSVersion uint16
+	cipherSuites  []uint16
+	// имена заданных опций транспорта для сообщения о конфликте
+	options []string
+}
+
+func (t *transportConfig) set(option string) {
+	for _, name := range t.options {
+		if name == option {
+			return
+		}
+	}
+
+	t.options = append(t.options, option)
+}
+
+func (t *transportConfig) has(option string) bool {
+	for _, name := range t.options {
+		if name == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setupTransport создает http.Client с опциями транспорта. Если передан собственный http.Client, опции транспорта
+// к нему применить нельзя, и такая конфигурация считается ошибкой, чтобы опции не игнорировались молча.
+func (c *Client) setupTransport(customClient bool) error {
+	if customClient {
+		if len(c.transport.options) == 0 {
+			return nil
+		}
+
+		return &ConfigError{
+			Field:  strings.Join(c.transport.options, ", "),
+			Reason: "cannot be applied to a custom http.Client, configure its transport instead",
+		}
+	}
+
+	// WithHostHeader без других опций транспорта тоже требует своего транспорта для ServerName
+	if len(c.transport.options) == 0 && c.hostHeader == "" {
+		return nil
+	}
+
+	if c.transport.roundTripper != nil {
+		if c.transport.proxyURL != nil || c.transport.tlsConfig != nil || c.transport.minTLSVersion != 0 {
+			return &ConfigError{
+				Field:  "WithProxy, WithTLSClientConfig, WithMinTLSVersion",
+				Reason: "cannot be applied to WithTransport, configure the transport instead",
+			}
+		}
+
+		c.httpClient = &http.Client{Transport: c.transport.roundTripper}
+
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.transport.proxyURL != nil {
+		if err := setupProxy(transport, c.transport.proxyURL); err != nil {
+			return err
+		}
+	}
+	if c.transport.tlsConfig != nil {
+		transport.TLSClientConfig = c.transport.tlsConfig.Clone()
+	}
+	if c.transport.minTLSVersion != 0 {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+
+		transport.TLSClientConfig.MinVersion = c.transport.minTLSVersion
+		if len(c.transport.cipherSuites) > 0 {
+			transport.TLSClientConfig.CipherSuites = c.transport.cipherSuites
+		}
+	}
+	if c.hostHeader != "" && (transport.TLSClientConfig == nil || transport.TLSClientConfig.ServerName == "") {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+
+		// комарх адресуется по ip или имени балансировщика, сертификат выписан на имя из заголовка Host
+		transport.TLSClientConfig.ServerName = hostname(c.hostHeader)
+	}
+
+	c.httpClient = &http.Client{Transport: transport}
+
+	return nil
+}
+
+// setupProxy направляет соединения транспорта через прокси: http(s) прокси через Proxy, socks5 через DialContext
+func setupProxy(transport *http.Transport, proxyURL *url.URL) error {
+	switch proxyURL.Scheme {
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(proxyURL)
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			return &ConfigError{Field: "WithProxy", Reason: err.Error()}
+		}
+
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return &ConfigError{Field: "WithProxy", Reason: "socks5 dialer does not support context"}
+		}
+
+		// адрес комарха разрешается и соединение устанавливается прокси, переменные HTTP_PROXY не действуют
+		transport.Proxy = nil
+		transport.DialContext = contextDialer.DialContext
+	default:
+		return &ConfigError{Field: "WithProxy", Reason: "unsupported scheme " + strconv.Quote(proxyURL.Scheme) + ", use http, https or socks5"}
+	}
+
+	return nil
+}
+
+// hostname возвращает имя хоста без порта
+func hostname(host string) string {
+	u := url.URL{Host: host}
+
+	return u.Hostname()
+}
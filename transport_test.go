@@ -0,0 +1,283 @@
+package comarch_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWithProxy(t *testing.T) {
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		tokenHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	assert.Nil(t, err)
+
+	c, err := comarch.New(log, "http://comarch.invalid", testUsername, testPassword, nil, comarch.WithProxy(proxyURL))
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"comarch.invalid"}, hosts)
+}
+
+func TestNew_TransportOptionsConflict(t *testing.T) {
+	proxyURL, err := url.Parse("http://proxy.invalid:3128")
+	assert.Nil(t, err)
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, &http.Client{},
+		comarch.WithProxy(proxyURL),
+		comarch.WithTLSClientConfig(&tls.Config{}),
+	)
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "WithProxy, WithTLSClientConfig")
+	}
+
+	_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithTLSClientConfig(&tls.Config{}))
+	assert.Nil(t, err)
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	newTLSServer := func(t *testing.T, version uint16) *httptest.Server {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(tokenHandler))
+		srv.TLS = &tls.Config{MinVersion: version, MaxVersion: version}
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+
+		return srv
+	}
+
+	newClient := func(t *testing.T, srv *httptest.Server, opts ...comarch.Option) *comarch.Client {
+		rootCAs := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+		opts = append([]comarch.Option{
+			// без WithMinTLSVersion клиент готов к tls 1.0
+			comarch.WithTLSClientConfig(&tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS10}),
+		}, opts...)
+
+		c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return c
+	}
+
+	t.Run("tls10_refused", func(t *testing.T) {
+		srv := newTLSServer(t, tls.VersionTLS10)
+
+		c := newClient(t, srv, comarch.WithMinTLSVersion(tls.VersionTLS12))
+		_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "protocol version")
+		}
+	})
+
+	t.Run("tls12_allowed_cipher", func(t *testing.T) {
+		srv := newTLSServer(t, tls.VersionTLS12)
+
+		c := newClient(t, srv, comarch.WithMinTLSVersion(tls.VersionTLS12, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256))
+		_, err := c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid_version", func(t *testing.T) {
+		_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithMinTLSVersion(0x0200))
+		var configErr *comarch.ConfigError
+		if assert.True(t, errors.As(err, &configErr)) {
+			assert.Equal(t, "WithMinTLSVersion", configErr.Field)
+		}
+	})
+}
+
+// socks5Server минимальный socks5 прокси без авторизации, который запоминает адреса подключений
+func socks5Server(t *testing.T) (string, func() []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	var targets []string
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+
+		// приветствие: версия, число методов, методы
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+			return
+		}
+		conn.Write([]byte{5, 0})
+
+		// запрос CONNECT: версия, команда, резерв, тип адреса, адрес, порт
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+
+		var host string
+		switch request[3] {
+		case 1:
+			addr := make([]byte, 4)
+			io.ReadFull(conn, addr)
+			host = net.IP(addr).String()
+		case 3:
+			size := make([]byte, 1)
+			io.ReadFull(conn, size)
+			addr := make([]byte, size[0])
+			io.ReadFull(conn, addr)
+			host = string(addr)
+		default:
+			return
+		}
+
+		port := make([]byte, 2)
+		io.ReadFull(conn, port)
+		target := net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+
+		mu.Lock()
+		targets = append(targets, target)
+		mu.Unlock()
+
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer upstream.Close()
+
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return ln.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), targets...)
+	}
+}
+
+func TestWithProxy_SOCKS5(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(tokenHandler))
+	defer srv.Close()
+
+	proxyAddr, targets := socks5Server(t)
+	proxyURL, err := url.Parse("socks5://" + proxyAddr)
+	assert.Nil(t, err)
+
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil, comarch.WithProxy(proxyURL))
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{srv.Listener.Addr().String()}, targets())
+}
+
+func TestWithProxy_UnsupportedScheme(t *testing.T) {
+	for _, rawURL := range []string{"ftp://proxy.invalid:21", "socks4://proxy.invalid:1080"} {
+		proxyURL, err := url.Parse(rawURL)
+		assert.Nil(t, err)
+
+		_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithProxy(proxyURL))
+		var configErr *comarch.ConfigError
+		if assert.True(t, errors.As(err, &configErr), rawURL) {
+			assert.Equal(t, "WithProxy", configErr.Field)
+		}
+	}
+
+	_, err := comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithProxy(nil))
+	assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration))
+}
+
+func TestWithHostHeader(t *testing.T) {
+	var host, serverName string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		serverName = r.TLS.ServerName
+		tokenHandler(w, r)
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	// сертификат httptest выписан в том числе на example.com
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil,
+		comarch.WithHostHeader("example.com"),
+		comarch.WithTLSClientConfig(&tls.Config{RootCAs: roots}),
+	)
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, "example.com", serverName)
+
+	// ServerName из WithTLSClientConfig не перезаписывается
+	c, err = comarch.New(log, srv.URL, testUsername, testPassword, nil,
+		comarch.WithHostHeader("comarch.example.com:443"),
+		comarch.WithTLSClientConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"}),
+	)
+	assert.Nil(t, err)
+
+	_, err = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Nil(t, err)
+	assert.Equal(t, "comarch.example.com:443", host)
+	assert.Equal(t, "example.com", serverName)
+
+	for _, invalid := range []string{"http://example.com", "example.com/path", ":443"} {
+		_, err = comarch.New(log, testBasePath, testUsername, testPassword, nil, comarch.WithHostHeader(invalid))
+		assert.True(t, errors.Is(err, comarch.ErrInvalidConfiguration), invalid)
+	}
+}
+
+func TestWithHostHeader_OnlyOption(t *testing.T) {
+	serverNames := make(chan string, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(tokenHandler))
+	srv.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		serverNames <- hello.ServerName
+		return nil, nil
+	}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// без других опций транспорта имя из Host все равно уходит в SNI вместо ip из адреса
+	c, err := comarch.New(log, srv.URL, testUsername, testPassword, nil, comarch.WithHostHeader("example.com"))
+	assert.Nil(t, err)
+
+	// сертификат httptest не доверенный, важно только имя в ClientHello
+	_, _ = c.SignInByCard(testCredentialsCardNo, testCredentialsPassword)
+	assert.Equal(t, "example.com", <-serverNames)
+}
@@ -0,0 +1,149 @@
+package comarch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// defaultWatchInterval период опроса и время ожидания long-poll в WatchBalance по умолчанию
+const defaultWatchInterval = time.Second * 30
+
+// WatchBalance следит за балансом участника: первым приходит текущий баланс, затем каждый измененный.
+// Если комарх поддерживает long-poll (/resources/balanceinfo/longpoll), запрос висит до изменения баланса,
+// иначе баланс опрашивается с периодом WithWatchInterval. Long-poll, на который отвечают сразу без изменений
+// баланса, повторяется не чаще WithWatchInterval. Ошибки не прерывают наблюдение: они отправляются
+// в канал ошибок (если он не прочитан, новые ошибки отбрасываются), и запрос повторяется через период опроса.
+// Наблюдение завершается отменой ctx, после чего оба канала закрываются. Таймаут http.Client должен быть
+// больше WithWatchInterval, иначе long-poll будет прерываться клиентом.
+func (c *Client) WatchBalance(ctx context.Context, accessToken AccessToken) (<-chan BalanceInfoResp, <-chan error) {
+	updates := make(chan BalanceInfoResp)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(updates)
+		defer close(errs)
+
+		var last *BalanceInfoResp
+		var etag string
+		longPoll := true
+
+		for {
+			var balance *BalanceInfoResp
+			var err error
+			start := time.Now()
+			if longPoll {
+				balance, etag, err = c.longPollBalance(ctx, accessToken, etag)
+				if errors.Is(err, ErrNotFound) {
+					longPoll = false
+					continue
+				}
+			} else {
+				balance, err = c.getBalanceInfo(ctx, accessToken)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			changed := false
+			if err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			} else if balance != nil && (last == nil || !sameBalance(*last, *balance)) {
+				select {
+				case updates <- *balance:
+				case <-ctx.Done():
+					return
+				}
+				last = balance
+				changed = true
+			}
+
+			// long-poll сам ждет изменений, пауза нужна после ошибки. Комарх или прокси, которые отвечают на
+			// long-poll сразу без изменений, опрашиваются не чаще периода опроса
+			pause := c.watchInterval
+			if longPoll && err == nil {
+				if changed {
+					continue
+				}
+
+				pause -= time.Since(start)
+				if pause <= 0 {
+					continue
+				}
+			}
+
+			timer := time.NewTimer(pause)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return updates, errs
+}
+
+// longPollBalance ждет изменения баланса относительно версии etag. Возвращает nil баланс, если за время
+// ожидания баланс не изменился, и ErrNotFound, если комарх не поддерживает long-poll
+func (c *Client) longPollBalance(ctx context.Context, accessToken AccessToken, etag string) (*BalanceInfoResp, string, error) {
+	wait := int(c.watchInterval / time.Second)
+	if wait < 1 {
+		wait = 1
+	}
+
+	params := url.Values{}
+	params.Set("wait", strconv.Itoa(wait))
+
+	u := c.endpoint("/resources/balanceinfo/longpoll") + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, etag, err
+	}
+
+	if err := c.authorize(req, accessToken); err != nil {
+		return nil, etag, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.do("WatchBalance", req)
+	if err != nil {
+		return nil, etag, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+
+	if err := checkResponse(resp); err != nil {
+		return nil, etag, err
+	}
+
+	var balance BalanceInfoResp
+	if err := c.decodeResponse(resp, &balance); err != nil {
+		return nil, etag, err
+	}
+	balance.location = c.location
+
+	return &balance, resp.Header.Get("ETag"), nil
+}
+
+// sameBalance сравнивает балансы без учета того, взяты ли они из кэша
+func sameBalance(a, b BalanceInfoResp) bool {
+	a.fromCache, b.fromCache = false, false
+
+	return reflect.DeepEqual(a, b)
+}
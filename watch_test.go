@@ -0,0 +1,130 @@
+package comarch_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kazhuravlev/go-comarch"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_WatchBalance_LongPoll(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cwaapiinterface/resources/balanceinfo/longpoll", r.URL.Path)
+		assert.Equal(t, "1", r.URL.Query().Get("wait"))
+
+		mu.Lock()
+		polls++
+		n := polls
+		mu.Unlock()
+
+		switch n {
+		case 1:
+			assert.Empty(t, r.Header.Get("If-None-Match"))
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"cardNo":"1","balanceInfo":{"balance":10}}`))
+		case 2:
+			// за время ожидания баланс не изменился
+			assert.Equal(t, `"v1"`, r.Header.Get("If-None-Match"))
+			w.WriteHeader(http.StatusNotModified)
+		case 3:
+			assert.Equal(t, `"v1"`, r.Header.Get("If-None-Match"))
+			w.Header().Set("ETag", `"v2"`)
+			w.Write([]byte(`{"cardNo":"1","balanceInfo":{"balance":20}}`))
+		default:
+			<-r.Context().Done()
+		}
+	}, comarch.WithWatchInterval(time.Millisecond*10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, errs := c.WatchBalance(ctx, testAccessToken)
+
+	assert.Equal(t, 10, (<-updates).BalanceInfo.Balance)
+	assert.Equal(t, 20, (<-updates).BalanceInfo.Balance)
+
+	cancel()
+	for range updates {
+	}
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+}
+
+func TestClient_WatchBalance_PollingFallback(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cwaapiinterface/resources/balanceinfo/longpoll" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		assert.Equal(t, "/cwaapiinterface/resources/balanceinfo", r.URL.Path)
+
+		mu.Lock()
+		polls++
+		n := polls
+		mu.Unlock()
+
+		switch n {
+		case 1, 2:
+			w.Write([]byte(`{"balanceInfo":{"balance":10}}`))
+		case 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"balanceInfo":{"balance":30}}`))
+		}
+	}, comarch.WithWatchInterval(time.Millisecond*10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, errs := c.WatchBalance(ctx, testAccessToken)
+
+	// одинаковый баланс второго опроса не отправляется
+	assert.Equal(t, 10, (<-updates).BalanceInfo.Balance)
+	assert.True(t, errors.Is(<-errs, comarch.ErrBadResponse))
+	assert.Equal(t, 30, (<-updates).BalanceInfo.Balance)
+
+	cancel()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			for range updates {
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watch is not stopped")
+	}
+}
+
+func TestClient_WatchBalance_LongPollAnswersImmediately(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		mu.Unlock()
+
+		// прокси не держит запрос и не поддерживает ETag
+		w.Write([]byte(`{"cardNo":"1","balanceInfo":{"balance":10}}`))
+	}, comarch.WithWatchInterval(time.Millisecond*100))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, _ := c.WatchBalance(ctx, testAccessToken)
+
+	assert.Equal(t, 10, (<-updates).BalanceInfo.Balance)
+	time.Sleep(time.Millisecond * 350)
+	cancel()
+	for range updates {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, polls <= 6, "polls %d", polls)
+}